@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"techmarket_showcase/repo"
 	"text/tabwriter"
 	"time"
 )
@@ -19,11 +20,22 @@ const (
 type DatabaseType string
 
 const (
-	Postgres  DatabaseType = "PostgreSQL"
-	MongoDB   DatabaseType = "MongoDB"
-	Cassandra DatabaseType = "Cassandra"
+	Postgres          DatabaseType = "PostgreSQL"
+	PostgresCopy      DatabaseType = "PostgreSQL COPY"
+	MongoDB           DatabaseType = "MongoDB"
+	MongoDBReferenced DatabaseType = "MongoDB referenciado"
+	Cassandra         DatabaseType = "Cassandra"
+	SQLite            DatabaseType = "SQLite"
+	Bolt              DatabaseType = "bbolt"
+	Fanout            DatabaseType = "Fan-out"
 )
 
+// Variant identifica uma configuração alternativa do mesmo banco no relatório,
+// por exemplo "PostgreSQL (cache)".
+func (d DatabaseType) Variant(name string) DatabaseType {
+	return DatabaseType(fmt.Sprintf("%s (%s)", d, name))
+}
+
 type BenchmarkResult struct {
 	Database   DatabaseType
 	Operation  OperationType
@@ -32,9 +44,27 @@ type BenchmarkResult struct {
 	RecordSize int
 }
 
+// ErrorResult acumula as operações que falharam para um mesmo banco e
+// entidade. Last guarda o erro mais recente.
+type ErrorResult struct {
+	Database  DatabaseType
+	Operation OperationType
+	Entity    string
+	Count     int
+	Last      error
+}
+
+type ResilienceResult struct {
+	Database DatabaseType
+	Stats    repo.ResilienceStats
+}
+
 type BenchmarkLogger struct {
-	results []BenchmarkResult
-	logFile *os.File
+	results    []BenchmarkResult
+	errors     []ErrorResult
+	resilience []ResilienceResult
+	sweeps     []SweepResult
+	logFile    *os.File
 }
 
 func NewBenchmarkLogger(logFilePath string) (*BenchmarkLogger, error) {
@@ -53,13 +83,39 @@ func (b *BenchmarkLogger) AddResult(result BenchmarkResult) {
 	b.results = append(b.results, result)
 }
 
+func (b *BenchmarkLogger) Results() []BenchmarkResult {
+	return b.results
+}
+
+func (b *BenchmarkLogger) AddError(db DatabaseType, op OperationType, entity string, err error) {
+	log.Printf("Erro durante operação %s em %s para entidade %s: %v\n", op, db, entity, err)
+
+	for i := range b.errors {
+		e := &b.errors[i]
+		if e.Database == db && e.Operation == op && e.Entity == entity {
+			e.Count++
+			e.Last = err
+			return
+		}
+	}
+	b.errors = append(b.errors, ErrorResult{Database: db, Operation: op, Entity: entity, Count: 1, Last: err})
+}
+
+func (b *BenchmarkLogger) ErrorResults() []ErrorResult {
+	return b.errors
+}
+
+func (b *BenchmarkLogger) AddResilienceStats(db DatabaseType, stats repo.ResilienceStats) {
+	b.resilience = append(b.resilience, ResilienceResult{Database: db, Stats: stats})
+}
+
 func (b *BenchmarkLogger) MeasureOperation(db DatabaseType, op OperationType, entity string, recordSize int, operation func() error) {
 	start := time.Now()
 	err := operation()
 	duration := time.Since(start)
 
 	if err != nil {
-		log.Printf("Erro durante operação %s em %s para entidade %s: %v\n", op, db, entity, err)
+		b.AddError(db, op, entity, err)
 		return
 	}
 
@@ -72,8 +128,67 @@ func (b *BenchmarkLogger) MeasureOperation(db DatabaseType, op OperationType, en
 	})
 }
 
+// entities traduz os métodos de repo.TechMarketRepository para os nomes usados
+// no relatório.
+var entities = map[string]string{
+	"BatchCreateClient":           "Cliente",
+	"BatchCreateProduct":          "Produto",
+	"BatchCreateOrder":            "Pedido",
+	"BatchCreateOrderItem":        "Item de pedido",
+	"BatchCreatePayment":          "Pagamento",
+	"GetClientByEmail":            "Cliente por email",
+	"GetProductByCategory":        "Produto por categoria",
+	"GetDeliveredOrdersByClient":  "Produtos entregues por cliente",
+	"Get5MostSoldProducts":        "5 produtos mais vendidos",
+	"GetLastMonthPixPayments":     "Pagamentos pix do último mês",
+	"GetClientTotalSpentByPeriod": "Total gasto por cliente no último mês",
+	"RefundPayment":               "Estorno",
+	"BatchCreateReview":           "Avaliação",
+	"GetProductReviews":           "Avaliações por produto",
+	"GetAverageRatingByCategory":  "Nota média por categoria",
+	"BatchCreateShipment":         "Envio",
+	"AppendTrackingEvents":        "Evento de rastreamento",
+	"GetTrackingHistory":          "Histórico de rastreamento",
+	"BatchCreateAddress":          "Endereço",
+	"SetDefaultAddress":           "Endereço padrão",
+	"GetClientAddresses":          "Endereços por cliente",
+	"GetRevenueByState":           "Receita por UF",
+	"BatchCreateCoupon":           "Cupom",
+	"CreateOrderWithCoupon":       "Pedido com cupom",
+	"GetCouponUsage":              "Uso por cupom",
+}
+
+// Observer retorna uma função para repo.NewInstrumentedRepository que registra
+// cada chamada do repositório como um resultado do benchmark.
+func (b *BenchmarkLogger) Observer(db DatabaseType) func(repo.Call) {
+	return func(call repo.Call) {
+		op := Query
+		if call.Write {
+			op = Insert
+		}
+
+		entity, ok := entities[call.Method]
+		if !ok {
+			entity = call.Method
+		}
+
+		if call.Err != nil {
+			b.AddError(db, op, entity, call.Err)
+			return
+		}
+
+		b.AddResult(BenchmarkResult{
+			Database:   db,
+			Operation:  op,
+			Entity:     entity,
+			Duration:   call.Duration,
+			RecordSize: call.Cardinality,
+		})
+	}
+}
+
 func (b *BenchmarkLogger) GenerateReport() error {
-	if len(b.results) == 0 {
+	if len(b.results) == 0 && len(b.errors) == 0 {
 		return fmt.Errorf("nenhum resultado para gerar relatório")
 	}
 
@@ -95,7 +210,7 @@ func (b *BenchmarkLogger) GenerateReport() error {
 			r.Operation,
 			r.Entity,
 			r.RecordSize,
-			r.Duration.Round(time.Millisecond),
+			r.Duration.Round(time.Microsecond),
 			recordsPerSecond,
 		)
 	}
@@ -104,6 +219,79 @@ func (b *BenchmarkLogger) GenerateReport() error {
 		return fmt.Errorf("erro ao gerar tabela: %v", err)
 	}
 
+	if err := b.generateErrorReport(); err != nil {
+		return err
+	}
+
+	if err := b.generateResilienceReport(); err != nil {
+		return err
+	}
+
+	return b.generateSweepReport()
+}
+
+func (b *BenchmarkLogger) generateErrorReport() error {
+	if len(b.errors) == 0 {
+		return nil
+	}
+
+	header := "\n=== Erros ===\n\n"
+	if _, err := b.logFile.WriteString(header); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(b.logFile, 0, 0, 3, ' ', tabwriter.TabIndent)
+
+	fmt.Fprintln(w, "Banco de Dados\tOperação\tEntidade\tFalhas\tÚltimo erro\t")
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+
+	for _, e := range b.errors {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%v\t\n",
+			e.Database,
+			e.Operation,
+			e.Entity,
+			e.Count,
+			e.Last,
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("erro ao gerar tabela: %v", err)
+	}
+
+	return nil
+}
+
+func (b *BenchmarkLogger) generateResilienceReport() error {
+	if len(b.resilience) == 0 {
+		return nil
+	}
+
+	header := "\n=== Resiliência ===\n\n"
+	if _, err := b.logFile.WriteString(header); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(b.logFile, 0, 0, 3, ' ', tabwriter.TabIndent)
+
+	fmt.Fprintln(w, "Banco de Dados\tChamadas\tRetentativas\tFalhas\tRejeitadas\tCircuito\t")
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+
+	for _, r := range b.resilience {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\t\n",
+			r.Database,
+			r.Stats.Calls,
+			r.Stats.Retries,
+			r.Stats.Failures,
+			r.Stats.Rejected,
+			r.Stats.State,
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("erro ao gerar tabela: %v", err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,49 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"techmarket_showcase/config"
+	"techmarket_showcase/repo"
+	"testing"
+)
+
+func TestObserverAccountsInjectedErrors(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("BOLT_PATH", filepath.Join(dir, "techmarket.bolt"))
+	logPath := filepath.Join(dir, "benchmark_results.log")
+
+	logger, err := NewBenchmarkLogger(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	faulty := repo.NewFaultyRepository(repo.NewBoltRepository(), config.FaultConfig{
+		Methods: map[string]config.MethodFault{
+			"GetClientByEmail": {ErrorRate: 1},
+		},
+	})
+	r := repo.NewInstrumentedRepository(faulty, logger.Observer(Bolt))
+
+	for range 3 {
+		r.GetClientByEmail("ana@techmarket.com")
+	}
+	r.GetProductByCategory("Notebooks")
+
+	errs := logger.ErrorResults()
+	if len(errs) != 1 || errs[0].Entity != "Cliente por email" || errs[0].Count != 3 || errs[0].Operation != Query {
+		t.Fatalf("erros = %+v", errs)
+	}
+	if results := logger.Results(); len(results) != 1 || results[0].Entity != "Produto por categoria" {
+		t.Fatalf("resultados = %+v", results)
+	}
+
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	report, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(report), "=== Erros ===") {
+		t.Errorf("relatório sem seção de erros:\n%s", report)
+	}
+}
@@ -0,0 +1,167 @@
+package benchmark
+
+import (
+	"fmt"
+	"strings"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"text/tabwriter"
+	"time"
+)
+
+// Dataset é o conjunto de registros gravado em cada rodada da varredura.
+type Dataset struct {
+	Clients  []model.Client
+	Products []model.Product
+	Orders   []model.Order
+	Items    []model.OrderItem
+	Payments []model.Payment
+}
+
+func (d Dataset) Records() int {
+	return len(d.Clients) + len(d.Products) + len(d.Orders) + len(d.Items) + len(d.Payments)
+}
+
+// Load grava o conjunto na ordem das chaves estrangeiras e para no primeiro
+// erro.
+func (d Dataset) Load(r repo.TechMarketRepository) error {
+	steps := []func() error{
+		func() error { return r.BatchCreateClient(d.Clients) },
+		func() error { return r.BatchCreateProduct(d.Products) },
+		func() error { return r.BatchCreateOrder(d.Orders) },
+		func() error { return r.BatchCreateOrderItem(d.Items) },
+		func() error { return r.BatchCreatePayment(d.Payments) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SweepResult é a carga completa de um Dataset em um banco com um conjunto de
+// opções de gravação.
+type SweepResult struct {
+	Database DatabaseType
+	Options  repo.WriteOptions
+	Records  int
+	Duration time.Duration
+	Err      error
+}
+
+func (r SweepResult) RecordsPerSecond() float64 {
+	return float64(r.Records) / r.Duration.Seconds()
+}
+
+// WriteOptionsGrid retorna todas as combinações dos valores informados.
+func WriteOptionsGrid(batchSizes, parallelism []int, transactionPerBatch, unordered []bool) []repo.WriteOptions {
+	var grid []repo.WriteOptions
+	for _, batchSize := range batchSizes {
+		for _, p := range parallelism {
+			for _, perBatch := range transactionPerBatch {
+				for _, u := range unordered {
+					grid = append(grid, repo.WriteOptions{
+						BatchSize:           batchSize,
+						Parallelism:         p,
+						TransactionPerBatch: perBatch,
+						Unordered:           u,
+					})
+				}
+			}
+		}
+	}
+	return grid
+}
+
+// Sweep grava um Dataset novo, criado por newDataset, para cada opção do grid.
+// Cada chamada Batch* entra no relatório com o banco identificado pela
+// variante das opções, por exemplo "SQLite (lote=500 ...)". Como as opções
+// são trocadas no próprio repositório, r não deve estar envolvido em
+// decoradores.
+func (b *BenchmarkLogger) Sweep(db DatabaseType, r repo.TechMarketRepository, grid []repo.WriteOptions, newDataset func() Dataset) []SweepResult {
+	tunable, ok := r.(repo.WriteTunable)
+	if !ok {
+		b.AddError(db, Insert, "Varredura", fmt.Errorf("o repositório não aceita opções de gravação"))
+		return nil
+	}
+
+	var results []SweepResult
+	for _, opts := range grid {
+		tunable.SetWriteOptions(opts)
+		instrumented := repo.NewInstrumentedRepository(r, b.Observer(db.Variant(opts.String())))
+
+		dataset := newDataset()
+		start := time.Now()
+		err := dataset.Load(instrumented)
+		results = append(results, SweepResult{
+			Database: db,
+			Options:  opts,
+			Records:  dataset.Records(),
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+	tunable.SetWriteOptions(repo.WriteOptions{})
+
+	b.sweeps = append(b.sweeps, results...)
+	return results
+}
+
+// BestWriteOptions retorna, para cada banco, a rodada sem erro com mais
+// registros por segundo.
+func BestWriteOptions(results []SweepResult) map[DatabaseType]SweepResult {
+	best := make(map[DatabaseType]SweepResult)
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		if current, ok := best[r.Database]; !ok || r.RecordsPerSecond() > current.RecordsPerSecond() {
+			best[r.Database] = r
+		}
+	}
+	return best
+}
+
+func (b *BenchmarkLogger) generateSweepReport() error {
+	if len(b.sweeps) == 0 {
+		return nil
+	}
+
+	header := "\n=== Varredura de opções de gravação ===\n\n"
+	if _, err := b.logFile.WriteString(header); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(b.logFile, 0, 0, 3, ' ', tabwriter.TabIndent)
+
+	fmt.Fprintln(w, "Banco de Dados\tOpções\tRegistros\tDuração\tRegistros/Segundo\tMelhor\t")
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+
+	best := BestWriteOptions(b.sweeps)
+	for _, r := range b.sweeps {
+		if r.Err != nil {
+			fmt.Fprintf(w, "%s\t%s\t%d\t-\terro: %v\t\t\n", r.Database, r.Options, r.Records, r.Err)
+			continue
+		}
+
+		mark := ""
+		if best[r.Database] == r {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%.2f\t%s\t\n",
+			r.Database,
+			r.Options,
+			r.Records,
+			r.Duration.Round(time.Microsecond),
+			r.RecordsPerSecond(),
+			mark,
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("erro ao gerar tabela: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package benchmark
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/seed"
+	"testing"
+	"time"
+)
+
+func TestWriteOptionsGrid(t *testing.T) {
+	grid := WriteOptionsGrid([]int{100, 500}, []int{1, 4}, []bool{false, true}, []bool{false})
+	if len(grid) != 8 {
+		t.Fatalf("len(grid) = %d, esperado 8", len(grid))
+	}
+	want := repo.WriteOptions{BatchSize: 500, Parallelism: 4, TransactionPerBatch: true}
+	if grid[7] != want {
+		t.Errorf("grid[7] = %+v, esperado %+v", grid[7], want)
+	}
+}
+
+func TestBestWriteOptionsIgnoresFailedRuns(t *testing.T) {
+	results := []SweepResult{
+		{Database: SQLite, Options: repo.WriteOptions{BatchSize: 100}, Records: 1000, Duration: 2 * time.Second},
+		{Database: SQLite, Options: repo.WriteOptions{BatchSize: 500}, Records: 1000, Duration: time.Second},
+		{Database: SQLite, Options: repo.WriteOptions{BatchSize: 1000}, Records: 1000, Duration: time.Millisecond, Err: errors.New("falhou")},
+		{Database: Postgres, Options: repo.WriteOptions{BatchSize: 100}, Records: 1000, Duration: time.Second},
+	}
+
+	best := BestWriteOptions(results)
+	if got := best[SQLite].Options.BatchSize; got != 500 {
+		t.Errorf("melhor lote do SQLite = %d, esperado 500", got)
+	}
+	if got := best[Postgres].Options.BatchSize; got != 100 {
+		t.Errorf("melhor lote do PostgreSQL = %d, esperado 100", got)
+	}
+}
+
+func TestSweepLoadsEachCombination(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SQLITE_PATH", filepath.Join(dir, "techmarket.db"))
+	logPath := filepath.Join(dir, "sweep_results.log")
+
+	logger, err := NewBenchmarkLogger(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := model.NewIDGenerator(0)
+	newDataset := func() Dataset {
+		clients := seed.GenerateClients(ids, 20)
+		products := seed.GenerateProducts(ids, 5)
+		orders := seed.GenerateOrders(ids, 10, clients, products)
+		return Dataset{
+			Clients:  clients,
+			Products: products,
+			Orders:   orders,
+			Items:    seed.OrderItems(orders),
+			Payments: seed.GeneratePayments(ids, orders, 10),
+		}
+	}
+
+	grid := WriteOptionsGrid([]int{3, 50}, []int{1, 4}, []bool{false, true}, []bool{false})
+	results := logger.Sweep(SQLite, repo.NewSQLiteRepository(), grid, newDataset)
+	if len(results) != len(grid) {
+		t.Fatalf("len(results) = %d, esperado %d", len(results), len(grid))
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Options, r.Err)
+		}
+	}
+
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	report, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(report), "=== Varredura de opções de gravação ===") {
+		t.Errorf("relatório sem seção da varredura:\n%s", report)
+	}
+}
@@ -0,0 +1,31 @@
+// Comando cassandra-repair recalcula as tabelas de consulta do Cassandra
+// (vendas por produto, ranking de mais vendidos, pedidos por UF e por cupom e
+// pagamentos com cliente e valor) a partir das tabelas de origem, e copia para
+// as tabelas _v2 os produtos e pagamentos gravados antes da versão 9 do schema:
+//
+//	go run ./cmd/cassandra-repair
+//
+// Deve ser executado sem gravações em andamento no keyspace.
+package main
+
+import (
+	"log"
+	"techmarket_showcase/config"
+	"techmarket_showcase/repo"
+)
+
+func main() {
+	config.LoadDotEnv()
+
+	report, err := repo.NewCassandraRepository().RebuildQueryTables()
+	if err != nil {
+		log.Fatalf("Erro ao reconstruir as tabelas de consulta: %v", err)
+	}
+
+	log.Printf("Clientes: %d", report.Clients)
+	log.Printf("Produtos: %d", report.Products)
+	log.Printf("Pedidos: %d (%d com endereço, %d com cupom)", report.Orders, report.AddressedOrders, report.CouponOrders)
+	log.Printf("Produtos vendidos: %d", report.SoldProducts)
+	log.Printf("Pagamentos: %d (%d sem pedido)", report.Payments, report.OrphanPayments)
+	log.Printf("Avaliações: %d", report.Reviews)
+}
@@ -0,0 +1,106 @@
+// Comando schema aplica, reverte e lista as migrações de schema:
+//
+//	go run ./cmd/schema up [versão]
+//	go run ./cmd/schema down [passos]
+//	go run ./cmd/schema status
+//
+// Por padrão atua no PostgreSQL, MongoDB e Cassandra; -db restringe os bancos.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"techmarket_showcase/config"
+	"techmarket_showcase/schema"
+	"text/tabwriter"
+)
+
+func newEngine(name string) schema.Engine {
+	switch name {
+	case "postgres":
+		return schema.NewPostgresEngine()
+	case "mongodb":
+		return schema.NewMongoDBEngine()
+	case "cassandra":
+		return schema.NewCassandraEngine()
+	}
+	log.Fatalf("Banco de dados desconhecido: %s", name)
+	return nil
+}
+
+func main() {
+	databases := flag.String("db", "postgres,mongodb,cassandra", "bancos separados por vírgula")
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if command != "up" && command != "down" && command != "status" {
+		fmt.Fprintln(os.Stderr, "uso: schema [-db bancos] up [versão] | down [passos] | status")
+		os.Exit(2)
+	}
+
+	n := 0
+	if flag.NArg() > 1 {
+		var err error
+		if n, err = strconv.Atoi(flag.Arg(1)); err != nil || n < 0 {
+			log.Fatalf("Número inválido: %s", flag.Arg(1))
+		}
+	}
+	if command == "down" && n == 0 {
+		n = 1
+	}
+
+	config.LoadDotEnv()
+	ctx := context.Background()
+
+	for _, name := range strings.Split(*databases, ",") {
+		name = strings.TrimSpace(name)
+		runner := schema.NewRunner(newEngine(name))
+
+		switch command {
+		case "up":
+			applied, err := runner.Up(ctx, n)
+			for _, m := range applied {
+				log.Printf("%s: aplicada %04d_%s", name, m.Version, m.Name)
+			}
+			if err != nil {
+				log.Fatalf("%s: %v", name, err)
+			}
+			if len(applied) == 0 {
+				log.Printf("%s: nenhuma migração pendente", name)
+			}
+		case "down":
+			reverted, err := runner.Down(ctx, n)
+			for _, m := range reverted {
+				log.Printf("%s: revertida %04d_%s", name, m.Version, m.Name)
+			}
+			if err != nil {
+				log.Fatalf("%s: %v", name, err)
+			}
+		case "status":
+			status, err := runner.Status(ctx)
+			if err != nil {
+				log.Fatalf("%s: %v", name, err)
+			}
+			printStatus(name, status)
+		}
+	}
+}
+
+func printStatus(database string, status []schema.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "\n=== %s ===\n", database)
+	fmt.Fprintln(w, "Versão\tNome\tAplicada em\t")
+	for _, s := range status {
+		applied := "pendente"
+		if !s.AppliedAt.IsZero() {
+			applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t\n", s.Version, s.Name, applied)
+	}
+	w.Flush()
+}
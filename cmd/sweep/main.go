@@ -0,0 +1,100 @@
+// Comando sweep grava o mesmo volume de dados em cada banco de
+// SWEEP_DATABASES com todas as combinações de tamanho de lote, paralelismo,
+// transação e ordem configuradas e aponta as opções mais rápidas de cada um:
+//
+//	go run ./cmd/sweep
+//
+// Cada rodada grava registros novos, então o banco cresce a cada combinação.
+// O relatório completo vai para sweep_results.log.
+package main
+
+import (
+	"log"
+	"techmarket_showcase/benchmark"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/seed"
+)
+
+func newRepository(name string) (benchmark.DatabaseType, repo.TechMarketRepository) {
+	switch name {
+	case "postgres":
+		return benchmark.Postgres, repo.NewPostgresRepository()
+	case "postgres-copy":
+		return benchmark.PostgresCopy, repo.NewPostgresCopyRepository()
+	case "mongodb":
+		return benchmark.MongoDB, repo.NewMongoDBRepository()
+	case "mongodb-referenced":
+		return benchmark.MongoDBReferenced, repo.NewMongoDBReferencedRepository()
+	case "cassandra":
+		if config.LoadCassandraConfig().WriteStrategy == "concorrente" {
+			return benchmark.Cassandra.Variant("concorrente"), repo.NewCassandraRepository()
+		}
+		return benchmark.Cassandra, repo.NewCassandraRepository()
+	case "sqlite":
+		return benchmark.SQLite, repo.NewSQLiteRepository()
+	}
+	log.Fatalf("Banco de dados sem opções de gravação: %s", name)
+	return "", nil
+}
+
+func main() {
+	config.LoadDotEnv()
+
+	benchLogger, err := benchmark.NewBenchmarkLogger("sweep_results.log")
+	if err != nil {
+		log.Fatalf("Erro ao criar benchmark logger: %v", err)
+	}
+	defer benchLogger.Close()
+
+	sweep := config.LoadSweepConfig()
+	grid := benchmark.WriteOptionsGrid(
+		sweep.BatchSizes,
+		sweep.Parallelism,
+		flags(sweep.Transactions, "chamada", "lote"),
+		flags(sweep.Orders, "ordenada", "livre"),
+	)
+
+	ids := model.NewIDGenerator(config.LoadIDConfig().Node)
+	newDataset := func() benchmark.Dataset {
+		clients := seed.GenerateClients(ids, sweep.Clients)
+		products := seed.GenerateProducts(ids, max(sweep.Clients/4, 1))
+		orders := seed.GenerateOrders(ids, max(sweep.Clients/2, 1), clients, products)
+		return benchmark.Dataset{
+			Clients:  clients,
+			Products: products,
+			Orders:   orders,
+			Items:    seed.OrderItems(orders),
+			Payments: seed.GeneratePayments(ids, orders, len(orders)),
+		}
+	}
+
+	var results []benchmark.SweepResult
+	for _, name := range sweep.Databases {
+		db, r := newRepository(name)
+		log.Printf("%s: %d combinações", db, len(grid))
+		results = append(results, benchLogger.Sweep(db, r, grid, newDataset)...)
+	}
+
+	for db, best := range benchmark.BestWriteOptions(results) {
+		log.Printf("%s: melhor %s (%.2f registros/segundo)", db, best.Options, best.RecordsPerSecond())
+	}
+}
+
+// flags converte os valores de SWEEP_TRANSACTIONS ou SWEEP_ORDERS em
+// booleanos, com falseValue e trueValue como os nomes aceitos.
+func flags(values []string, falseValue, trueValue string) []bool {
+	var result []bool
+	for _, value := range values {
+		switch value {
+		case falseValue:
+			result = append(result, false)
+		case trueValue:
+			result = append(result, true)
+		default:
+			log.Fatalf("Valor inválido %q: use %s ou %s", value, falseValue, trueValue)
+		}
+	}
+	return result
+}
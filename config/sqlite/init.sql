@@ -0,0 +1,157 @@
+CREATE TABLE IF NOT EXISTS cliente (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nome VARCHAR(255) NOT NULL,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    telefone VARCHAR(20),
+    data_cadastro DATETIME DEFAULT CURRENT_TIMESTAMP,
+    cpf VARCHAR(14) UNIQUE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cliente_email ON cliente (email);
+
+CREATE TABLE IF NOT EXISTS produto (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nome VARCHAR(255) NOT NULL,
+    categoria VARCHAR(100) NOT NULL,
+    preco DECIMAL(10, 2) NOT NULL,
+    estoque INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_produto_categoria ON produto (categoria);
+
+CREATE TABLE IF NOT EXISTS endereco (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    id_cliente INT NOT NULL REFERENCES cliente (id),
+    cep TEXT NOT NULL,
+    logradouro TEXT NOT NULL,
+    numero TEXT NOT NULL,
+    complemento TEXT NOT NULL DEFAULT '',
+    cidade TEXT NOT NULL,
+    uf TEXT NOT NULL,
+    padrao BOOLEAN NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_endereco_cliente ON endereco (id_cliente, id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_endereco_padrao ON endereco (id_cliente) WHERE padrao;
+
+CREATE TABLE IF NOT EXISTS pedido (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    id_cliente INT NOT NULL REFERENCES cliente (id),
+    id_endereco INT REFERENCES endereco (id),
+    data_pedido DATETIME DEFAULT CURRENT_TIMESTAMP,
+    status VARCHAR(50) NOT NULL,
+    valor_total DECIMAL(10, 2) NOT NULL,
+    codigo_cupom VARCHAR(30) NOT NULL DEFAULT '',
+    desconto DECIMAL(10, 2) NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_pedido_id_cliente ON pedido (id_cliente);
+
+CREATE INDEX IF NOT EXISTS idx_pedido_status ON pedido (status);
+
+CREATE INDEX IF NOT EXISTS idx_pedido_endereco ON pedido (id_endereco);
+
+CREATE INDEX IF NOT EXISTS idx_pedido_cupom ON pedido (codigo_cupom);
+
+CREATE TABLE IF NOT EXISTS item_pedido (
+    id_pedido INT NOT NULL REFERENCES pedido (id),
+    id_produto INT NOT NULL REFERENCES produto (id),
+    quantidade INT NOT NULL,
+    preco_unitario DECIMAL(10, 2) NOT NULL,
+    PRIMARY KEY (id_pedido, id_produto)
+);
+
+CREATE TABLE IF NOT EXISTS pagamento (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    id_pedido INT NOT NULL REFERENCES pedido (id),
+    id_cliente INT NOT NULL REFERENCES cliente (id),
+    tipo VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    valor DECIMAL(10, 2) NOT NULL,
+    parcelas INT NOT NULL DEFAULT 1,
+    valor_estornado DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (valor_estornado BETWEEN 0 AND valor),
+    data_pagamento DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pagamento_id_pedido ON pagamento (id_pedido);
+
+CREATE INDEX IF NOT EXISTS idx_pagamento_tipo ON pagamento (tipo);
+
+CREATE INDEX IF NOT EXISTS idx_pagamento_data ON pagamento (data_pagamento);
+
+CREATE INDEX IF NOT EXISTS idx_pagamento_cliente_data ON pagamento (id_cliente, data_pagamento);
+
+CREATE TABLE IF NOT EXISTS estorno (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    id_pagamento INT NOT NULL REFERENCES pagamento (id),
+    id_pedido INT NOT NULL REFERENCES pedido (id),
+    id_cliente INT NOT NULL REFERENCES cliente (id),
+    valor DECIMAL(10, 2) NOT NULL CHECK (valor > 0),
+    motivo TEXT NOT NULL DEFAULT '',
+    data_estorno DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_estorno_id_pagamento ON estorno (id_pagamento, data_estorno);
+
+CREATE TABLE IF NOT EXISTS avaliacao (
+    id INTEGER PRIMARY KEY,
+    id_produto INT NOT NULL REFERENCES produto (id),
+    id_cliente INT NOT NULL REFERENCES cliente (id),
+    nota INT NOT NULL CHECK (nota BETWEEN 1 AND 5),
+    comentario TEXT NOT NULL DEFAULT '',
+    data_avaliacao DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_avaliacao_produto ON avaliacao (id_produto, id);
+
+CREATE TABLE IF NOT EXISTS envio (
+    id INTEGER PRIMARY KEY,
+    id_pedido INT NOT NULL REFERENCES pedido (id),
+    transportadora TEXT NOT NULL,
+    codigo_rastreio TEXT NOT NULL,
+    cep TEXT NOT NULL,
+    logradouro TEXT NOT NULL,
+    numero TEXT NOT NULL,
+    complemento TEXT NOT NULL DEFAULT '',
+    cidade TEXT NOT NULL,
+    uf TEXT NOT NULL,
+    data_envio DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_envio_pedido ON envio (id_pedido);
+
+CREATE TABLE IF NOT EXISTS evento_rastreio (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    id_envio INT NOT NULL REFERENCES envio (id),
+    status TEXT NOT NULL,
+    local TEXT NOT NULL DEFAULT '',
+    descricao TEXT NOT NULL DEFAULT '',
+    data_evento DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_evento_rastreio_envio ON evento_rastreio (id_envio, data_evento);
+
+CREATE TABLE IF NOT EXISTS cupom (
+    codigo VARCHAR(30) PRIMARY KEY,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('Percentual', 'Valor Fixo')),
+    percentual INT NOT NULL DEFAULT 0 CHECK (percentual BETWEEN 0 AND 100),
+    valor DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    valor_minimo DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    inicio_validade DATETIME NOT NULL,
+    fim_validade DATETIME NOT NULL,
+    limite_por_cliente INT NOT NULL CHECK (limite_por_cliente >= 1)
+);
+
+CREATE TABLE IF NOT EXISTS cupom_categoria (
+    codigo VARCHAR(30) NOT NULL REFERENCES cupom (codigo),
+    categoria VARCHAR(100) NOT NULL,
+    PRIMARY KEY (codigo, categoria)
+);
+
+CREATE TABLE IF NOT EXISTS uso_cupom (
+    codigo VARCHAR(30) NOT NULL REFERENCES cupom (codigo),
+    id_cliente INT NOT NULL REFERENCES cliente (id),
+    usos INT NOT NULL,
+    PRIMARY KEY (codigo, id_cliente)
+);
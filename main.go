@@ -1,9 +1,13 @@
 package main
 
 import (
+	"errors"
 	"log"
+	"slices"
+	"sync"
 	"techmarket_showcase/benchmark"
 	"techmarket_showcase/config"
+	"techmarket_showcase/model"
 	"techmarket_showcase/repo"
 	"techmarket_showcase/repo/seed"
 	"time"
@@ -14,8 +18,110 @@ const (
 	PRODUCT_INSERT_SIZE = 5000
 	ORDER_INSERT_SIZE   = 10000
 	PAYMENT_INSERT_SIZE = 10000
+	REFUND_SIZE         = 500
+	REVIEW_INSERT_SIZE  = 10000
+	REVIEW_PAGE_SIZE    = 20
+	TRACKING_LOOKUPS    = 100
+	ADDRESS_LOOKUPS     = 100
+	COUPON_ORDER_SIZE   = 2000
+	// Os pedidos com cupom são dos primeiros COUPON_CLIENTS clientes, gravados
+	// por COUPON_WORKERS goroutines, para que disputem os limites de uso.
+	COUPON_CLIENTS = 200
+	COUPON_WORKERS = 8
 )
 
+type target struct {
+	db        benchmark.DatabaseType
+	repo      repo.TechMarketRepository
+	cached    repo.TechMarketRepository
+	resilient *repo.ResilientRepository
+	fanout    *repo.FanoutRepository
+	// precomputed lê os mais vendidos da coleção pré-agregada do MongoDB.
+	precomputed repo.TechMarketRepository
+	// As interfaces opcionais vêm do repositório sem decoradores (o fan-out as
+	// repassa aos backends) e ficam nil quando ele não as implementa.
+	refunder  repo.Refunder
+	reviewer  repo.Reviewer
+	tracker   repo.ShipmentTracker
+	addresses repo.AddressBook
+	coupons   repo.CouponBook
+}
+
+func newRepository(name string) (benchmark.DatabaseType, repo.TechMarketRepository) {
+	switch name {
+	case "postgres":
+		return benchmark.Postgres, repo.NewPostgresRepository()
+	case "postgres-copy":
+		return benchmark.PostgresCopy, repo.NewPostgresCopyRepository()
+	case "mongodb":
+		return benchmark.MongoDB, repo.NewMongoDBRepository()
+	case "mongodb-referenced":
+		return benchmark.MongoDBReferenced, repo.NewMongoDBReferencedRepository()
+	case "cassandra":
+		if config.LoadCassandraConfig().WriteStrategy == "concorrente" {
+			return benchmark.Cassandra.Variant("concorrente"), repo.NewCassandraRepository()
+		}
+		return benchmark.Cassandra, repo.NewCassandraRepository()
+	case "sqlite":
+		return benchmark.SQLite, repo.NewSQLiteRepository()
+	case "bolt":
+		return benchmark.Bolt, repo.NewBoltRepository()
+	case "fanout":
+		var backends []repo.Backend
+		for _, backend := range config.LoadFanoutConfig().Backends {
+			if backend == "fanout" {
+				log.Fatalf("FANOUT_BACKENDS não pode conter fanout")
+			}
+			_, r := newRepository(backend)
+			backends = append(backends, repo.Backend{Name: backend, Repo: r})
+		}
+		return benchmark.Fanout, repo.NewFanoutRepository(backends)
+	}
+	log.Fatalf("Banco de dados desconhecido: %s", name)
+	return "", nil
+}
+
+func newTargets(databases []string, benchLogger *benchmark.BenchmarkLogger) []target {
+	var targets []target
+	for _, name := range databases {
+		var t target
+		t.db, t.repo = newRepository(name)
+		t.fanout, _ = t.repo.(*repo.FanoutRepository)
+		t.refunder, _ = t.repo.(repo.Refunder)
+		t.reviewer, _ = t.repo.(repo.Reviewer)
+		t.tracker, _ = t.repo.(repo.ShipmentTracker)
+		t.addresses, _ = t.repo.(repo.AddressBook)
+		t.coupons, _ = t.repo.(repo.CouponBook)
+		for _, feature := range []struct {
+			name      string
+			supported bool
+		}{
+			{"estornos", t.refunder != nil},
+			{"avaliações", t.reviewer != nil},
+			{"envios", t.tracker != nil},
+			{"endereços", t.addresses != nil},
+			{"cupons", t.coupons != nil},
+		} {
+			if !feature.supported {
+				log.Printf("%s não implementa %s; benchmark ignorado", t.db, feature.name)
+			}
+		}
+		if mongo, ok := t.repo.(repo.PrecomputedSales); ok && config.LoadMongoDBConfig().SalesCollection {
+			t.precomputed = repo.NewInstrumentedRepository(mongo.Precomputed(), benchLogger.Observer(t.db.Variant("pré-agregado")))
+		}
+
+		if faults := config.LoadFaultConfig(); len(faults.Methods) > 0 {
+			t.repo = repo.NewFaultyRepository(t.repo, faults)
+		}
+
+		t.resilient = repo.NewResilientRepository(t.repo)
+		t.cached = repo.NewInstrumentedRepository(repo.NewCachedRepository(t.resilient), benchLogger.Observer(t.db.Variant("cache")))
+		t.repo = repo.NewInstrumentedRepository(t.resilient, benchLogger.Observer(t.db))
+		targets = append(targets, t)
+	}
+	return targets
+}
+
 func main() {
 	config.LoadDotEnv()
 
@@ -25,205 +131,319 @@ func main() {
 	}
 	defer benchLogger.Close()
 
-	mongoRepo := repo.NewMongoDBRepository()
-	postgresRepo := repo.NewPostgresRepository()
-	cassandraRepo := repo.NewCassandraRepository()
-
-	clients := seed.GenerateClients(CLIENT_INSERT_SIZE)
-	products := seed.GenerateProducts(PRODUCT_INSERT_SIZE)
-	orders := seed.GenerateOrders(ORDER_INSERT_SIZE, CLIENT_INSERT_SIZE, PRODUCT_INSERT_SIZE)
-	payments := seed.GeneratePayments(ORDER_INSERT_SIZE, PAYMENT_INSERT_SIZE)
-
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Insert, "Cliente", CLIENT_INSERT_SIZE, func() error {
-		return postgresRepo.BatchCreateClient(clients)
-	})
-
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Insert, "Produto", PRODUCT_INSERT_SIZE, func() error {
-		return postgresRepo.BatchCreateProduct(products)
-	})
+	targets := newTargets(config.LoadBenchmarkConfig().Databases, benchLogger)
+
+	// Os IDs são gerados antes da gravação, então todos os bancos recebem os
+	// mesmos registros com as mesmas referências.
+	ids := model.NewIDGenerator(config.LoadIDConfig().Node)
+	clients := seed.GenerateClients(ids, CLIENT_INSERT_SIZE)
+	addresses := seed.GenerateAddresses(ids, clients)
+	products := seed.GenerateProducts(ids, PRODUCT_INSERT_SIZE)
+	orders := seed.GenerateOrders(ids, ORDER_INSERT_SIZE, clients, products)
+	seed.AssignAddresses(orders, addresses)
+	items := seed.OrderItems(orders)
+	payments := seed.GeneratePayments(ids, orders, PAYMENT_INSERT_SIZE)
+	refunds := seed.GenerateRefunds(ids, payments, REFUND_SIZE)
+	reviews := seed.GenerateReviews(ids, orders, REVIEW_INSERT_SIZE)
+	shipments, trackingEvents := seed.GenerateShipments(ids, orders, addresses)
+	coupons := seed.GenerateCoupons()
+	couponOrders := seed.GenerateCouponOrders(ids, COUPON_ORDER_SIZE, clients[:COUPON_CLIENTS], products, coupons)
+
+	// Os erros de cada chamada são registrados pelo observer do benchLogger.
+	// Os pedidos referenciam os endereços, então nos bancos sem endereços eles
+	// são gravados sem endereço.
+	for _, t := range targets {
+		t.repo.BatchCreateClient(clients)
+		if t.addresses != nil {
+			measure(benchLogger.Observer(t.db), "BatchCreateAddress", true, func() (int, error) {
+				return len(addresses), t.addresses.BatchCreateAddress(slices.Clone(addresses))
+			})
+		}
+		t.repo.BatchCreateProduct(products)
+		if t.addresses != nil {
+			t.repo.BatchCreateOrder(orders)
+		} else {
+			t.repo.BatchCreateOrder(withoutAddresses(orders))
+		}
+		t.repo.BatchCreateOrderItem(items)
+		t.repo.BatchCreatePayment(payments)
+	}
 
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Insert, "Pedido", ORDER_INSERT_SIZE, func() error {
-		return postgresRepo.BatchCreateOrder(orders)
-	})
+	// Os estornos são aplicados um a um, como chegariam em produção, e medidos
+	// juntos.
+	for _, t := range targets {
+		if t.refunder != nil {
+			applyRefunds(t, slices.Clone(refunds), benchLogger.Observer(t.db))
+		}
+	}
 
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Insert, "Pagamento", PAYMENT_INSERT_SIZE, func() error {
-		return postgresRepo.BatchCreatePayment(payments)
-	})
+	// As avaliações paginadas são as do produto com mais avaliações.
+	reviewedProduct := seed.MostReviewedProduct(reviews)
+	for _, t := range targets {
+		if t.reviewer != nil {
+			benchmarkReviews(t, slices.Clone(reviews), reviewedProduct, benchLogger.Observer(t.db))
+		}
+	}
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Insert, "Cliente", CLIENT_INSERT_SIZE, func() error {
-		return mongoRepo.BatchCreateClient(clients)
-	})
+	for _, t := range targets {
+		if t.tracker != nil {
+			benchmarkTracking(t, slices.Clone(shipments), trackingEvents, benchLogger.Observer(t.db))
+		}
+	}
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Insert, "Produto", PRODUCT_INSERT_SIZE, func() error {
-		return mongoRepo.BatchCreateProduct(products)
-	})
+	for _, t := range targets {
+		if t.addresses != nil {
+			benchmarkAddresses(t, addresses, benchLogger.Observer(t.db))
+		}
+	}
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Insert, "Pedido", ORDER_INSERT_SIZE, func() error {
-		return mongoRepo.BatchCreateOrder(orders)
-	})
+	for _, t := range targets {
+		if t.coupons != nil {
+			benchmarkCoupons(t, slices.Clone(coupons), couponOrders, benchLogger.Observer(t.db))
+		}
+	}
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Insert, "Pagamento", PAYMENT_INSERT_SIZE, func() error {
-		return mongoRepo.BatchCreatePayment(payments)
-	})
+	clientID := clients[0].ID
+
+	queries := []func(r repo.TechMarketRepository){
+		func(r repo.TechMarketRepository) { r.GetClientByEmail("teste@teste.com") },
+		func(r repo.TechMarketRepository) { r.GetProductByCategory("teste") },
+		func(r repo.TechMarketRepository) { r.GetDeliveredOrdersByClient(clientID) },
+		func(r repo.TechMarketRepository) { r.Get5MostSoldProducts() },
+		func(r repo.TechMarketRepository) { r.GetLastMonthPixPayments() },
+		func(r repo.TechMarketRepository) {
+			r.GetClientTotalSpentByPeriod(clientID, time.Now().AddDate(0, -1, 0), time.Now())
+		},
+	}
 
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Insert, "Cliente", CLIENT_INSERT_SIZE, func() error {
-		return cassandraRepo.BatchCreateClient(clients)
-	})
+	for _, query := range queries {
+		for _, t := range targets {
+			query(t.repo)
+		}
+	}
 
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Insert, "Produto", PRODUCT_INSERT_SIZE, func() error {
-		return cassandraRepo.BatchCreateProduct(products)
-	})
+	for _, t := range targets {
+		if t.precomputed != nil {
+			t.precomputed.Get5MostSoldProducts()
+		}
+	}
 
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Insert, "Pedido", ORDER_INSERT_SIZE, func() error {
-		return cassandraRepo.BatchCreateOrder(orders)
-	})
+	// A primeira chamada popula o cache e a segunda mede o acerto.
+	cachedQueries := []func(r repo.TechMarketRepository){
+		func(r repo.TechMarketRepository) { r.GetProductByCategory("teste") },
+		func(r repo.TechMarketRepository) { r.Get5MostSoldProducts() },
+	}
+	for _, query := range cachedQueries {
+		for _, t := range targets {
+			query(t.cached)
+			query(t.cached)
+		}
+	}
 
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Insert, "Pagamento", PAYMENT_INSERT_SIZE, func() error {
-		return cassandraRepo.BatchCreatePayment(payments)
-	})
+	for _, t := range targets {
+		benchLogger.AddResilienceStats(t.db, t.resilient.Stats())
 
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Query, "Cliente por email", CLIENT_INSERT_SIZE, func() error {
-		_, err := postgresRepo.GetClientByEmail("teste@teste.com")
-		if err != nil {
-			return err
+		if t.fanout != nil {
+			t.fanout.Wait()
+			log.Printf("Fan-out: %d leituras sombra divergentes", t.fanout.Divergences())
 		}
-		return nil
-	})
+	}
+}
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Query, "Cliente por email", CLIENT_INSERT_SIZE, func() error {
-		_, err := mongoRepo.GetClientByEmail("teste@teste.com")
-		if err != nil {
-			return err
-		}
-		return nil
-	})
+// measure reporta ao observer a duração de fn, que retorna quantos registros
+// processou. Serve aos métodos das interfaces opcionais, como repo.Refunder,
+// que não passam pelo InstrumentedRepository.
+func measure(observe func(repo.Call), method string, write bool, fn func() (int, error)) {
+	start := time.Now()
+	n, err := fn()
+	observe(repo.Call{Method: method, Write: write, Duration: time.Since(start), Cardinality: n, Err: err})
+}
 
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Query, "Cliente por email", CLIENT_INSERT_SIZE, func() error {
-		_, err := cassandraRepo.GetClientByEmail("teste@teste.com")
-		if err != nil {
-			return err
+// applyRefunds registra refunds em t.refunder, um a um; o primeiro erro
+// interrompe a sequência.
+func applyRefunds(t target, refunds []model.Refund, observe func(repo.Call)) {
+	measure(observe, "RefundPayment", true, func() (int, error) {
+		for i := range refunds {
+			if err := t.refunder.RefundPayment(&refunds[i]); err != nil {
+				return i, err
+			}
 		}
-		return nil
+		return len(refunds), nil
 	})
+}
 
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Query, "Produto por categoria", PRODUCT_INSERT_SIZE, func() error {
-		_, err := postgresRepo.GetProductByCategory("teste")
-		if err != nil {
-			return err
-		}
-		return nil
+// benchmarkReviews grava as avaliações em t.reviewer e mede as duas primeiras
+// páginas das avaliações de productID e a nota média por categoria.
+func benchmarkReviews(t target, reviews []model.Review, productID uint, observe func(repo.Call)) {
+	measure(observe, "BatchCreateReview", true, func() (int, error) {
+		return len(reviews), t.reviewer.BatchCreateReview(reviews)
 	})
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Query, "Produto por categoria", PRODUCT_INSERT_SIZE, func() error {
-		_, err := mongoRepo.GetProductByCategory("teste")
-		if err != nil {
-			return err
-		}
-		return nil
-	})
+	var beforeID uint
+	for range 2 {
+		measure(observe, "GetProductReviews", false, func() (int, error) {
+			page, err := t.reviewer.GetProductReviews(productID, beforeID, REVIEW_PAGE_SIZE)
+			if len(page) > 0 {
+				beforeID = page[len(page)-1].ID
+			}
+			return len(page), err
+		})
+	}
 
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Query, "Produto por categoria", PRODUCT_INSERT_SIZE, func() error {
-		_, err := cassandraRepo.GetProductByCategory("teste")
-		if err != nil {
-			return err
-		}
-		return nil
+	measure(observe, "GetAverageRatingByCategory", false, func() (int, error) {
+		ratings, err := t.reviewer.GetAverageRatingByCategory()
+		return len(ratings), err
 	})
+}
 
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Query, "Produtos entregues por cliente", CLIENT_INSERT_SIZE, func() error {
-		_, err := postgresRepo.GetDeliveredOrdersByClient(1)
-		if err != nil {
-			return err
-		}
-		return nil
+// benchmarkTracking grava os envios e acrescenta os eventos em rodadas, como
+// chegariam das transportadoras: a rodada k traz o k-ésimo evento de cada
+// envio que ainda tem eventos. Depois lê o histórico de até TRACKING_LOOKUPS
+// pedidos.
+func benchmarkTracking(t target, shipments []model.Shipment, events []model.TrackingEvent, observe func(repo.Call)) {
+	measure(observe, "BatchCreateShipment", true, func() (int, error) {
+		return len(shipments), t.tracker.BatchCreateShipment(shipments)
 	})
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Query, "Produtos entregues por cliente", CLIENT_INSERT_SIZE, func() error {
-		_, err := mongoRepo.GetDeliveredOrdersByClient(1)
-		if err != nil {
-			return err
+	var rounds [][]model.TrackingEvent
+	seen := make(map[uint]int)
+	for _, event := range events {
+		k := seen[event.ShipmentID]
+		seen[event.ShipmentID]++
+		if k == len(rounds) {
+			rounds = append(rounds, nil)
 		}
-		return nil
-	})
+		rounds[k] = append(rounds[k], event)
+	}
+	for _, round := range rounds {
+		measure(observe, "AppendTrackingEvents", true, func() (int, error) {
+			return len(round), t.tracker.AppendTrackingEvents(round)
+		})
+	}
 
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Query, "Produtos entregues por cliente", CLIENT_INSERT_SIZE, func() error {
-		_, err := cassandraRepo.GetDeliveredOrdersByClient(1)
-		if err != nil {
-			return err
+	lookups := shipments[:min(len(shipments), TRACKING_LOOKUPS)]
+	measure(observe, "GetTrackingHistory", false, func() (int, error) {
+		for i, shipment := range lookups {
+			if _, err := t.tracker.GetTrackingHistory(shipment.OrderID); err != nil {
+				return i, err
+			}
 		}
-		return nil
+		return len(lookups), nil
 	})
+}
 
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Query, "5 produtos mais vendidos", PRODUCT_INSERT_SIZE, func() error {
-		_, err := postgresRepo.Get5MostSoldProducts()
-		if err != nil {
-			return err
-		}
-		return nil
-	})
+// withoutAddresses copia os pedidos sem o endereço de entrega.
+func withoutAddresses(orders []model.Order) []model.Order {
+	orders = slices.Clone(orders)
+	for i := range orders {
+		orders[i].AddressID = nil
+	}
+	return orders
+}
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Query, "5 produtos mais vendidos", PRODUCT_INSERT_SIZE, func() error {
-		_, err := mongoRepo.Get5MostSoldProducts()
-		if err != nil {
-			return err
+// benchmarkAddresses lê os endereços dos primeiros ADDRESS_LOOKUPS clientes,
+// torna padrão o último endereço de cada um e mede a receita por UF.
+func benchmarkAddresses(t target, addresses []model.Address, observe func(repo.Call)) {
+	var clientIDs []uint
+	last := make(map[uint]uint)
+	for _, address := range addresses {
+		if _, ok := last[address.ClientID]; !ok {
+			if len(clientIDs) == ADDRESS_LOOKUPS {
+				continue
+			}
+			clientIDs = append(clientIDs, address.ClientID)
 		}
-		return nil
-	})
+		last[address.ClientID] = address.ID
+	}
 
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Query, "5 produtos mais vendidos", PRODUCT_INSERT_SIZE, func() error {
-		_, err := cassandraRepo.Get5MostSoldProducts()
-		if err != nil {
-			return err
+	measure(observe, "GetClientAddresses", false, func() (int, error) {
+		n := 0
+		for _, clientID := range clientIDs {
+			found, err := t.addresses.GetClientAddresses(clientID)
+			if err != nil {
+				return n, err
+			}
+			n += len(found)
 		}
-		return nil
+		return n, nil
 	})
 
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Query, "Pagamentos pix do último mês", PAYMENT_INSERT_SIZE, func() error {
-		_, err := postgresRepo.GetLastMonthPixPayments()
-		if err != nil {
-			return err
+	measure(observe, "SetDefaultAddress", true, func() (int, error) {
+		for i, clientID := range clientIDs {
+			if err := t.addresses.SetDefaultAddress(clientID, last[clientID]); err != nil {
+				return i, err
+			}
 		}
-		return nil
+		return len(clientIDs), nil
 	})
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Query, "Pagamentos pix do último mês", PAYMENT_INSERT_SIZE, func() error {
-		_, err := mongoRepo.GetLastMonthPixPayments()
-		if err != nil {
-			return err
-		}
-		return nil
+	measure(observe, "GetRevenueByState", false, func() (int, error) {
+		revenue, err := t.addresses.GetRevenueByState()
+		return len(revenue), err
 	})
+}
 
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Query, "Pagamentos pix do último mês", PAYMENT_INSERT_SIZE, func() error {
-		_, err := cassandraRepo.GetLastMonthPixPayments()
-		if err != nil {
-			return err
-		}
-		return nil
+// benchmarkCoupons grava os cupons e os pedidos com cupom, em COUPON_WORKERS
+// goroutines, e mede o resumo de uso de cada cupom. Pedidos recusados pelo
+// cupom, como os acima do limite de usos, não contam como erro; os itens dos
+// pedidos gravados são gravados em seguida.
+func benchmarkCoupons(t target, coupons []model.Coupon, orders []model.Order, observe func(repo.Call)) {
+	measure(observe, "BatchCreateCoupon", true, func() (int, error) {
+		return len(coupons), t.coupons.BatchCreateCoupon(coupons)
 	})
 
-	benchLogger.MeasureOperation(benchmark.Postgres, benchmark.Query, "Total gasto por cliente no último mês", CLIENT_INSERT_SIZE, func() error {
-		_, err := postgresRepo.GetClientTotalSpentByPeriod(1, time.Now().AddDate(0, -1, 0), time.Now())
-		if err != nil {
-			return err
+	var (
+		mu       sync.Mutex
+		items    []model.OrderItem
+		rejected int
+	)
+	measure(observe, "CreateOrderWithCoupon", true, func() (int, error) {
+		jobs := make(chan model.Order)
+		errs := make(chan error, COUPON_WORKERS)
+		for range COUPON_WORKERS {
+			go func() {
+				var err error
+				for order := range jobs {
+					if err != nil {
+						continue
+					}
+					var v *model.ValidationError
+					switch e := t.coupons.CreateOrderWithCoupon(&order); {
+					case e == nil:
+						mu.Lock()
+						items = append(items, order.Itens...)
+						mu.Unlock()
+					case errors.As(e, &v):
+						mu.Lock()
+						rejected++
+						mu.Unlock()
+					default:
+						err = e
+					}
+				}
+				errs <- err
+			}()
 		}
-		return nil
-	})
+		for _, order := range orders {
+			jobs <- order
+		}
+		close(jobs)
 
-	benchLogger.MeasureOperation(benchmark.MongoDB, benchmark.Query, "Total gasto por cliente no último mês", CLIENT_INSERT_SIZE, func() error {
-		_, err := mongoRepo.GetClientTotalSpentByPeriod(1, time.Now().AddDate(0, -1, 0), time.Now())
-		if err != nil {
-			return err
+		var err error
+		for range COUPON_WORKERS {
+			err = errors.Join(err, <-errs)
 		}
-		return nil
+		return len(orders), err
 	})
-
-	benchLogger.MeasureOperation(benchmark.Cassandra, benchmark.Query, "Total gasto por cliente no último mês", CLIENT_INSERT_SIZE, func() error {
-		_, err := cassandraRepo.GetClientTotalSpentByPeriod(1, time.Now().AddDate(0, -1, 0), time.Now())
-		if err != nil {
-			return err
+	log.Printf("%s: %d pedidos com cupom recusados de %d", t.db, rejected, len(orders))
+	t.repo.BatchCreateOrderItem(items)
+
+	measure(observe, "GetCouponUsage", false, func() (int, error) {
+		for i, coupon := range coupons {
+			if _, err := t.coupons.GetCouponUsage(coupon.Code); err != nil {
+				return i, err
+			}
 		}
-		return nil
+		return len(coupons), nil
 	})
-
 }
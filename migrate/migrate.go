@@ -0,0 +1,232 @@
+// Package migrate copia todos os dados de um backend para outro através da
+// camada de repositórios, gravando um checkpoint após cada lote para que uma
+// migração interrompida possa ser retomada.
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+)
+
+// Entidades na ordem em que são migradas, respeitando as chaves estrangeiras.
+// Os itens são uma etapa separada dos pedidos para que uma falha entre as duas
+// gravações não obrigue a regravar pedidos já copiados.
+const (
+	EntityClients   = "clientes"
+	EntityAddresses = "enderecos"
+	EntityProducts  = "produtos"
+	EntityOrders    = "pedidos"
+	EntityItems     = "itens_pedido"
+	EntityPayments  = "pagamentos"
+)
+
+var entities = []string{EntityClients, EntityAddresses, EntityProducts, EntityOrders, EntityItems, EntityPayments}
+
+// Checkpoint guarda o último ID copiado de cada entidade. É gravado após cada
+// lote, então no máximo o lote em andamento é repetido ao retomar.
+type Checkpoint struct {
+	Source   string          `json:"origem"`
+	Target   string          `json:"destino"`
+	LastID   map[string]uint `json:"ultimo_id"`
+	Migrated map[string]int  `json:"migrados"`
+	Done     map[string]bool `json:"concluido"`
+}
+
+// Progress é reportado após cada lote gravado no destino.
+type Progress struct {
+	Entity   string
+	Migrated int
+	LastID   uint
+	Done     bool
+}
+
+type Migrator struct {
+	source         repo.Exporter
+	target         repo.TechMarketRepository
+	batchSize      int
+	checkpointPath string
+	checkpoint     Checkpoint
+
+	// Addresses recebe os endereços. Por padrão é o próprio destino, quando
+	// ele implementa repo.AddressBook; um destino envolvido por decoradores
+	// precisa informar o repositório por baixo deles.
+	Addresses repo.AddressBook
+
+	// OnProgress recebe o andamento de cada entidade.
+	OnProgress func(Progress)
+}
+
+// NewMigrator prepara a cópia de source para target. Se já existir um
+// checkpoint para o mesmo par origem/destino, a migração continua de onde
+// parou; um checkpoint de outro par é rejeitado.
+func NewMigrator(sourceName string, source repo.Exporter, targetName string, target repo.TechMarketRepository) (*Migrator, error) {
+	config := config.LoadMigrationConfig()
+
+	m := &Migrator{
+		source:         source,
+		target:         target,
+		batchSize:      config.BatchSize,
+		checkpointPath: config.CheckpointPath,
+		checkpoint: Checkpoint{
+			Source:   sourceName,
+			Target:   targetName,
+			LastID:   make(map[string]uint),
+			Migrated: make(map[string]int),
+			Done:     make(map[string]bool),
+		},
+		OnProgress: func(Progress) {},
+	}
+	m.Addresses, _ = target.(repo.AddressBook)
+
+	data, err := os.ReadFile(m.checkpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler checkpoint %s: %w", m.checkpointPath, err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar checkpoint %s: %w", m.checkpointPath, err)
+	}
+	if checkpoint.Source != sourceName || checkpoint.Target != targetName {
+		return nil, fmt.Errorf("checkpoint %s pertence à migração %s -> %s", m.checkpointPath, checkpoint.Source, checkpoint.Target)
+	}
+	maps.Copy(m.checkpoint.LastID, checkpoint.LastID)
+	maps.Copy(m.checkpoint.Migrated, checkpoint.Migrated)
+	maps.Copy(m.checkpoint.Done, checkpoint.Done)
+	return m, nil
+}
+
+// Run copia as entidades que ainda não foram concluídas. Em caso de erro o
+// checkpoint reflete o último lote gravado com sucesso.
+func (m *Migrator) Run() error {
+	steps := map[string]func() error{
+		EntityClients: func() error {
+			return migrateEntity(m, EntityClients, m.source.ExportClients, clientID, all(m.target.BatchCreateClient))
+		},
+		EntityAddresses: func() error {
+			return migrateEntity(m, EntityAddresses, m.source.ExportAddresses, addressID, func(addresses []model.Address) (int, error) {
+				if m.Addresses == nil {
+					return 0, errors.New("o destino não guarda endereços")
+				}
+				return len(addresses), m.Addresses.BatchCreateAddress(addresses)
+			})
+		},
+		EntityProducts: func() error {
+			return migrateEntity(m, EntityProducts, m.source.ExportProducts, productID, all(m.target.BatchCreateProduct))
+		},
+		EntityOrders: func() error {
+			return migrateEntity(m, EntityOrders, m.source.ExportOrders, orderID, all(m.target.BatchCreateOrder))
+		},
+		EntityItems: func() error {
+			return migrateEntity(m, EntityItems, m.source.ExportOrders, orderID, func(orders []model.Order) (int, error) {
+				var items []model.OrderItem
+				for _, order := range orders {
+					items = append(items, order.Itens...)
+				}
+				if len(items) == 0 {
+					return 0, nil
+				}
+				return len(items), m.target.BatchCreateOrderItem(items)
+			})
+		},
+		EntityPayments: func() error {
+			return migrateEntity(m, EntityPayments, m.source.ExportPayments, paymentID, all(m.target.BatchCreatePayment))
+		},
+	}
+
+	for _, entity := range entities {
+		if m.checkpoint.Done[entity] {
+			continue
+		}
+		if err := steps[entity](); err != nil {
+			return fmt.Errorf("erro ao migrar %s: %w", entity, err)
+		}
+	}
+	return nil
+}
+
+// ResetCheckpoint apaga o checkpoint em MIGRATION_CHECKPOINT, fazendo a
+// próxima migração começar do início.
+func ResetCheckpoint() error {
+	path := config.LoadMigrationConfig().CheckpointPath
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func migrateEntity[T any](m *Migrator, entity string, export func(afterID uint, limit int) ([]T, error), id func(T) uint, write func([]T) (int, error)) error {
+	for {
+		batch, err := export(m.checkpoint.LastID[entity], m.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		// O destino pode alterar o lote (IDs gerados), então o último ID da
+		// origem é lido antes da gravação.
+		lastID := id(batch[len(batch)-1])
+		written, err := write(slices.Clone(batch))
+		if err != nil {
+			return err
+		}
+
+		m.checkpoint.LastID[entity] = lastID
+		m.checkpoint.Migrated[entity] += written
+		if err := m.save(); err != nil {
+			return err
+		}
+		m.OnProgress(Progress{Entity: entity, Migrated: m.checkpoint.Migrated[entity], LastID: lastID})
+	}
+
+	m.checkpoint.Done[entity] = true
+	if err := m.save(); err != nil {
+		return err
+	}
+	m.OnProgress(Progress{Entity: entity, Migrated: m.checkpoint.Migrated[entity], LastID: m.checkpoint.LastID[entity], Done: true})
+	return nil
+}
+
+// save grava o checkpoint em um arquivo temporário e o renomeia, para que uma
+// interrupção no meio da escrita não corrompa o checkpoint anterior.
+func (m *Migrator) save() error {
+	data, err := json.MarshalIndent(m.checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := m.checkpointPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, m.checkpointPath); err != nil {
+		return fmt.Errorf("erro ao gravar checkpoint: %w", err)
+	}
+	return nil
+}
+
+// all adapta um método Batch* para migrateEntity, que precisa saber quantos
+// registros foram gravados.
+func all[T any](write func([]T) error) func([]T) (int, error) {
+	return func(batch []T) (int, error) {
+		return len(batch), write(batch)
+	}
+}
+
+func clientID(c model.Client) uint   { return c.ID }
+func addressID(a model.Address) uint { return a.ID }
+func productID(p model.Product) uint { return p.ID }
+func orderID(o model.Order) uint     { return o.ID }
+func paymentID(p model.Payment) uint { return p.ID }
@@ -0,0 +1,224 @@
+package migrate_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"techmarket_showcase/config"
+	"techmarket_showcase/migrate"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/conformance"
+	"testing"
+	"time"
+)
+
+func setup(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SQLITE_PATH", filepath.Join(dir, "techmarket.db"))
+	t.Setenv("BOLT_PATH", filepath.Join(dir, "techmarket.bolt"))
+	t.Setenv("MIGRATION_CHECKPOINT", filepath.Join(dir, "checkpoint.json"))
+	// Lotes pequenos para exercitar a paginação com o conjunto da conformidade.
+	t.Setenv("MIGRATION_BATCH_SIZE", "2")
+}
+
+func TestMigrateSQLiteToBolt(t *testing.T) {
+	setup(t)
+	conformance.RunAfter(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewSQLiteRepository()
+	}, func(t *testing.T, loaded repo.TechMarketRepository) repo.TechMarketRepository {
+		target := repo.NewBoltRepository()
+		m, err := migrate.NewMigrator("sqlite", loaded.(repo.Exporter), "bolt", target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return target
+	})
+}
+
+func TestMigrateBoltToSQLite(t *testing.T) {
+	setup(t)
+	conformance.RunAfter(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewBoltRepository()
+	}, func(t *testing.T, loaded repo.TechMarketRepository) repo.TechMarketRepository {
+		target := repo.NewSQLiteRepository()
+		m, err := migrate.NewMigrator("bolt", loaded.(repo.Exporter), "sqlite", target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return target
+	})
+}
+
+func TestMigrateResumesFromCheckpoint(t *testing.T) {
+	setup(t)
+	conformance.RunAfter(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewSQLiteRepository()
+	}, func(t *testing.T, loaded repo.TechMarketRepository) repo.TechMarketRepository {
+		source := loaded.(repo.Exporter)
+		target := repo.NewBoltRepository()
+
+		faulty := repo.NewFaultyRepository(target, config.FaultConfig{
+			Methods: map[string]config.MethodFault{"BatchCreatePayment": {ErrorRate: 1}},
+		})
+		m, err := migrate.NewMigrator("sqlite", source, "bolt", faulty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Run(); !errors.Is(err, repo.ErrTransient) {
+			t.Fatalf("err = %v, esperado falha injetada nos pagamentos", err)
+		}
+
+		data, err := os.ReadFile(os.Getenv("MIGRATION_CHECKPOINT"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var checkpoint migrate.Checkpoint
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			t.Fatal(err)
+		}
+		if !checkpoint.Done[migrate.EntityItems] || checkpoint.Done[migrate.EntityPayments] {
+			t.Fatalf("checkpoint = %+v, esperado parar nos pagamentos", checkpoint)
+		}
+		if checkpoint.Migrated[migrate.EntityItems] != 7 {
+			t.Errorf("itens migrados = %d, esperado 7", checkpoint.Migrated[migrate.EntityItems])
+		}
+
+		// Outro par origem/destino não reaproveita o checkpoint.
+		if _, err := migrate.NewMigrator("postgres", source, "bolt", target); err == nil {
+			t.Error("checkpoint de outra migração foi aceito")
+		}
+
+		// Clientes, produtos, pedidos e itens já gravados não são repetidos: o
+		// bbolt rejeitaria emails e itens duplicados.
+		m, err = migrate.NewMigrator("sqlite", source, "bolt", target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return target
+	})
+}
+
+func TestMigrateAddresses(t *testing.T) {
+	setup(t)
+	source := repo.NewSQLiteRepository()
+	clients := []model.Client{
+		{ID: 1, Nome: "Ana Souza", Email: "ana.souza@techmarket.com", Phone: "11987654321", CreatedAt: time.Now(), CPF: "529.982.247-25"},
+	}
+	if err := source.BatchCreateClient(clients); err != nil {
+		t.Fatal(err)
+	}
+	// Três endereços em lotes de 2: o padrão fica no segundo lote.
+	addresses := []model.Address{
+		{ID: 1, ClientID: 1, PostalAddress: model.PostalAddress{CEP: "01310-100", Street: "Avenida Paulista", Number: "1000", City: "São Paulo", State: "SP"}},
+		{ID: 2, ClientID: 1, PostalAddress: model.PostalAddress{CEP: "20040-002", Street: "Rua da Assembleia", Number: "10", City: "Rio de Janeiro", State: "RJ"}},
+		{ID: 3, ClientID: 1, PostalAddress: model.PostalAddress{CEP: "30130-000", Street: "Avenida Afonso Pena", Number: "500", City: "Belo Horizonte", State: "MG"}, Default: true},
+	}
+	if err := source.BatchCreateAddress(addresses); err != nil {
+		t.Fatal(err)
+	}
+	product := model.Product{ID: 1, Name: "Tablet", Category: "Tablets", Price: model.Reais(1000), Stock: 1}
+	if err := source.BatchCreateProduct([]model.Product{product}); err != nil {
+		t.Fatal(err)
+	}
+	addressID := uint(2)
+	order := model.Order{
+		ID: 1, ClientID: 1, AddressID: &addressID, OrderDate: time.Now(), Status: "Pago", TotalValue: model.Reais(1000),
+		Itens: []model.OrderItem{{OrderID: 1, ProductID: 1, Quantity: 1, Product: product}},
+	}
+	if err := source.BatchCreateOrder([]model.Order{order}); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.BatchCreateOrderItem(order.Itens); err != nil {
+		t.Fatal(err)
+	}
+
+	target := repo.NewBoltRepository()
+	m, err := migrate.NewMigrator("sqlite", source, "bolt", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := target.GetClientAddresses(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := source.GetClientAddresses(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("endereços no destino = %+v, esperado %+v", got, want)
+	}
+
+	revenue, err := target.GetRevenueByState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revenue) != 1 || revenue[0].State != "RJ" || revenue[0].Revenue != model.Reais(1000) {
+		t.Errorf("receita por UF no destino = %+v, esperado o pedido no RJ", revenue)
+	}
+}
+
+// Os pedidos chegam ao destino com o cupom e o desconto, mas os cupons não são
+// copiados.
+func TestMigrateCouponOrders(t *testing.T) {
+	setup(t)
+	source := repo.NewSQLiteRepository()
+	client := model.Client{ID: 1, Nome: "Ana Souza", Email: "ana.souza@techmarket.com", Phone: "11987654321", CreatedAt: time.Now(), CPF: "529.982.247-25"}
+	if err := source.BatchCreateClient([]model.Client{client}); err != nil {
+		t.Fatal(err)
+	}
+	product := model.Product{ID: 1, Name: "Tablet", Category: "Tablets", Price: model.Reais(1000), Stock: 1}
+	if err := source.BatchCreateProduct([]model.Product{product}); err != nil {
+		t.Fatal(err)
+	}
+	coupon := model.Coupon{
+		Code: "DEZ10", Type: model.CouponPercentage, Percent: 10,
+		ValidFrom: time.Now().AddDate(0, 0, -1), ValidUntil: time.Now().AddDate(0, 0, 1), UsageLimit: 1,
+	}
+	if err := source.BatchCreateCoupon([]model.Coupon{coupon}); err != nil {
+		t.Fatal(err)
+	}
+	order := model.Order{
+		ID: 1, ClientID: 1, OrderDate: time.Now(), Status: model.OrderStatusDelivered, CouponCode: "DEZ10",
+		Itens: []model.OrderItem{{OrderID: 1, ProductID: 1, Quantity: 1, Product: product}},
+	}
+	if err := source.CreateOrderWithCoupon(&order); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.BatchCreateOrderItem(order.Itens); err != nil {
+		t.Fatal(err)
+	}
+
+	target := repo.NewBoltRepository()
+	m, err := migrate.NewMigrator("sqlite", source, "bolt", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	orders, err := target.GetDeliveredOrdersByClient(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].CouponCode != "DEZ10" || orders[0].Discount != model.Reais(100) || orders[0].TotalValue != model.Reais(900) {
+		t.Errorf("pedidos no destino = %+v, esperado o pedido 1 com o cupom DEZ10 e R$ 100 de desconto", orders)
+	}
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Os IDs são snowflakes de 63 bits, atribuídos antes da gravação para que o
+// mesmo registro tenha o mesmo ID em todos os bancos:
+//
+//	41 bits de milissegundos desde IDEpoch | 10 bits de nó | 12 bits de sequência
+//
+// Por caberem em um int64 positivo, são gravados como BIGINT no PostgreSQL e
+// no SQLite, como inteiro de 64 bits no _id do MongoDB, em big-endian no bbolt e
+// como texto decimal nas tabelas do Cassandra. Como crescem com o tempo, a
+// ordem por ID é também a ordem de criação.
+const (
+	idNodeBits     = 10
+	idSequenceBits = 12
+
+	MaxIDNode = 1<<idNodeBits - 1
+)
+
+// IDEpoch é o instante zero dos IDs.
+var IDEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type IDGenerator struct {
+	node uint64
+
+	mu       sync.Mutex
+	lastMS   int64
+	sequence uint64
+}
+
+// NewIDGenerator cria um gerador para o nó informado. Processos que gravam ao
+// mesmo tempo precisam usar nós diferentes para não gerar IDs repetidos.
+func NewIDGenerator(node int) *IDGenerator {
+	if node < 0 || node > MaxIDNode {
+		panic(fmt.Sprintf("Nó de ID inválido: %d (deve estar entre 0 e %d)", node, MaxIDNode))
+	}
+	return &IDGenerator{node: uint64(node)}
+}
+
+// Next retorna um novo ID. Se a sequência do milissegundo se esgotar, espera o
+// próximo milissegundo.
+func (g *IDGenerator) Next() uint {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Since(IDEpoch).Milliseconds()
+	if ms < g.lastMS {
+		// Relógio voltou: continua a partir do último instante usado.
+		ms = g.lastMS
+	}
+
+	if ms == g.lastMS {
+		g.sequence = (g.sequence + 1) & (1<<idSequenceBits - 1)
+		if g.sequence == 0 {
+			for ms <= g.lastMS {
+				time.Sleep(100 * time.Microsecond)
+				ms = time.Since(IDEpoch).Milliseconds()
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMS = ms
+
+	return uint(uint64(ms)<<(idNodeBits+idSequenceBits) | g.node<<idSequenceBits | g.sequence)
+}
+
+// IDTime retorna o instante em que o ID foi gerado.
+func IDTime(id uint) time.Time {
+	ms := int64(id >> (idNodeBits + idSequenceBits))
+	return IDEpoch.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// FormatID converte o ID para o texto usado como chave no Cassandra.
+func FormatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// ParseID converte de volta um ID gravado por FormatID.
+func ParseID(s string) (uint, error) {
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("id inválido %q: %w", s, err)
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,65 @@
+package model_test
+
+import (
+	"sync"
+	"techmarket_showcase/model"
+	"testing"
+	"time"
+)
+
+func TestIDGeneratorUniqueAndIncreasing(t *testing.T) {
+	ids := model.NewIDGenerator(7)
+
+	// Mais IDs do que cabem na sequência de um milissegundo.
+	var last uint
+	for range 10000 {
+		id := ids.Next()
+		if id <= last {
+			t.Fatalf("ID %d não é maior que o anterior %d", id, last)
+		}
+		last = id
+	}
+
+	if generated := model.IDTime(last); time.Since(generated) > time.Minute || time.Until(generated) > time.Millisecond {
+		t.Errorf("IDTime(%d) = %v, esperado um instante próximo de agora", last, generated)
+	}
+}
+
+func TestIDGeneratorNodes(t *testing.T) {
+	// Geradores de nós diferentes nunca repetem IDs, mesmo no mesmo milissegundo.
+	var (
+		mu   sync.Mutex
+		seen = make(map[uint]int)
+		wg   sync.WaitGroup
+	)
+	for node := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := model.NewIDGenerator(node)
+			for range 2000 {
+				id := ids.Next()
+				mu.Lock()
+				if other, ok := seen[id]; ok {
+					t.Errorf("ID %d gerado pelos nós %d e %d", id, other, node)
+				}
+				seen[id] = node
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFormatParseID(t *testing.T) {
+	id := model.NewIDGenerator(model.MaxIDNode).Next()
+
+	parsed, err := model.ParseID(model.FormatID(id))
+	if err != nil || parsed != id {
+		t.Fatalf("ParseID(FormatID(%d)) = %d, %v", id, parsed, err)
+	}
+
+	if _, err := model.ParseID("abc"); err == nil {
+		t.Error("ParseID aceitou um ID inválido")
+	}
+}
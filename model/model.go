@@ -2,32 +2,63 @@ package model
 
 import "time"
 
+// Valores gravados pelo seed e usados como filtro pelas consultas de todos os
+// repositórios.
+const (
+	OrderStatusDelivered  = "Entregue"
+	OrderStatusCancelled  = "Cancelado"
+	PaymentTypePix        = "PIX"
+	PaymentTypeCreditCard = "Cartão de Crédito"
+
+	PaymentStatusApproved = "Aprovado"
+	// Status de pagamentos e pedidos após um estorno; ver RefundStatus.
+	StatusRefunded          = "Estornado"
+	StatusPartiallyRefunded = "Estornado Parcialmente"
+)
+
 type Client struct {
-	ID        uint      `gorm:"primaryKey;column:id"`
-	Nome      string    `gorm:"column:nome"`
-	Email     string    `gorm:"column:email"`
-	Phone     string    `gorm:"column:telefone"`
-	CreatedAt time.Time `gorm:"column:data_cadastro"`
-	CPF       string    `gorm:"column:cpf"`
+	ID        uint      `gorm:"primaryKey;column:id" bson:"_id"`
+	Nome      string    `gorm:"column:nome" bson:"nome"`
+	Email     string    `gorm:"column:email" bson:"email"`
+	Phone     string    `gorm:"column:telefone" bson:"telefone"`
+	CreatedAt time.Time `gorm:"column:data_cadastro" bson:"data_cadastro"`
+	CPF       string    `gorm:"column:cpf" bson:"cpf"`
 }
 
 type Product struct {
-	ID       uint    `gorm:"primaryKey;column:id"`
-	Name     string  `gorm:"column:nome"`
-	Category string  `gorm:"column:categoria"`
-	Price    float64 `gorm:"column:preco"`
-	Stock    int     `gorm:"column:estoque"`
+	ID       uint   `gorm:"primaryKey;column:id" bson:"_id"`
+	Name     string `gorm:"column:nome" bson:"nome"`
+	Category string `gorm:"column:categoria" bson:"categoria"`
+	Price    Money  `gorm:"column:preco" bson:"preco"`
+	Stock    int    `gorm:"column:estoque" bson:"estoque"`
 }
 
+// Order referencia em AddressID um endereço de entrega do próprio cliente;
+// nil nos pedidos sem endereço, como os gravados antes do cadastro de
+// endereços. CouponCode é o cupom aplicado ao pedido, vazio quando não há, e
+// Discount o desconto que ele deu, já abatido de TotalValue.
 type Order struct {
 	ID         uint        `gorm:"primaryKey;column:id"`
 	ClientID   uint        `gorm:"column:id_cliente"`
+	AddressID  *uint       `gorm:"column:id_endereco"`
 	OrderDate  time.Time   `gorm:"column:data_pedido"`
 	Status     string      `gorm:"column:status"`
-	TotalValue float64     `gorm:"column:valor_total"`
+	TotalValue Money       `gorm:"column:valor_total"`
+	CouponCode string      `gorm:"column:codigo_cupom"`
+	Discount   Money       `gorm:"column:desconto"`
 	Itens      []OrderItem `gorm:"-"`
 }
 
+// Subtotal soma os itens de Itens pelo preço unitário em Product.Price, antes
+// do desconto.
+func (o Order) Subtotal() Money {
+	var subtotal Money
+	for _, item := range o.Itens {
+		subtotal += item.Product.Price.Mul(item.Quantity)
+	}
+	return subtotal
+}
+
 type OrderItem struct {
 	OrderID   uint    `gorm:"primaryKey;column:id_pedido"`
 	ProductID uint    `gorm:"primaryKey;column:id_produto"`
@@ -35,10 +66,168 @@ type OrderItem struct {
 	Product   Product `gorm:"-"`
 }
 
+// Payment guarda o cliente do pedido para que o total gasto por cliente seja
+// somado sem passar pelos pedidos. Amount é o valor pago, que pode ser só parte
+// do total do pedido, e Installments o número de parcelas: mais de uma apenas
+// em PaymentTypeCreditCard, 1 nos pagamentos à vista. Refunded acumula os
+// estornos do pagamento e é descontado do total gasto.
 type Payment struct {
-	ID          uint      `gorm:"primaryKey;column:id"`
-	OrderID     uint      `gorm:"column:id_pedido"`
-	Type        string    `gorm:"column:tipo"`
-	Status      string    `gorm:"column:status"`
-	PaymentDate time.Time `gorm:"column:data_pagamento"`
+	ID           uint      `gorm:"primaryKey;column:id" bson:"_id"`
+	OrderID      uint      `gorm:"column:id_pedido" bson:"pedido_id"`
+	ClientID     uint      `gorm:"column:id_cliente" bson:"id_cliente"`
+	Type         string    `gorm:"column:tipo" bson:"tipo"`
+	Status       string    `gorm:"column:status" bson:"status"`
+	Amount       Money     `gorm:"column:valor" bson:"valor"`
+	Installments int       `gorm:"column:parcelas" bson:"parcelas"`
+	Refunded     Money     `gorm:"column:valor_estornado" bson:"valor_estornado"`
+	PaymentDate  time.Time `gorm:"column:data_pagamento" bson:"data_pagamento"`
+}
+
+// Refund é um estorno, total ou parcial, de um pagamento. OrderID e ClientID
+// são copiados do pagamento pelo repositório que grava o estorno.
+type Refund struct {
+	ID         uint      `gorm:"primaryKey;column:id" bson:"_id"`
+	PaymentID  uint      `gorm:"column:id_pagamento" bson:"id_pagamento"`
+	OrderID    uint      `gorm:"column:id_pedido" bson:"id_pedido"`
+	ClientID   uint      `gorm:"column:id_cliente" bson:"id_cliente"`
+	Amount     Money     `gorm:"column:valor" bson:"valor"`
+	Reason     string    `gorm:"column:motivo" bson:"motivo"`
+	RefundDate time.Time `gorm:"column:data_estorno" bson:"data_estorno"`
+}
+
+// RefundStatus é o status de um pagamento ou pedido de valor total com
+// refunded já estornado: StatusRefunded quando nada resta, senão
+// StatusPartiallyRefunded.
+func RefundStatus(total, refunded Money) string {
+	if refunded >= total {
+		return StatusRefunded
+	}
+	return StatusPartiallyRefunded
+}
+
+// Review é a avaliação de um produto por um cliente, com nota de 1 a 5
+// (MinRating a MaxRating) e um comentário opcional.
+type Review struct {
+	ID        uint      `gorm:"primaryKey;column:id" bson:"_id"`
+	ProductID uint      `gorm:"column:id_produto" bson:"id_produto"`
+	ClientID  uint      `gorm:"column:id_cliente" bson:"id_cliente"`
+	Rating    int       `gorm:"column:nota" bson:"nota"`
+	Comment   string    `gorm:"column:comentario" bson:"comentario"`
+	CreatedAt time.Time `gorm:"column:data_avaliacao" bson:"data_avaliacao"`
+}
+
+const (
+	MinRating = 1
+	MaxRating = 5
+	// MaxCommentLength é o tamanho máximo do comentário, em caracteres.
+	MaxCommentLength = 2000
+)
+
+// CategoryRating resume as avaliações dos produtos de uma categoria. Os bancos
+// retornam a contagem e a soma das notas, e a média é calculada em Average,
+// para que seja a mesma em todos eles.
+type CategoryRating struct {
+	Category  string `gorm:"column:categoria" bson:"_id"`
+	Reviews   int    `gorm:"column:avaliacoes" bson:"avaliacoes"`
+	RatingSum int    `gorm:"column:soma_notas" bson:"soma_notas"`
+}
+
+func (c CategoryRating) Average() float64 {
+	if c.Reviews == 0 {
+		return 0
+	}
+	return float64(c.RatingSum) / float64(c.Reviews)
+}
+
+// Status dos eventos de rastreamento gravados pelo seed. Um envio entregue
+// termina em OrderStatusDelivered.
+const (
+	TrackingPosted         = "Postado"
+	TrackingInTransit      = "Em Trânsito"
+	TrackingOutForDelivery = "Saiu para Entrega"
+	TrackingFailedDelivery = "Entrega Não Realizada"
+)
+
+// PostalAddress é um endereço no formato dos Correios: CEP 00000-000 e UF com
+// a sigla do estado.
+type PostalAddress struct {
+	CEP        string `gorm:"column:cep" bson:"cep"`
+	Street     string `gorm:"column:logradouro" bson:"logradouro"`
+	Number     string `gorm:"column:numero" bson:"numero"`
+	Complement string `gorm:"column:complemento" bson:"complemento"`
+	City       string `gorm:"column:cidade" bson:"cidade"`
+	State      string `gorm:"column:uf" bson:"uf"`
+}
+
+// Address é um endereço de entrega do cadastro do cliente. Um cliente pode ter
+// vários endereços, e no máximo um deles é o padrão (Default).
+type Address struct {
+	ID            uint `gorm:"primaryKey;column:id" bson:"_id"`
+	ClientID      uint `gorm:"column:id_cliente" bson:"id_cliente"`
+	PostalAddress `gorm:"embedded" bson:",inline"`
+	Default       bool `gorm:"column:padrao" bson:"padrao"`
+}
+
+// StateRevenue resume os pedidos com endereço de entrega em uma UF: a
+// quantidade e a soma de valor_total, sem descontar estornos. Pedidos
+// cancelados ou sem endereço não entram.
+type StateRevenue struct {
+	State   string `gorm:"column:uf" bson:"_id"`
+	Orders  int    `gorm:"column:pedidos" bson:"pedidos"`
+	Revenue Money  `gorm:"column:receita" bson:"receita"`
+}
+
+// Shipment é um volume de um pedido entregue por uma transportadora; um pedido
+// pode ser dividido em vários envios. Address é uma cópia do endereço de
+// entrega no momento do envio. Events só é preenchido na leitura, em ordem de
+// data: os eventos são gravados à parte, à medida que chegam da
+// transportadora.
+type Shipment struct {
+	ID           uint            `gorm:"primaryKey;column:id" bson:"_id"`
+	OrderID      uint            `gorm:"column:id_pedido" bson:"pedido_id"`
+	Carrier      string          `gorm:"column:transportadora" bson:"transportadora"`
+	TrackingCode string          `gorm:"column:codigo_rastreio" bson:"codigo_rastreio"`
+	Address      PostalAddress   `gorm:"embedded" bson:"endereco"`
+	ShippedAt    time.Time       `gorm:"column:data_envio" bson:"data_envio"`
+	Events       []TrackingEvent `gorm:"-" bson:"eventos"`
+}
+
+// TrackingEvent é um evento da linha do tempo de um envio.
+type TrackingEvent struct {
+	ShipmentID  uint      `gorm:"column:id_envio" bson:"id_envio"`
+	Status      string    `gorm:"column:status" bson:"status"`
+	Location    string    `gorm:"column:local" bson:"local"`
+	Description string    `gorm:"column:descricao" bson:"descricao"`
+	OccurredAt  time.Time `gorm:"column:data_evento" bson:"data_evento"`
+}
+
+// Tipos de cupom: CouponPercentage desconta Percent por cento do valor dos
+// itens elegíveis e CouponFixed desconta Amount, limitado a esse valor.
+const (
+	CouponPercentage = "Percentual"
+	CouponFixed      = "Valor Fixo"
+)
+
+// Coupon é um cupom de desconto, identificado pelo código que o cliente
+// digita. Vale para pedidos feitos de ValidFrom até ValidUntil, com valor dos
+// itens de ao menos MinOrderValue, e cada cliente pode usá-lo até UsageLimit
+// vezes. Com Categories o desconto incide apenas sobre os itens dessas
+// categorias; vazio, sobre todos.
+type Coupon struct {
+	Code          string    `gorm:"primaryKey;column:codigo" bson:"_id"`
+	Type          string    `gorm:"column:tipo" bson:"tipo"`
+	Percent       int       `gorm:"column:percentual" bson:"percentual"`
+	Amount        Money     `gorm:"column:valor" bson:"valor"`
+	MinOrderValue Money     `gorm:"column:valor_minimo" bson:"valor_minimo"`
+	ValidFrom     time.Time `gorm:"column:inicio_validade" bson:"inicio_validade"`
+	ValidUntil    time.Time `gorm:"column:fim_validade" bson:"fim_validade"`
+	UsageLimit    int       `gorm:"column:limite_por_cliente" bson:"limite_por_cliente"`
+	Categories    []string  `gorm:"-" bson:"categorias"`
+}
+
+// CouponUsage resume os pedidos feitos com um cupom: a quantidade e a soma dos
+// descontos.
+type CouponUsage struct {
+	Orders   int   `gorm:"column:pedidos" bson:"pedidos"`
+	Discount Money `gorm:"column:desconto" bson:"desconto"`
 }
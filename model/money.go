@@ -0,0 +1,215 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/inf.v0"
+)
+
+// Money é um valor exato em centavos da moeda Currency, a única usada pelo
+// TechMarket. Os bancos guardam só o número, com duas casas decimais:
+// DECIMAL(10, 2) no PostgreSQL e no SQLite, Decimal128 no MongoDB e decimal no
+// Cassandra. Colunas double antigas do Cassandra continuam aceitas, já que
+// qualquer valor com dois decimais volta ao mesmo número de centavos.
+type Money int64
+
+// Currency é o código ISO 4217 dos valores de Money.
+const Currency = "BRL"
+
+// Reais converte um valor inteiro em reais.
+func Reais(reais int64) Money {
+	return Money(reais * 100)
+}
+
+// ParseMoney lê um número decimal como "1234.5" ou "-0.99", arredondando para
+// o centavo mais próximo (metade para longe do zero).
+func ParseMoney(s string) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("valor monetário inválido: %q", s)
+	}
+	return moneyFromRat(r)
+}
+
+func moneyFromRat(r *big.Rat) (Money, error) {
+	cents := new(big.Rat).Mul(r, big.NewRat(100, 1))
+
+	// Soma meio centavo na direção do sinal e trunca.
+	shifted := cents.Add(cents, big.NewRat(int64(cents.Sign()), 2))
+	rounded := new(big.Int).Quo(shifted.Num(), shifted.Denom())
+	if !rounded.IsInt64() {
+		return 0, fmt.Errorf("valor monetário fora do limite: %s", r.FloatString(2))
+	}
+	return Money(rounded.Int64()), nil
+}
+
+func moneyFromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// Cents retorna o valor em centavos.
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// Mul multiplica o valor por uma quantidade, como o preço unitário de um item.
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// Decimal formata o valor com duas casas e ponto decimal, como é gravado nos
+// bancos: "1234.50".
+func (m Money) Decimal() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// String formata o valor em reais: "R$ 1.234,50".
+func (m Money) String() string {
+	decimal := m.Decimal()
+	sign := ""
+	if strings.HasPrefix(decimal, "-") {
+		sign, decimal = "-", decimal[1:]
+	}
+	integer, fraction, _ := strings.Cut(decimal, ".")
+
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+	return fmt.Sprintf("%sR$ %s,%s", sign, grouped.String(), fraction)
+}
+
+// Value grava o valor como texto decimal, que o PostgreSQL converte para
+// NUMERIC sem passar por ponto flutuante.
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+// Scan lê NUMERIC do PostgreSQL (texto) e os REAL ou INTEGER em que o SQLite
+// guarda colunas DECIMAL. NULL, como um SUM sem linhas, vira zero.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Reais(v)
+	case float64:
+		*m = moneyFromFloat(v)
+	case []byte:
+		return m.parse(string(v))
+	case string:
+		return m.parse(v)
+	default:
+		return fmt.Errorf("não é possível ler %T como valor monetário", src)
+	}
+	return nil
+}
+
+func (m *Money) parse(s string) error {
+	value, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = value
+	return nil
+}
+
+// MarshalJSON grava o valor como número com duas casas, usado nos itens JSON
+// do Cassandra e nos registros do bbolt.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON aceita números, inclusive os float64 gravados antes de Money.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		*m = 0
+		return nil
+	}
+	return m.parse(s)
+}
+
+// MarshalBSONValue grava o valor como Decimal128, que o $sum do MongoDB soma
+// sem arredondar.
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	dec, err := primitive.ParseDecimal128(m.Decimal())
+	if err != nil {
+		return 0, nil, err
+	}
+	return bson.MarshalValue(dec)
+}
+
+// UnmarshalBSONValue lê Decimal128 e também os double e inteiros gravados
+// antes de Money.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		*m = 0
+	case bsontype.Decimal128:
+		return m.parse(raw.Decimal128().String())
+	case bsontype.Double:
+		*m = moneyFromFloat(raw.Double())
+	case bsontype.Int32:
+		*m = Reais(int64(raw.Int32()))
+	case bsontype.Int64:
+		*m = Reais(raw.Int64())
+	default:
+		return fmt.Errorf("não é possível ler BSON %s como valor monetário", t)
+	}
+	return nil
+}
+
+// MarshalCQL grava colunas decimal com escala 2 e, nas tabelas que ainda
+// usam double, o valor em reais.
+func (m Money) MarshalCQL(info gocql.TypeInfo) ([]byte, error) {
+	switch info.Type() {
+	case gocql.TypeDecimal:
+		return gocql.Marshal(info, inf.NewDec(int64(m), 2))
+	case gocql.TypeDouble:
+		return gocql.Marshal(info, float64(m)/100)
+	}
+	return nil, fmt.Errorf("não é possível gravar valor monetário em coluna %s", info.Type())
+}
+
+func (m *Money) UnmarshalCQL(info gocql.TypeInfo, data []byte) error {
+	if len(data) == 0 {
+		*m = 0
+		return nil
+	}
+
+	switch info.Type() {
+	case gocql.TypeDecimal:
+		var dec inf.Dec
+		if err := gocql.Unmarshal(info, data, &dec); err != nil {
+			return err
+		}
+		return m.parse(dec.String())
+	case gocql.TypeDouble:
+		var value float64
+		if err := gocql.Unmarshal(info, data, &value); err != nil {
+			return err
+		}
+		*m = moneyFromFloat(value)
+		return nil
+	}
+	return fmt.Errorf("não é possível ler coluna %s como valor monetário", info.Type())
+}
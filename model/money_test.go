@@ -0,0 +1,187 @@
+package model_test
+
+import (
+	"encoding/json"
+	"techmarket_showcase/model"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := map[string]model.Money{
+		"1234.5":   123450,
+		"0.1":      10,
+		"-0.99":    -99,
+		"10":       1000,
+		"2.005":    201,
+		"-2.005":   -201,
+		"19.994":   1999,
+		"1e3":      100000,
+		" 7.30 ":   730,
+		"99999.99": 9999999,
+	}
+	for s, want := range cases {
+		got, err := model.ParseMoney(s)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q): %v", s, err)
+		}
+		if got != want {
+			t.Errorf("ParseMoney(%q) = %d, esperado %d", s, got, want)
+		}
+	}
+
+	if _, err := model.ParseMoney("R$ 10"); err == nil {
+		t.Error("ParseMoney aceitou um valor inválido")
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	cases := []struct {
+		money   model.Money
+		decimal string
+		display string
+	}{
+		{0, "0.00", "R$ 0,00"},
+		{5, "0.05", "R$ 0,05"},
+		{123450, "1234.50", "R$ 1.234,50"},
+		{-99, "-0.99", "-R$ 0,99"},
+		{model.Reais(1234567), "1234567.00", "R$ 1.234.567,00"},
+	}
+	for _, c := range cases {
+		if got := c.money.Decimal(); got != c.decimal {
+			t.Errorf("Decimal(%d) = %q, esperado %q", c.money, got, c.decimal)
+		}
+		if got := c.money.String(); got != c.display {
+			t.Errorf("String(%d) = %q, esperado %q", c.money, got, c.display)
+		}
+	}
+}
+
+func TestMoneySumIsExact(t *testing.T) {
+	// Em float64, dez vezes 0.10 não soma 1.00.
+	var total model.Money
+	for range 10 {
+		total += 10
+	}
+	if total != model.Reais(1) {
+		t.Errorf("total = %s, esperado R$ 1,00", total)
+	}
+	if got := model.Money(19999).Mul(3); got != 59997 {
+		t.Errorf("Mul = %d, esperado 59997", got)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	cases := []struct {
+		src  any
+		want model.Money
+	}{
+		{nil, 0},
+		{"20599.97", 2059997},
+		{[]byte("0.10"), 10},
+		{float64(20599.97), 2059997},
+		{int64(300), model.Reais(300)},
+	}
+	for _, c := range cases {
+		var got model.Money
+		if err := got.Scan(c.src); err != nil {
+			t.Fatalf("Scan(%#v): %v", c.src, err)
+		}
+		if got != c.want {
+			t.Errorf("Scan(%#v) = %d, esperado %d", c.src, got, c.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Preco model.Money `json:"preco"`
+	}{2059997})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"preco":20599.97}` {
+		t.Errorf("json = %s", data)
+	}
+
+	// Registros gravados antes de Money guardavam float64.
+	var decoded struct {
+		Preco model.Money `json:"preco"`
+	}
+	if err := json.Unmarshal([]byte(`{"preco":199.99}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Preco != 19999 {
+		t.Errorf("preco = %d, esperado 19999", decoded.Preco)
+	}
+}
+
+func TestMoneyBSON(t *testing.T) {
+	data, err := bson.Marshal(bson.M{"preco": model.Money(2059997)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind := bson.Raw(data).Lookup("preco").Type; kind != bson.TypeDecimal128 {
+		t.Fatalf("preco gravado como %s, esperado Decimal128", kind)
+	}
+
+	var decoded struct {
+		Preco model.Money `bson:"preco"`
+	}
+	if err := bson.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Preco != 2059997 {
+		t.Errorf("preco = %d, esperado 2059997", decoded.Preco)
+	}
+
+	legacy, _ := bson.Marshal(bson.M{"preco": 199.99})
+	if err := bson.Unmarshal(legacy, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Preco != 19999 {
+		t.Errorf("preco double = %d, esperado 19999", decoded.Preco)
+	}
+}
+
+func TestMoneyCQL(t *testing.T) {
+	for _, kind := range []gocql.Type{gocql.TypeDecimal, gocql.TypeDouble} {
+		info := gocql.NewNativeType(4, kind, "")
+		data, err := gocql.Marshal(info, model.Money(2059997))
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+
+		var decoded model.Money
+		if err := gocql.Unmarshal(info, data, &decoded); err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if decoded != 2059997 {
+			t.Errorf("%s: valor = %d, esperado 2059997", kind, decoded)
+		}
+	}
+}
+
+// O pgx recebe Money tanto pelo GORM quanto pelo COPY e precisa gravá-lo como
+// NUMERIC exato, nos formatos texto e binário.
+func TestMoneyPostgresNumeric(t *testing.T) {
+	m := pgtype.NewMap()
+	for _, format := range []int16{pgtype.TextFormatCode, pgtype.BinaryFormatCode} {
+		buf, err := m.Encode(pgtype.NumericOID, format, model.Money(2059997), nil)
+		if err != nil {
+			t.Fatalf("formato %d: %v", format, err)
+		}
+
+		var decoded pgtype.Numeric
+		if err := m.Scan(pgtype.NumericOID, format, buf, &decoded); err != nil {
+			t.Fatalf("formato %d: %v", format, err)
+		}
+		value, _ := decoded.Value()
+		if got, err := model.ParseMoney(value.(string)); err != nil || got != 2059997 {
+			t.Errorf("formato %d: numeric = %v, esperado 20599.97", format, value)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+package model
+
+import (
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError descreve o problema de um campo, identificado pelo nome da coluna
+// usada nos bancos.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError reúne todos os campos inválidos de um registro. Entity é o
+// nome da tabela ou coleção e ID o identificador informado, que pode ser zero
+// quando o banco ainda vai atribuí-lo. Registros identificados por texto, como
+// os cupons, preenchem Key em vez de ID.
+type ValidationError struct {
+	Entity string
+	ID     uint
+	Key    string
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		fields[i] = field.Error()
+	}
+	id := fmt.Sprint(e.ID)
+	if e.Key != "" {
+		id = fmt.Sprintf("%q", e.Key)
+	}
+	return fmt.Sprintf("%s %s inválido: %s", e.Entity, id, strings.Join(fields, "; "))
+}
+
+// Field retorna o erro do campo informado, se houver.
+func (e *ValidationError) Field(name string) (FieldError, bool) {
+	for _, field := range e.Fields {
+		if field.Field == name {
+			return field, true
+		}
+	}
+	return FieldError{}, false
+}
+
+// Add registra um problema no campo informado.
+func (e *ValidationError) Add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err retorna e se algum campo foi registrado e nil caso contrário.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// CPFCheckDigits calcula os dois dígitos verificadores dos nove primeiros
+// dígitos de um CPF.
+func CPFCheckDigits(base string) string {
+	digits := make([]int, 0, 11)
+	for _, r := range base {
+		digits = append(digits, int(r-'0'))
+	}
+
+	for len(digits) < 11 {
+		sum := 0
+		for i, digit := range digits {
+			sum += digit * (len(digits) + 1 - i)
+		}
+		d := 11 - (sum % 11)
+		if d >= 10 {
+			d = 0
+		}
+		digits = append(digits, d)
+	}
+	return fmt.Sprintf("%d%d", digits[9], digits[10])
+}
+
+// FormatCPF formata os onze dígitos de um CPF como 000.000.000-00.
+func FormatCPF(digits string) string {
+	return digits[0:3] + "." + digits[3:6] + "." + digits[6:9] + "-" + digits[9:11]
+}
+
+// ValidCPF informa se cpf está no formato 000.000.000-00 e tem dígitos
+// verificadores corretos. Sequências de um único dígito, como 111.111.111-11,
+// passam no cálculo mas não são CPFs válidos.
+func ValidCPF(cpf string) bool {
+	if len(cpf) != 14 || cpf[3] != '.' || cpf[7] != '.' || cpf[11] != '-' {
+		return false
+	}
+	digits := cpf[0:3] + cpf[4:7] + cpf[8:11] + cpf[12:14]
+	if !onlyDigits(digits) || strings.Count(digits, digits[:1]) == len(digits) {
+		return false
+	}
+	return CPFCheckDigits(digits[:9]) == digits[9:]
+}
+
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return false
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return strings.Contains(domain, ".")
+}
+
+func onlyDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func (c Client) Validate() error {
+	v := &ValidationError{Entity: "cliente", ID: c.ID}
+	if strings.TrimSpace(c.Nome) == "" {
+		v.Add("nome", "obrigatório")
+	}
+	if !validEmail(c.Email) {
+		v.Add("email", "formato inválido: %q", c.Email)
+	}
+	if !ValidCPF(c.CPF) {
+		v.Add("cpf", "inválido: %q", c.CPF)
+	}
+	if c.Phone != "" && (!onlyDigits(c.Phone) || len(c.Phone) < 10 || len(c.Phone) > 11) {
+		v.Add("telefone", "deve ter DDD e 8 ou 9 dígitos: %q", c.Phone)
+	}
+	return v.Err()
+}
+
+func (p Product) Validate() error {
+	v := &ValidationError{Entity: "produto", ID: p.ID}
+	if strings.TrimSpace(p.Name) == "" {
+		v.Add("nome", "obrigatório")
+	}
+	if strings.TrimSpace(p.Category) == "" {
+		v.Add("categoria", "obrigatória")
+	}
+	if p.Price < 0 {
+		v.Add("preco", "não pode ser negativo: %s", p.Price)
+	}
+	if p.Stock < 0 {
+		v.Add("estoque", "não pode ser negativo: %d", p.Stock)
+	}
+	return v.Err()
+}
+
+// Validate confere o pedido e os itens em Itens, que devem existir: um pedido
+// sem itens não tem o que cobrar.
+func (o Order) Validate() error {
+	v := &ValidationError{Entity: "pedido", ID: o.ID}
+	if o.ClientID == 0 {
+		v.Add("id_cliente", "obrigatório")
+	}
+	if o.OrderDate.IsZero() {
+		v.Add("data_pedido", "obrigatória")
+	}
+	if strings.TrimSpace(o.Status) == "" {
+		v.Add("status", "obrigatório")
+	}
+	if o.TotalValue < 0 {
+		v.Add("valor_total", "não pode ser negativo: %s", o.TotalValue)
+	}
+	if o.AddressID != nil && *o.AddressID == 0 {
+		v.Add("id_endereco", "deve ser nil ou um ID de endereço")
+	}
+	switch {
+	case o.Discount < 0:
+		v.Add("desconto", "não pode ser negativo: %s", o.Discount)
+	case o.Discount > 0 && o.CouponCode == "":
+		v.Add("desconto", "só pode ser dado por um cupom")
+	}
+	if len(o.Itens) == 0 {
+		v.Add("itens", "o pedido precisa de ao menos um item")
+	}
+
+	seen := make(map[uint]bool)
+	for i, item := range o.Itens {
+		if item.OrderID != 0 && o.ID != 0 && item.OrderID != o.ID {
+			v.Add(fmt.Sprintf("itens[%d].id_pedido", i), "pertence ao pedido %d", item.OrderID)
+		}
+		if seen[item.ProductID] {
+			v.Add(fmt.Sprintf("itens[%d].id_produto", i), "produto %d repetido no pedido", item.ProductID)
+		}
+		seen[item.ProductID] = true
+		item.addErrors(v, fmt.Sprintf("itens[%d].", i))
+	}
+	return v.Err()
+}
+
+func (i OrderItem) Validate() error {
+	v := &ValidationError{Entity: "item_pedido", ID: i.OrderID}
+	if i.OrderID == 0 {
+		v.Add("id_pedido", "obrigatório")
+	}
+	i.addErrors(v, "")
+	return v.Err()
+}
+
+func (i OrderItem) addErrors(v *ValidationError, prefix string) {
+	if i.ProductID == 0 {
+		v.Add(prefix+"id_produto", "obrigatório")
+	}
+	if i.Quantity <= 0 {
+		v.Add(prefix+"quantidade", "deve ser positiva: %d", i.Quantity)
+	}
+	if i.Product.Price < 0 {
+		v.Add(prefix+"preco_unitario", "não pode ser negativo: %s", i.Product.Price)
+	}
+}
+
+// Validate confere apenas os campos do pagamento. Que o pedido existe e é do
+// mesmo cliente depende do banco e é verificado pelo repositório.
+func (p Payment) Validate() error {
+	v := &ValidationError{Entity: "pagamento", ID: p.ID}
+	if p.OrderID == 0 {
+		v.Add("id_pedido", "obrigatório")
+	}
+	if p.ClientID == 0 {
+		v.Add("id_cliente", "obrigatório")
+	}
+	if strings.TrimSpace(p.Type) == "" {
+		v.Add("tipo", "obrigatório")
+	}
+	if strings.TrimSpace(p.Status) == "" {
+		v.Add("status", "obrigatório")
+	}
+	if p.Amount <= 0 {
+		v.Add("valor", "deve ser positivo: %s", p.Amount)
+	}
+	switch {
+	case p.Installments < 1:
+		v.Add("parcelas", "deve ser ao menos 1: %d", p.Installments)
+	case p.Installments > 1 && p.Type != PaymentTypeCreditCard:
+		v.Add("parcelas", "apenas %s pode ser parcelado: %d", PaymentTypeCreditCard, p.Installments)
+	}
+	if p.Refunded < 0 || p.Refunded > p.Amount {
+		v.Add("valor_estornado", "deve estar entre zero e o valor pago: %s", p.Refunded)
+	}
+	if p.PaymentDate.IsZero() {
+		v.Add("data_pagamento", "obrigatória")
+	}
+	return v.Err()
+}
+
+func (r Refund) Validate() error {
+	v := &ValidationError{Entity: "estorno", ID: r.ID}
+	if r.PaymentID == 0 {
+		v.Add("id_pagamento", "obrigatório")
+	}
+	if r.Amount <= 0 {
+		v.Add("valor", "deve ser positivo: %s", r.Amount)
+	}
+	if r.RefundDate.IsZero() {
+		v.Add("data_estorno", "obrigatória")
+	}
+	return v.Err()
+}
+
+// CheckRefund confere se o estorno r pode ser aplicado a p, o pagamento lido
+// do banco (ou o valor zero, se ele não existe): apenas pagamentos aprovados
+// ou estornados em parte, até o saldo ainda não estornado.
+func (p Payment) CheckRefund(r Refund) error {
+	v := &ValidationError{Entity: "estorno", ID: r.ID}
+	switch {
+	case p.ID == 0:
+		v.Add("id_pagamento", "pagamento %d não encontrado", r.PaymentID)
+	case p.Status != PaymentStatusApproved && p.Status != StatusPartiallyRefunded:
+		v.Add("id_pagamento", "pagamento com status %q não pode ser estornado", p.Status)
+	case r.Amount > p.Amount-p.Refunded:
+		v.Add("valor", "%s excede o saldo de %s do pagamento", r.Amount, p.Amount-p.Refunded)
+	}
+	return v.Err()
+}
+
+// Validate confere apenas os campos da avaliação. Que o produto existe é
+// verificado pelo repositório.
+func (r Review) Validate() error {
+	v := &ValidationError{Entity: "avaliacao", ID: r.ID}
+	if r.ProductID == 0 {
+		v.Add("id_produto", "obrigatório")
+	}
+	if r.ClientID == 0 {
+		v.Add("id_cliente", "obrigatório")
+	}
+	if r.Rating < MinRating || r.Rating > MaxRating {
+		v.Add("nota", "deve estar entre %d e %d: %d", MinRating, MaxRating, r.Rating)
+	}
+	if n := utf8.RuneCountInString(r.Comment); n > MaxCommentLength {
+		v.Add("comentario", "deve ter até %d caracteres: %d", MaxCommentLength, n)
+	}
+	if r.CreatedAt.IsZero() {
+		v.Add("data_avaliacao", "obrigatória")
+	}
+	return v.Err()
+}
+
+// States são as siglas das 27 unidades federativas.
+var States = []string{
+	"AC", "AL", "AM", "AP", "BA", "CE", "DF", "ES", "GO", "MA", "MG", "MS", "MT", "PA",
+	"PB", "PE", "PI", "PR", "RJ", "RN", "RO", "RR", "RS", "SC", "SE", "SP", "TO",
+}
+
+// ValidCEP informa se cep está no formato 00000-000.
+func ValidCEP(cep string) bool {
+	return len(cep) == 9 && cep[5] == '-' && onlyDigits(cep[:5]) && onlyDigits(cep[6:])
+}
+
+func (a PostalAddress) addErrors(v *ValidationError, prefix string) {
+	if !ValidCEP(a.CEP) {
+		v.Add(prefix+"cep", "deve estar no formato 00000-000: %q", a.CEP)
+	}
+	if strings.TrimSpace(a.Street) == "" {
+		v.Add(prefix+"logradouro", "obrigatório")
+	}
+	if strings.TrimSpace(a.Number) == "" {
+		v.Add(prefix+"numero", "obrigatório")
+	}
+	if strings.TrimSpace(a.City) == "" {
+		v.Add(prefix+"cidade", "obrigatória")
+	}
+	if !slices.Contains(States, a.State) {
+		v.Add(prefix+"uf", "sigla de estado inválida: %q", a.State)
+	}
+}
+
+// Validate confere apenas os campos do endereço. Que o cliente existe é
+// verificado pelo repositório.
+func (a Address) Validate() error {
+	v := &ValidationError{Entity: "endereco", ID: a.ID}
+	if a.ClientID == 0 {
+		v.Add("id_cliente", "obrigatório")
+	}
+	a.PostalAddress.addErrors(v, "")
+	return v.Err()
+}
+
+// Validate confere o envio e o endereço de entrega. Que o pedido existe é
+// verificado pelo repositório, e Events é ignorado.
+func (s Shipment) Validate() error {
+	v := &ValidationError{Entity: "envio", ID: s.ID}
+	if s.OrderID == 0 {
+		v.Add("id_pedido", "obrigatório")
+	}
+	if strings.TrimSpace(s.Carrier) == "" {
+		v.Add("transportadora", "obrigatória")
+	}
+	if strings.TrimSpace(s.TrackingCode) == "" {
+		v.Add("codigo_rastreio", "obrigatório")
+	}
+	if s.ShippedAt.IsZero() {
+		v.Add("data_envio", "obrigatória")
+	}
+	s.Address.addErrors(v, "endereco.")
+	return v.Err()
+}
+
+// Validate confere apenas os campos do evento. Que o envio existe é verificado
+// pelo repositório.
+func (e TrackingEvent) Validate() error {
+	v := &ValidationError{Entity: "evento_rastreio", ID: e.ShipmentID}
+	if e.ShipmentID == 0 {
+		v.Add("id_envio", "obrigatório")
+	}
+	if strings.TrimSpace(e.Status) == "" {
+		v.Add("status", "obrigatório")
+	}
+	if e.OccurredAt.IsZero() {
+		v.Add("data_evento", "obrigatória")
+	}
+	return v.Err()
+}
+
+// MaxCouponCodeLength é o tamanho máximo do código de um cupom, em caracteres.
+const MaxCouponCodeLength = 30
+
+func (c Coupon) Validate() error {
+	v := &ValidationError{Entity: "cupom", Key: c.Code}
+	if n := utf8.RuneCountInString(c.Code); strings.TrimSpace(c.Code) == "" || n > MaxCouponCodeLength {
+		v.Add("codigo", "deve ter de 1 a %d caracteres: %q", MaxCouponCodeLength, c.Code)
+	}
+	switch c.Type {
+	case CouponPercentage:
+		if c.Percent < 1 || c.Percent > 100 {
+			v.Add("percentual", "deve estar entre 1 e 100: %d", c.Percent)
+		}
+		if c.Amount != 0 {
+			v.Add("valor", "deve ser zero em cupons %s: %s", CouponPercentage, c.Amount)
+		}
+	case CouponFixed:
+		if c.Amount <= 0 {
+			v.Add("valor", "deve ser positivo: %s", c.Amount)
+		}
+		if c.Percent != 0 {
+			v.Add("percentual", "deve ser zero em cupons %s: %d", CouponFixed, c.Percent)
+		}
+	default:
+		v.Add("tipo", "deve ser %q ou %q: %q", CouponPercentage, CouponFixed, c.Type)
+	}
+	if c.MinOrderValue < 0 {
+		v.Add("valor_minimo", "não pode ser negativo: %s", c.MinOrderValue)
+	}
+	if c.ValidFrom.IsZero() {
+		v.Add("inicio_validade", "obrigatório")
+	}
+	if c.ValidUntil.IsZero() || !c.ValidUntil.After(c.ValidFrom) {
+		v.Add("fim_validade", "deve ser posterior ao início da validade")
+	}
+	if c.UsageLimit < 1 {
+		v.Add("limite_por_cliente", "deve ser ao menos 1: %d", c.UsageLimit)
+	}
+	seen := make(map[string]bool)
+	for i, category := range c.Categories {
+		switch {
+		case strings.TrimSpace(category) == "":
+			v.Add(fmt.Sprintf("categorias[%d]", i), "vazia")
+		case seen[category]:
+			v.Add(fmt.Sprintf("categorias[%d]", i), "categoria %q repetida", category)
+		}
+		seen[category] = true
+	}
+	return v.Err()
+}
+
+// CouponNotFound é o erro de um pedido com um cupom que não existe.
+func CouponNotFound(o Order) error {
+	v := &ValidationError{Entity: "pedido", ID: o.ID}
+	v.Add("codigo_cupom", "cupom %q não encontrado", o.CouponCode)
+	return v
+}
+
+// Discount calcula o desconto do cupom no pedido o, cujos itens têm o preço
+// unitário em Product.Price e a categoria em categories, indexada pelo ID do
+// produto. Um pedido fora da validade, abaixo do valor mínimo ou sem itens das
+// categorias do cupom retorna *ValidationError. O desconto percentual é
+// truncado no centavo, e nenhum desconto passa do valor dos itens elegíveis.
+func (c Coupon) Discount(o Order, categories map[uint]string) (Money, error) {
+	v := &ValidationError{Entity: "pedido", ID: o.ID}
+	if o.OrderDate.Before(c.ValidFrom) || o.OrderDate.After(c.ValidUntil) {
+		v.Add("codigo_cupom", "cupom %q fora da validade", c.Code)
+		return 0, v
+	}
+
+	var subtotal, eligible Money
+	for i, item := range o.Itens {
+		category, ok := categories[item.ProductID]
+		if !ok {
+			v.Add(fmt.Sprintf("itens[%d].id_produto", i), "produto %d não encontrado", item.ProductID)
+			continue
+		}
+		value := item.Product.Price.Mul(item.Quantity)
+		subtotal += value
+		if len(c.Categories) == 0 || slices.Contains(c.Categories, category) {
+			eligible += value
+		}
+	}
+	switch {
+	case len(v.Fields) > 0:
+	case subtotal < c.MinOrderValue:
+		v.Add("valor_total", "%s abaixo do mínimo de %s do cupom %q", subtotal, c.MinOrderValue, c.Code)
+	case eligible == 0:
+		v.Add("itens", "nenhum item das categorias do cupom %q", c.Code)
+	}
+	if err := v.Err(); err != nil {
+		return 0, err
+	}
+
+	discount := c.Amount
+	if c.Type == CouponPercentage {
+		discount = eligible * Money(c.Percent) / 100
+	}
+	return min(discount, eligible), nil
+}
+
+// CheckUses confere se o cliente do pedido o, que já usou o cupom uses vezes,
+// pode usá-lo de novo.
+func (c Coupon) CheckUses(o Order, uses int) error {
+	v := &ValidationError{Entity: "pedido", ID: o.ID}
+	if uses >= c.UsageLimit {
+		v.Add("codigo_cupom", "o cliente %d já usou o cupom %q %d vez(es), o limite", o.ClientID, c.Code, uses)
+	}
+	return v.Err()
+}
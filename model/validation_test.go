@@ -0,0 +1,325 @@
+package model_test
+
+import (
+	"errors"
+	"techmarket_showcase/model"
+	"testing"
+	"time"
+)
+
+func TestValidCPF(t *testing.T) {
+	cases := map[string]bool{
+		"529.982.247-25": true,
+		"111.444.777-35": true,
+		"390.533.447-05": true,
+		"529.982.247-24": false,
+		"52998224725":    false,
+		"529.982.247.25": false,
+		"111.111.111-11": false,
+		"000.000.000-00": false,
+		"abc.982.247-25": false,
+		"":               false,
+	}
+	for cpf, want := range cases {
+		if got := model.ValidCPF(cpf); got != want {
+			t.Errorf("ValidCPF(%q) = %v, esperado %v", cpf, got, want)
+		}
+	}
+
+	if got := model.CPFCheckDigits("529982247"); got != "25" {
+		t.Errorf("CPFCheckDigits = %q, esperado 25", got)
+	}
+}
+
+func TestClientValidate(t *testing.T) {
+	valid := model.Client{ID: 1, Nome: "Ana Souza", Email: "ana.souza@techmarket.com", Phone: "11987654321", CPF: "529.982.247-25"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("cliente válido rejeitado: %v", err)
+	}
+
+	invalid := model.Client{ID: 7, Nome: " ", Email: "Ana <ana@techmarket.com>", Phone: "1198765", CPF: "529.982.247-24"}
+	var v *model.ValidationError
+	if err := invalid.Validate(); !errors.As(err, &v) {
+		t.Fatalf("erro = %v, esperado *ValidationError", err)
+	}
+	if v.Entity != "cliente" || v.ID != 7 {
+		t.Errorf("registro = %s %d", v.Entity, v.ID)
+	}
+	for _, field := range []string{"nome", "email", "cpf", "telefone"} {
+		if _, ok := v.Field(field); !ok {
+			t.Errorf("campo %s não reportado em %v", field, v)
+		}
+	}
+}
+
+func TestProductValidate(t *testing.T) {
+	err := model.Product{ID: 3, Name: "Fone", Category: "Fones de Ouvido", Price: 19999, Stock: -1}.Validate()
+	var v *model.ValidationError
+	if !errors.As(err, &v) || len(v.Fields) != 1 {
+		t.Fatalf("erro = %v, esperado apenas estoque", err)
+	}
+	if _, ok := v.Field("estoque"); !ok {
+		t.Errorf("campo estoque não reportado em %v", v)
+	}
+}
+
+func TestOrderValidate(t *testing.T) {
+	order := model.Order{ID: 1, ClientID: 1, OrderDate: time.Now(), Status: model.OrderStatusDelivered, TotalValue: model.Reais(100)}
+
+	var v *model.ValidationError
+	if err := order.Validate(); !errors.As(err, &v) {
+		t.Fatalf("pedido sem itens aceito: %v", err)
+	}
+	if _, ok := v.Field("itens"); !ok {
+		t.Errorf("campo itens não reportado em %v", v)
+	}
+
+	order.Itens = []model.OrderItem{{OrderID: 1, ProductID: 5, Quantity: 1}}
+	if err := order.Validate(); err != nil {
+		t.Fatalf("pedido válido rejeitado: %v", err)
+	}
+
+	order.Itens = append(order.Itens, model.OrderItem{OrderID: 2, ProductID: 5, Quantity: 0})
+	if err := order.Validate(); !errors.As(err, &v) {
+		t.Fatalf("itens inválidos aceitos: %v", err)
+	}
+	for _, field := range []string{"itens[1].id_pedido", "itens[1].id_produto", "itens[1].quantidade"} {
+		if _, ok := v.Field(field); !ok {
+			t.Errorf("campo %s não reportado em %v", field, v)
+		}
+	}
+
+	order.Itens = order.Itens[:1]
+	order.AddressID = new(uint)
+	if err := order.Validate(); !errors.As(err, &v) {
+		t.Fatalf("endereço zero aceito: %v", err)
+	}
+	if _, ok := v.Field("id_endereco"); !ok {
+		t.Errorf("campo id_endereco não reportado em %v", v)
+	}
+
+	order.AddressID = nil
+	order.Discount = model.Reais(10)
+	if err := order.Validate(); !errors.As(err, &v) {
+		t.Fatalf("desconto sem cupom aceito: %v", err)
+	}
+	if _, ok := v.Field("desconto"); !ok {
+		t.Errorf("campo desconto não reportado em %v", v)
+	}
+}
+
+func TestPaymentValidate(t *testing.T) {
+	payment := model.Payment{ID: 1, OrderID: 1, ClientID: 1, Type: model.PaymentTypeCreditCard, Status: "Aprovado", Amount: model.Reais(100), Installments: 10, PaymentDate: time.Now()}
+	if err := payment.Validate(); err != nil {
+		t.Fatalf("pagamento válido rejeitado: %v", err)
+	}
+
+	payment.Type = model.PaymentTypePix
+	payment.Amount = 0
+	var v *model.ValidationError
+	if err := payment.Validate(); !errors.As(err, &v) || len(v.Fields) != 2 {
+		t.Fatalf("erro = %v, esperado valor e parcelas", err)
+	}
+	if field, _ := v.Field("parcelas"); field.Message == "" {
+		t.Errorf("campo parcelas não reportado em %v", v)
+	}
+}
+
+func TestPaymentCheckRefund(t *testing.T) {
+	payment := model.Payment{ID: 2, Status: model.PaymentStatusApproved, Amount: model.Reais(100), Refunded: model.Reais(60)}
+	refund := model.Refund{PaymentID: 2, Amount: model.Reais(40), RefundDate: time.Now()}
+	if err := payment.CheckRefund(refund); err != nil {
+		t.Fatalf("estorno do saldo rejeitado: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		payment model.Payment
+		amount  model.Money
+		field   string
+	}{
+		{"acima do saldo", payment, model.Reais(40) + 1, "valor"},
+		{"pendente", model.Payment{ID: 2, Status: "Pendente", Amount: model.Reais(100)}, 1, "id_pagamento"},
+		{"estornado", model.Payment{ID: 2, Status: model.StatusRefunded, Amount: model.Reais(100), Refunded: model.Reais(100)}, 1, "id_pagamento"},
+		{"inexistente", model.Payment{}, 1, "id_pagamento"},
+	}
+	for _, c := range cases {
+		refund.Amount = c.amount
+		var v *model.ValidationError
+		if err := c.payment.CheckRefund(refund); !errors.As(err, &v) {
+			t.Errorf("%s: erro = %v, esperado *ValidationError", c.name, err)
+			continue
+		}
+		if _, ok := v.Field(c.field); !ok {
+			t.Errorf("%s: campo %s não reportado em %v", c.name, c.field, v)
+		}
+	}
+
+	if got := model.RefundStatus(model.Reais(100), model.Reais(60)); got != model.StatusPartiallyRefunded {
+		t.Errorf("RefundStatus parcial = %q", got)
+	}
+	if got := model.RefundStatus(model.Reais(100), model.Reais(100)); got != model.StatusRefunded {
+		t.Errorf("RefundStatus integral = %q", got)
+	}
+}
+
+func TestReviewValidate(t *testing.T) {
+	review := model.Review{ID: 1, ProductID: 1, ClientID: 1, Rating: 5, Comment: "Ótimo", CreatedAt: time.Now()}
+	if err := review.Validate(); err != nil {
+		t.Fatalf("avaliação válida rejeitada: %v", err)
+	}
+
+	review.Rating = 0
+	review.Comment = string(make([]rune, model.MaxCommentLength+1))
+	var v *model.ValidationError
+	if err := review.Validate(); !errors.As(err, &v) || len(v.Fields) != 2 {
+		t.Fatalf("erro = %v, esperado nota e comentario", err)
+	}
+	if _, ok := v.Field("nota"); !ok {
+		t.Errorf("campo nota não reportado em %v", v)
+	}
+}
+
+func TestCategoryRatingAverage(t *testing.T) {
+	if got := (model.CategoryRating{Category: "Notebooks", Reviews: 4, RatingSum: 14}).Average(); got != 3.5 {
+		t.Errorf("Average = %v, esperado 3.5", got)
+	}
+	if got := (model.CategoryRating{}).Average(); got != 0 {
+		t.Errorf("Average sem avaliações = %v, esperado 0", got)
+	}
+}
+
+func TestShipmentValidate(t *testing.T) {
+	shipment := model.Shipment{
+		ID: 1, OrderID: 1, Carrier: "Correios", TrackingCode: "AA123456789BR", ShippedAt: time.Now(),
+		Address: model.PostalAddress{CEP: "01310-100", Street: "Avenida Paulista", Number: "1000", City: "São Paulo", State: "SP"},
+	}
+	if err := shipment.Validate(); err != nil {
+		t.Fatalf("envio válido rejeitado: %v", err)
+	}
+
+	shipment.Address.CEP, shipment.Address.State = "01310100", "XX"
+	var v *model.ValidationError
+	if err := shipment.Validate(); !errors.As(err, &v) || len(v.Fields) != 2 {
+		t.Fatalf("erro = %v, esperado cep e uf", err)
+	}
+	for _, field := range []string{"endereco.cep", "endereco.uf"} {
+		if _, ok := v.Field(field); !ok {
+			t.Errorf("campo %s não reportado em %v", field, v)
+		}
+	}
+}
+
+func TestAddressValidate(t *testing.T) {
+	address := model.Address{
+		ID: 1, ClientID: 1, Default: true,
+		PostalAddress: model.PostalAddress{CEP: "30130-010", Street: "Avenida Afonso Pena", Number: "1500", City: "Belo Horizonte", State: "MG"},
+	}
+	if err := address.Validate(); err != nil {
+		t.Fatalf("endereço válido rejeitado: %v", err)
+	}
+
+	address.ClientID, address.Number = 0, " "
+	var v *model.ValidationError
+	if err := address.Validate(); !errors.As(err, &v) || len(v.Fields) != 2 {
+		t.Fatalf("erro = %v, esperado id_cliente e numero", err)
+	}
+	if v.Entity != "endereco" {
+		t.Errorf("entidade = %s, esperado endereco", v.Entity)
+	}
+	for _, field := range []string{"id_cliente", "numero"} {
+		if _, ok := v.Field(field); !ok {
+			t.Errorf("campo %s não reportado em %v", field, v)
+		}
+	}
+}
+
+func TestCouponValidate(t *testing.T) {
+	now := time.Now()
+	coupon := model.Coupon{
+		Code: "DEZ10", Type: model.CouponPercentage, Percent: 10, MinOrderValue: model.Reais(100),
+		ValidFrom: now, ValidUntil: now.AddDate(0, 1, 0), UsageLimit: 1, Categories: []string{"Notebooks"},
+	}
+	if err := coupon.Validate(); err != nil {
+		t.Fatalf("cupom válido rejeitado: %v", err)
+	}
+
+	coupon.Type, coupon.ValidUntil = model.CouponFixed, now
+	coupon.Categories = append(coupon.Categories, "Notebooks")
+	var v *model.ValidationError
+	if err := coupon.Validate(); !errors.As(err, &v) || len(v.Fields) != 4 {
+		t.Fatalf("erro = %v, esperado valor, percentual, fim_validade e categorias[1]", err)
+	}
+	if v.Key != "DEZ10" {
+		t.Errorf("chave = %q, esperado DEZ10", v.Key)
+	}
+	for _, field := range []string{"valor", "percentual", "fim_validade", "categorias[1]"} {
+		if _, ok := v.Field(field); !ok {
+			t.Errorf("campo %s não reportado em %v", field, v)
+		}
+	}
+}
+
+func TestCouponDiscount(t *testing.T) {
+	now := time.Now()
+	categories := map[uint]string{1: "Notebooks", 4: "Fones de Ouvido", 5: "Periféricos"}
+	order := model.Order{ID: 1, ClientID: 1, OrderDate: now, Itens: []model.OrderItem{
+		{ProductID: 4, Quantity: 3, Product: model.Product{Price: 19999}},
+		{ProductID: 5, Quantity: 1, Product: model.Product{Price: model.Reais(100)}},
+	}}
+	percentage := model.Coupon{
+		Code: "FONE15", Type: model.CouponPercentage, Percent: 15,
+		ValidFrom: now.AddDate(0, 0, -1), ValidUntil: now.AddDate(0, 0, 1), UsageLimit: 1, Categories: []string{"Fones de Ouvido"},
+	}
+	fixed := model.Coupon{
+		Code: "MIL", Type: model.CouponFixed, Amount: model.Reais(1000),
+		ValidFrom: now.AddDate(0, 0, -1), ValidUntil: now.AddDate(0, 0, 1), UsageLimit: 1,
+	}
+
+	// 15% de 599,97 são 89,9955, truncados em 89,99.
+	if got, err := percentage.Discount(order, categories); err != nil || got != 8999 {
+		t.Errorf("desconto percentual = %s, %v, esperado 89.99", got, err)
+	}
+	// O valor fixo é limitado ao valor dos itens.
+	if got, err := fixed.Discount(order, categories); err != nil || got != 69997 {
+		t.Errorf("desconto fixo = %s, %v, esperado 699.97", got, err)
+	}
+
+	expired := percentage
+	expired.ValidUntil = now.AddDate(0, 0, -1).Add(time.Hour)
+	minimum := fixed
+	minimum.MinOrderValue = model.Reais(700)
+	noItems := percentage
+	noItems.Categories = []string{"Notebooks"}
+	unknown := order
+	unknown.Itens = append(unknown.Itens, model.OrderItem{ProductID: 9, Quantity: 1})
+
+	cases := []struct {
+		name   string
+		coupon model.Coupon
+		order  model.Order
+		field  string
+	}{
+		{"fora da validade", expired, order, "codigo_cupom"},
+		{"abaixo do mínimo", minimum, order, "valor_total"},
+		{"sem itens elegíveis", noItems, order, "itens"},
+		{"produto inexistente", fixed, unknown, "itens[2].id_produto"},
+	}
+	for _, c := range cases {
+		var v *model.ValidationError
+		if _, err := c.coupon.Discount(c.order, categories); !errors.As(err, &v) {
+			t.Errorf("%s: erro = %v, esperado *ValidationError", c.name, err)
+			continue
+		}
+		if _, ok := v.Field(c.field); !ok {
+			t.Errorf("%s: campo %s não reportado em %v", c.name, c.field, v)
+		}
+	}
+
+	if err := percentage.CheckUses(order, 0); err != nil {
+		t.Errorf("primeiro uso rejeitado: %v", err)
+	}
+	if err := percentage.CheckUses(order, 1); err == nil {
+		t.Error("uso acima do limite aceito")
+	}
+}
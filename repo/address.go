@@ -0,0 +1,280 @@
+package repo
+
+import (
+	"cmp"
+	"errors"
+	"maps"
+	"slices"
+	"techmarket_showcase/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	_ AddressBook = &PostgresRepository{}
+	_ AddressBook = &SQLiteRepository{}
+)
+
+// existingClientsFunc informa quais dos clientes informados existem no banco.
+type existingClientsFunc func(clientIDs []uint) (map[uint]bool, error)
+
+// addressesFunc retorna os endereços informados que existem no banco, com ao
+// menos ID, ClientID e State preenchidos.
+type addressesFunc func(addressIDs []uint) (map[uint]model.Address, error)
+
+// validateAddresses valida os endereços e confere, com existingClients, que
+// cada cliente existe. Dois endereços padrão do mesmo cliente no lote são
+// recusados, já que só um deles poderia continuar padrão.
+func validateAddresses(addresses []model.Address, existingClients existingClientsFunc) error {
+	if err := validateRecords(addresses); err != nil {
+		return err
+	}
+
+	ids := make(map[uint]bool)
+	for _, address := range addresses {
+		ids[address.ClientID] = true
+	}
+	clients, err := existingClients(slices.Sorted(maps.Keys(ids)))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	defaults := make(map[uint]bool)
+	for _, address := range addresses {
+		v := &model.ValidationError{Entity: "endereco", ID: address.ID}
+		if !clients[address.ClientID] {
+			v.Add("id_cliente", "cliente %d não encontrado", address.ClientID)
+		}
+		if address.Default {
+			if defaults[address.ClientID] {
+				v.Add("padrao", "o lote já tem um endereço padrão do cliente %d", address.ClientID)
+			}
+			defaults[address.ClientID] = true
+		}
+		if err := v.Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateOrders valida os pedidos e confere, com existingClients, que o
+// cliente de cada pedido existe e, com addresses, que o endereço existe e é do
+// cliente do pedido. Os endereços encontrados são retornados para os bancos que
+// copiam a UF no pedido; sem pedidos com endereço, addresses não é consultado.
+func validateOrders(orders []model.Order, existingClients existingClientsFunc, addresses addressesFunc) (map[uint]model.Address, error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	if err := validateRecords(orders); err != nil {
+		return nil, err
+	}
+
+	clientIDs := make(map[uint]bool)
+	addressIDs := make(map[uint]bool)
+	for _, order := range orders {
+		clientIDs[order.ClientID] = true
+		if order.AddressID != nil {
+			addressIDs[*order.AddressID] = true
+		}
+	}
+	clients, err := existingClients(slices.Sorted(maps.Keys(clientIDs)))
+	if err != nil {
+		return nil, err
+	}
+	var found map[uint]model.Address
+	if len(addressIDs) > 0 {
+		if found, err = addresses(slices.Sorted(maps.Keys(addressIDs))); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, order := range orders {
+		v := &model.ValidationError{Entity: "pedido", ID: order.ID}
+		if !clients[order.ClientID] {
+			v.Add("id_cliente", "cliente %d não encontrado", order.ClientID)
+		}
+		if order.AddressID != nil {
+			address, ok := found[*order.AddressID]
+			switch {
+			case !ok:
+				v.Add("id_endereco", "endereço %d não encontrado", *order.AddressID)
+			case address.ClientID != order.ClientID:
+				v.Add("id_endereco", "o endereço %d é do cliente %d", *order.AddressID, address.ClientID)
+			}
+		}
+		if err := v.Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return found, errors.Join(errs...)
+}
+
+// addressNotFound é o erro de SetDefaultAddress para um endereço que não existe
+// ou é de outro cliente.
+func addressNotFound(clientID, addressID uint) error {
+	v := &model.ValidationError{Entity: "endereco", ID: addressID}
+	v.Add("id", "endereço %d não encontrado entre os do cliente %d", addressID, clientID)
+	return v
+}
+
+// sortAddresses ordena os endereços como GetClientAddresses: o padrão primeiro
+// e os demais por ID.
+func sortAddresses(addresses []model.Address) {
+	slices.SortFunc(addresses, func(a, b model.Address) int {
+		if a.Default != b.Default {
+			if a.Default {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+// gormExistingClients consulta a tabela cliente em blocos, como
+// gormOrderClients. Com forUpdate as linhas dos clientes ficam travadas até o
+// fim da transação de db.
+func gormExistingClients(db *gorm.DB, forUpdate bool) existingClientsFunc {
+	lock := ""
+	if forUpdate {
+		lock = " FOR UPDATE"
+	}
+	return func(clientIDs []uint) (map[uint]bool, error) {
+		clients := make(map[uint]bool)
+		for chunk := range slices.Chunk(clientIDs, 500) {
+			var ids []uint
+			if err := db.Raw(`SELECT id FROM cliente WHERE id IN ?`+lock, chunk).Scan(&ids).Error; err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				clients[id] = true
+			}
+		}
+		return clients, nil
+	}
+}
+
+// gormAddresses consulta a tabela endereco em blocos, como gormOrderClients.
+func gormAddresses(db *gorm.DB) addressesFunc {
+	return func(addressIDs []uint) (map[uint]model.Address, error) {
+		addresses := make(map[uint]model.Address)
+		for chunk := range slices.Chunk(addressIDs, 500) {
+			var rows []model.Address
+			if err := db.Raw(`SELECT * FROM endereco WHERE id IN ?`, chunk).Scan(&rows).Error; err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				addresses[row.ID] = row
+			}
+		}
+		return addresses, nil
+	}
+}
+
+// gormCreateAddresses grava os endereços em uma transação que antes desmarca o
+// padrão anterior dos clientes que recebem um novo; o índice único parcial
+// idx_endereco_padrao garante um único padrão por cliente. No PostgreSQL os
+// clientes são lidos com FOR UPDATE, então trocas concorrentes do padrão de um
+// mesmo cliente esperam uma pela outra; no SQLite a transação já começa com a
+// trava de escrita, como em gormRefund.
+func gormCreateAddresses(db *gorm.DB, forUpdate bool, addresses []model.Address) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := validateAddresses(addresses, gormExistingClients(tx, forUpdate)); err != nil {
+			return err
+		}
+
+		var clients []uint
+		for _, address := range addresses {
+			if address.Default {
+				clients = append(clients, address.ClientID)
+			}
+		}
+		for chunk := range slices.Chunk(clients, 500) {
+			if err := tx.Exec(`UPDATE endereco SET padrao = ? WHERE id_cliente IN ? AND padrao`, false, chunk).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Table("endereco").CreateInBatches(addresses, 100).Error
+	})
+}
+
+func gormSetDefaultAddress(db *gorm.DB, forUpdate bool, clientID, addressID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		clients, err := gormExistingClients(tx, forUpdate)([]uint{clientID})
+		if err != nil {
+			return err
+		}
+		addresses, err := gormAddresses(tx)([]uint{addressID})
+		if err != nil {
+			return err
+		}
+		if !clients[clientID] || addresses[addressID].ClientID != clientID {
+			return addressNotFound(clientID, addressID)
+		}
+
+		if err := tx.Exec(`UPDATE endereco SET padrao = ? WHERE id_cliente = ? AND padrao`, false, clientID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE endereco SET padrao = ? WHERE id = ?`, true, addressID).Error
+	})
+}
+
+func gormClientAddresses(db *gorm.DB, clientID uint) ([]model.Address, error) {
+	query := `SELECT * FROM endereco WHERE id_cliente = ? ORDER BY padrao DESC, id`
+	var addresses []model.Address
+	if err := db.Raw(query, clientID).Scan(&addresses).Error; err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+func gormRevenueByState(db *gorm.DB) ([]model.StateRevenue, error) {
+	query := `
+		SELECT e.uf, COUNT(*) AS pedidos, SUM(p.valor_total) AS receita
+		FROM pedido p
+		JOIN endereco e ON e.id = p.id_endereco
+		WHERE p.status <> ?
+		GROUP BY e.uf
+		ORDER BY e.uf
+	`
+	var revenue []model.StateRevenue
+	if err := db.Raw(query, model.OrderStatusCancelled).Scan(&revenue).Error; err != nil {
+		return nil, err
+	}
+	return revenue, nil
+}
+
+func (p *PostgresRepository) BatchCreateAddress(addresses []model.Address) error {
+	return gormCreateAddresses(p.db, true, addresses)
+}
+
+func (p *PostgresRepository) SetDefaultAddress(clientID uint, addressID uint) error {
+	return gormSetDefaultAddress(p.db, true, clientID, addressID)
+}
+
+func (p *PostgresRepository) GetClientAddresses(clientID uint) ([]model.Address, error) {
+	return gormClientAddresses(p.db, clientID)
+}
+
+func (p *PostgresRepository) GetRevenueByState() ([]model.StateRevenue, error) {
+	return gormRevenueByState(p.db)
+}
+
+func (s *SQLiteRepository) BatchCreateAddress(addresses []model.Address) error {
+	return gormCreateAddresses(s.db, false, addresses)
+}
+
+func (s *SQLiteRepository) SetDefaultAddress(clientID uint, addressID uint) error {
+	return gormSetDefaultAddress(s.db, false, clientID, addressID)
+}
+
+func (s *SQLiteRepository) GetClientAddresses(clientID uint) ([]model.Address, error) {
+	return gormClientAddresses(s.db, clientID)
+}
+
+func (s *SQLiteRepository) GetRevenueByState() ([]model.StateRevenue, error) {
+	return gormRevenueByState(s.db)
+}
@@ -0,0 +1,692 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	_ TechMarketRepository = &BoltRepository{}
+	_ Exporter             = &BoltRepository{}
+	_ AddressBook          = &BoltRepository{}
+)
+
+// O bbolt não tem índices secundários: cada bucket idx_* abaixo é mantido à mão
+// na mesma transação que grava o registro principal, o que torna explícito o
+// custo de indexação que os outros bancos escondem.
+var (
+	bucketClientes   = []byte("clientes")
+	bucketProdutos   = []byte("produtos")
+	bucketPedidos    = []byte("pedidos")
+	bucketItens      = []byte("itens_pedido")
+	bucketPagamentos = []byte("pagamentos")
+	bucketEnderecos  = []byte("enderecos")
+
+	// email -> id do cliente
+	bucketIdxClienteEmail = []byte("idx_cliente_email")
+	// categoria | 0x00 | id do produto -> vazio
+	bucketIdxProdutoCategoria = []byte("idx_produto_categoria")
+	// id do cliente | id do pedido -> vazio
+	bucketIdxPedidoCliente = []byte("idx_pedido_cliente")
+	// tipo | 0x00 | mes_ano | 0x00 | data_pagamento | id do pagamento -> vazio
+	bucketIdxPagamentoTipoMes = []byte("idx_pagamento_tipo_mes")
+	// id do pedido | id do pagamento -> vazio
+	bucketIdxPagamentoPedido = []byte("idx_pagamento_pedido")
+	// id do cliente | id do endereço -> vazio
+	bucketIdxEnderecoCliente = []byte("idx_endereco_cliente")
+	// id do cliente -> id do endereço padrão
+	bucketEnderecoPadrao = []byte("endereco_padrao")
+	// id do produto -> total vendido
+	bucketVendasProduto = []byte("vendas_produto")
+	// ^total vendido | id do produto -> vazio, ordenado do mais vendido ao menos vendido
+	bucketIdxProdutoVendas = []byte("idx_produto_vendas")
+)
+
+type BoltRepository struct {
+	db *bolt.DB
+}
+
+func (b *BoltRepository) BatchCreateClient(clients []model.Client) error {
+	if err := validateRecords(clients); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketClientes)
+		byEmail := tx.Bucket(bucketIdxClienteEmail)
+
+		for i := range clients {
+			client := &clients[i]
+			if byEmail.Get([]byte(client.Email)) != nil {
+				return fmt.Errorf("email já cadastrado: %s", client.Email)
+			}
+
+			if err := assignID(data, &client.ID); err != nil {
+				return err
+			}
+			if err := putJSON(data, uintKey(client.ID), client); err != nil {
+				return err
+			}
+			if err := byEmail.Put([]byte(client.Email), uintKey(client.ID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltRepository) BatchCreateProduct(products []model.Product) error {
+	if err := validateRecords(products); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketProdutos)
+		byCategory := tx.Bucket(bucketIdxProdutoCategoria)
+		bySales := tx.Bucket(bucketIdxProdutoVendas)
+
+		for i := range products {
+			product := &products[i]
+			if err := assignID(data, &product.ID); err != nil {
+				return err
+			}
+			if err := putJSON(data, uintKey(product.ID), product); err != nil {
+				return err
+			}
+			if err := byCategory.Put(joinKey([]byte(product.Category), uintKey(product.ID)), nil); err != nil {
+				return err
+			}
+			// Produtos sem vendas também entram no ranking, como no LEFT JOIN do SQL.
+			if err := bySales.Put(salesKey(0, product.ID), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltRepository) BatchCreateOrder(orders []model.Order) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if _, err := validateOrders(orders, boltExistingClients(tx), boltAddresses(tx)); err != nil {
+			return err
+		}
+
+		data := tx.Bucket(bucketPedidos)
+		byClient := tx.Bucket(bucketIdxPedidoCliente)
+
+		for i := range orders {
+			order := &orders[i]
+			if err := assignID(data, &order.ID); err != nil {
+				return err
+			}
+
+			stored := *order
+			stored.Itens = nil
+			if err := putJSON(data, uintKey(order.ID), stored); err != nil {
+				return err
+			}
+			if err := byClient.Put(append(uintKey(order.ClientID), uintKey(order.ID)...), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
+	if err := validateRecords(orderItems); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketItens)
+		sales := tx.Bucket(bucketVendasProduto)
+		bySales := tx.Bucket(bucketIdxProdutoVendas)
+
+		for _, item := range orderItems {
+			key := append(uintKey(item.OrderID), uintKey(item.ProductID)...)
+			if data.Get(key) != nil {
+				return fmt.Errorf("item duplicado no pedido %d: produto %d", item.OrderID, item.ProductID)
+			}
+			if err := putJSON(data, key, item); err != nil {
+				return err
+			}
+
+			var total uint64
+			if v := sales.Get(uintKey(item.ProductID)); v != nil {
+				total = binary.BigEndian.Uint64(v)
+			}
+			if err := bySales.Delete(salesKey(total, item.ProductID)); err != nil {
+				return err
+			}
+
+			total += uint64(item.Quantity)
+			if err := sales.Put(uintKey(item.ProductID), binary.BigEndian.AppendUint64(nil, total)); err != nil {
+				return err
+			}
+			if err := bySales.Put(salesKey(total, item.ProductID), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltRepository) BatchCreatePayment(payments []model.Payment) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		// Os pedidos são conferidos na mesma transação que grava os pagamentos.
+		if err := validatePayments(payments, boltOrderClients(tx)); err != nil {
+			return err
+		}
+
+		data := tx.Bucket(bucketPagamentos)
+		byTypeMonth := tx.Bucket(bucketIdxPagamentoTipoMes)
+		byOrder := tx.Bucket(bucketIdxPagamentoPedido)
+
+		for i := range payments {
+			payment := &payments[i]
+			if err := assignID(data, &payment.ID); err != nil {
+				return err
+			}
+			if err := putJSON(data, uintKey(payment.ID), payment); err != nil {
+				return err
+			}
+
+			key := append(paymentMonthPrefix(payment.Type, payment.PaymentDate), timeKey(payment.PaymentDate)...)
+			if err := byTypeMonth.Put(append(key, uintKey(payment.ID)...), nil); err != nil {
+				return err
+			}
+			if err := byOrder.Put(append(uintKey(payment.OrderID), uintKey(payment.ID)...), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BatchCreateAddress grava os endereços sem o campo Default: o padrão de cada
+// cliente fica só em endereco_padrao, então trocá-lo é uma única gravação.
+func (b *BoltRepository) BatchCreateAddress(addresses []model.Address) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := validateAddresses(addresses, boltExistingClients(tx)); err != nil {
+			return err
+		}
+
+		data := tx.Bucket(bucketEnderecos)
+		byClient := tx.Bucket(bucketIdxEnderecoCliente)
+		defaults := tx.Bucket(bucketEnderecoPadrao)
+
+		for i := range addresses {
+			address := &addresses[i]
+			if err := assignID(data, &address.ID); err != nil {
+				return err
+			}
+
+			stored := *address
+			stored.Default = false
+			if err := putJSON(data, uintKey(address.ID), stored); err != nil {
+				return err
+			}
+			if err := byClient.Put(append(uintKey(address.ClientID), uintKey(address.ID)...), nil); err != nil {
+				return err
+			}
+			if address.Default {
+				if err := defaults.Put(uintKey(address.ClientID), uintKey(address.ID)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltRepository) SetDefaultAddress(clientID uint, addressID uint) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketIdxEnderecoCliente).Get(append(uintKey(clientID), uintKey(addressID)...)) == nil {
+			return addressNotFound(clientID, addressID)
+		}
+		return tx.Bucket(bucketEnderecoPadrao).Put(uintKey(clientID), uintKey(addressID))
+	})
+}
+
+func (b *BoltRepository) GetClientAddresses(clientID uint) ([]model.Address, error) {
+	var addresses []model.Address
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketEnderecos)
+		defaultID := tx.Bucket(bucketEnderecoPadrao).Get(uintKey(clientID))
+		prefix := uintKey(clientID)
+
+		c := tx.Bucket(bucketIdxEnderecoCliente).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			var address model.Address
+			if err := getJSON(data, k[len(prefix):], &address); err != nil {
+				return err
+			}
+			address.Default = bytes.Equal(k[len(prefix):], defaultID)
+			addresses = append(addresses, address)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortAddresses(addresses)
+	return addresses, nil
+}
+
+// GetRevenueByState percorre todos os pedidos, já que não há índice por UF, e
+// lê o endereço de cada pedido com endereço.
+func (b *BoltRepository) GetRevenueByState() ([]model.StateRevenue, error) {
+	byState := make(map[string]*model.StateRevenue)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		addresses := tx.Bucket(bucketEnderecos)
+		return tx.Bucket(bucketPedidos).ForEach(func(_, v []byte) error {
+			var order model.Order
+			if err := json.Unmarshal(v, &order); err != nil {
+				return err
+			}
+			if order.AddressID == nil || order.Status == model.OrderStatusCancelled {
+				return nil
+			}
+
+			var address model.Address
+			if err := getJSON(addresses, uintKey(*order.AddressID), &address); err != nil {
+				return err
+			}
+			revenue, ok := byState[address.State]
+			if !ok {
+				revenue = &model.StateRevenue{State: address.State}
+				byState[address.State] = revenue
+			}
+			revenue.Orders++
+			revenue.Revenue += order.TotalValue
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var revenue []model.StateRevenue
+	for _, state := range slices.Sorted(maps.Keys(byState)) {
+		revenue = append(revenue, *byState[state])
+	}
+	return revenue, nil
+}
+
+func (b *BoltRepository) GetClientByEmail(email string) (model.Client, error) {
+	var client model.Client
+	err := b.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(bucketIdxClienteEmail).Get([]byte(email))
+		if id == nil {
+			return nil
+		}
+		return getJSON(tx.Bucket(bucketClientes), id, &client)
+	})
+	if err != nil {
+		return model.Client{}, err
+	}
+	return client, nil
+}
+
+func (b *BoltRepository) GetProductByCategory(category string) ([]model.Product, error) {
+	var products []model.Product
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketProdutos)
+		prefix := joinKey([]byte(category), nil)
+
+		c := tx.Bucket(bucketIdxProdutoCategoria).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			var product model.Product
+			if err := getJSON(data, k[len(prefix):], &product); err != nil {
+				return err
+			}
+			products = append(products, product)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (b *BoltRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
+	var orders []model.Order
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketPedidos)
+		prefix := uintKey(clientID)
+
+		c := tx.Bucket(bucketIdxPedidoCliente).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			var order model.Order
+			if err := getJSON(data, k[len(prefix):], &order); err != nil {
+				return err
+			}
+			if order.Status == model.OrderStatusDelivered {
+				orders = append(orders, order)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (b *BoltRepository) Get5MostSoldProducts() ([]model.Product, error) {
+	var products []model.Product
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketProdutos)
+
+		c := tx.Bucket(bucketIdxProdutoVendas).Cursor()
+		for k, _ := c.First(); k != nil && len(products) < 5; k, _ = c.Next() {
+			var product model.Product
+			if err := getJSON(data, k[8:], &product); err != nil {
+				return err
+			}
+			products = append(products, product)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (b *BoltRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
+	startDate := time.Now().AddDate(0, -1, 0)
+	endDate := time.Now()
+
+	var payments []model.Payment
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketPagamentos)
+		c := tx.Bucket(bucketIdxPagamentoTipoMes).Cursor()
+
+		// O índice é particionado por mês, então o período é percorrido mês a mês.
+		for month := monthStart(startDate); !month.After(endDate); month = month.AddDate(0, 1, 0) {
+			prefix := paymentMonthPrefix(model.PaymentTypePix, month)
+			start := append(append([]byte{}, prefix...), timeKey(startDate)...)
+
+			for k, _ := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				rest := k[len(prefix):]
+				if int64(binary.BigEndian.Uint64(rest[:8])) > endDate.UnixNano() {
+					break
+				}
+
+				var payment model.Payment
+				if err := getJSON(data, rest[8:], &payment); err != nil {
+					return err
+				}
+				payments = append(payments, payment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// Soma o valor dos pagamentos feitos no período para os pedidos do cliente,
+// percorrendo os índices de pedidos por cliente e de pagamentos por pedido.
+func (b *BoltRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	var total model.Money
+	err := b.db.View(func(tx *bolt.Tx) error {
+		payments := tx.Bucket(bucketPagamentos)
+		paymentsByOrder := tx.Bucket(bucketIdxPagamentoPedido).Cursor()
+		prefix := uintKey(clientID)
+
+		c := tx.Bucket(bucketIdxPedidoCliente).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			orderID := k[len(prefix):]
+
+			for pk, _ := paymentsByOrder.Seek(orderID); pk != nil && bytes.HasPrefix(pk, orderID); pk, _ = paymentsByOrder.Next() {
+				var payment model.Payment
+				if err := getJSON(payments, pk[len(orderID):], &payment); err != nil {
+					return err
+				}
+				if !payment.PaymentDate.Before(startDate) && !payment.PaymentDate.After(endDate) {
+					total += payment.Amount - payment.Refunded
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func boltExportPage[T any](db *bolt.DB, bucket []byte, afterID uint, limit int, each func(tx *bolt.Tx, record *T) error) ([]T, error) {
+	var records []T
+	err := db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Seek(uintKey(afterID + 1)); k != nil && len(records) < limit; k, v = c.Next() {
+			var record T
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if each != nil {
+				if err := each(tx, &record); err != nil {
+					return err
+				}
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (b *BoltRepository) ExportClients(afterID uint, limit int) ([]model.Client, error) {
+	return boltExportPage[model.Client](b.db, bucketClientes, afterID, limit, nil)
+}
+
+func (b *BoltRepository) ExportAddresses(afterID uint, limit int) ([]model.Address, error) {
+	return boltExportPage(b.db, bucketEnderecos, afterID, limit, func(tx *bolt.Tx, address *model.Address) error {
+		address.Default = bytes.Equal(tx.Bucket(bucketEnderecoPadrao).Get(uintKey(address.ClientID)), uintKey(address.ID))
+		return nil
+	})
+}
+
+func (b *BoltRepository) ExportProducts(afterID uint, limit int) ([]model.Product, error) {
+	return boltExportPage[model.Product](b.db, bucketProdutos, afterID, limit, nil)
+}
+
+// Os itens guardam o produto informado na gravação, que pode vir incompleto;
+// nome e categoria são lidos do bucket de produtos e o preço atual só é usado
+// quando o item não trouxe o preço unitário.
+func (b *BoltRepository) ExportOrders(afterID uint, limit int) ([]model.Order, error) {
+	return boltExportPage(b.db, bucketPedidos, afterID, limit, func(tx *bolt.Tx, order *model.Order) error {
+		products := tx.Bucket(bucketProdutos)
+		prefix := uintKey(order.ID)
+
+		c := tx.Bucket(bucketItens).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var item model.OrderItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return err
+			}
+
+			var product model.Product
+			if err := getJSON(products, uintKey(item.ProductID), &product); err != nil {
+				return err
+			}
+			if item.Product.Price != 0 {
+				product.Price = item.Product.Price
+			}
+			item.Product = product
+			order.Itens = append(order.Itens, item)
+		}
+		return nil
+	})
+}
+
+func (b *BoltRepository) ExportPayments(afterID uint, limit int) ([]model.Payment, error) {
+	return boltExportPage[model.Payment](b.db, bucketPagamentos, afterID, limit, nil)
+}
+
+// Cada Batch* roda em uma única transação, então repetir a chamada após uma
+// falha não duplica registros.
+func (b *BoltRepository) RetrySafeWrites() bool {
+	return true
+}
+
+func boltOrderClients(tx *bolt.Tx) orderClientsFunc {
+	return func(orderIDs []uint) (map[uint]uint, error) {
+		orders := tx.Bucket(bucketPedidos)
+		clients := make(map[uint]uint)
+		for _, id := range orderIDs {
+			if orders.Get(uintKey(id)) == nil {
+				continue
+			}
+			var order model.Order
+			if err := getJSON(orders, uintKey(id), &order); err != nil {
+				return nil, err
+			}
+			clients[id] = order.ClientID
+		}
+		return clients, nil
+	}
+}
+
+// boltAddresses lê os endereços na transação tx, como boltOrderClients.
+func boltExistingClients(tx *bolt.Tx) existingClientsFunc {
+	return func(clientIDs []uint) (map[uint]bool, error) {
+		clients := tx.Bucket(bucketClientes)
+		found := make(map[uint]bool)
+		for _, id := range clientIDs {
+			found[id] = clients.Get(uintKey(id)) != nil
+		}
+		return found, nil
+	}
+}
+
+func boltAddresses(tx *bolt.Tx) addressesFunc {
+	return func(addressIDs []uint) (map[uint]model.Address, error) {
+		data := tx.Bucket(bucketEnderecos)
+		addresses := make(map[uint]model.Address)
+		for _, id := range addressIDs {
+			if data.Get(uintKey(id)) == nil {
+				continue
+			}
+			var address model.Address
+			if err := getJSON(data, uintKey(id), &address); err != nil {
+				return nil, err
+			}
+			addresses[id] = address
+		}
+		return addresses, nil
+	}
+}
+
+func assignID(bucket *bolt.Bucket, id *uint) error {
+	if *id != 0 {
+		if uint64(*id) > bucket.Sequence() {
+			return bucket.SetSequence(uint64(*id))
+		}
+		return nil
+	}
+
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	*id = uint(seq)
+	return nil
+}
+
+func putJSON(bucket *bolt.Bucket, key []byte, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, data)
+}
+
+func getJSON(bucket *bolt.Bucket, key []byte, value any) error {
+	data := bucket.Get(key)
+	if data == nil {
+		return fmt.Errorf("registro %x não encontrado", key)
+	}
+	return json.Unmarshal(data, value)
+}
+
+func uintKey(id uint) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+func timeKey(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano()))
+}
+
+func joinKey(prefix []byte, suffix []byte) []byte {
+	key := append(append([]byte{}, prefix...), 0)
+	return append(key, suffix...)
+}
+
+func salesKey(total uint64, productID uint) []byte {
+	return append(binary.BigEndian.AppendUint64(nil, ^total), uintKey(productID)...)
+}
+
+func paymentMonthPrefix(paymentType string, date time.Time) []byte {
+	return joinKey(joinKey([]byte(paymentType), []byte(date.UTC().Format("2006-01"))), nil)
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func NewBoltRepository() *BoltRepository {
+	config := config.LoadBoltConfig()
+
+	db, err := bolt.Open(config.Path, 0600, &bolt.Options{Timeout: config.Timeout})
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao abrir o bbolt: %v", err))
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{
+			bucketClientes,
+			bucketProdutos,
+			bucketPedidos,
+			bucketItens,
+			bucketPagamentos,
+			bucketEnderecos,
+			bucketIdxClienteEmail,
+			bucketIdxProdutoCategoria,
+			bucketIdxPedidoCliente,
+			bucketIdxPagamentoTipoMes,
+			bucketIdxPagamentoPedido,
+			bucketIdxEnderecoCliente,
+			bucketEnderecoPadrao,
+			bucketVendasProduto,
+			bucketIdxProdutoVendas,
+		} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao criar os buckets do bbolt: %v", err))
+	}
+
+	return &BoltRepository{db: db}
+}
@@ -0,0 +1,15 @@
+package repo_test
+
+import (
+	"path/filepath"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/conformance"
+	"testing"
+)
+
+func TestBoltRepositoryConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+		t.Setenv("BOLT_PATH", filepath.Join(t.TempDir(), "techmarket.bolt"))
+		return repo.NewBoltRepository()
+	})
+}
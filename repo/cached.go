@@ -0,0 +1,301 @@
+package repo
+
+import (
+	"container/list"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"time"
+)
+
+var (
+	_ TechMarketRepository = &CachedRepository{}
+	_ Refunder             = &CachedRepository{}
+)
+
+type CacheStats struct {
+	Hits          int
+	Misses        int
+	Evictions     int
+	Invalidations int
+}
+
+type cacheEntry struct {
+	key     string
+	value   any
+	expires time.Time
+}
+
+// CachedRepository é um cache read-through em memória (LRU com TTL) na frente
+// de qualquer TechMarketRepository. Cada método Batch* invalida apenas as
+// consultas cujo resultado ele pode alterar.
+type CachedRepository struct {
+	inner    TechMarketRepository
+	capacity int
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   CacheStats
+}
+
+func NewCachedRepository(inner TechMarketRepository) *CachedRepository {
+	config := config.LoadCacheConfig()
+
+	return &CachedRepository{
+		inner:    inner,
+		capacity: config.Capacity,
+		ttl:      config.TTL,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (c *CachedRepository) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *CachedRepository) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.value, true
+}
+
+func (c *CachedRepository) set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.value = value
+		entry.expires = time.Now().Add(c.ttl)
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, value: value, expires: time.Now().Add(c.ttl)})
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+// invalidate remove as chaves informadas e todas as que começam com algum dos
+// prefixos terminados em ":".
+func (c *CachedRepository) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ":") {
+			if elem, ok := c.entries[key]; ok {
+				c.lru.Remove(elem)
+				delete(c.entries, key)
+				c.stats.Invalidations++
+			}
+			continue
+		}
+
+		for k, elem := range c.entries {
+			if strings.HasPrefix(k, key) {
+				c.lru.Remove(elem)
+				delete(c.entries, k)
+				c.stats.Invalidations++
+			}
+		}
+	}
+}
+
+func (c *CachedRepository) BatchCreateClient(clients []model.Client) error {
+	err := c.inner.BatchCreateClient(clients)
+
+	keys := make([]string, len(clients))
+	for i, client := range clients {
+		keys[i] = "GetClientByEmail:" + client.Email
+	}
+	c.invalidate(keys...)
+	return err
+}
+
+func (c *CachedRepository) BatchCreateProduct(products []model.Product) error {
+	err := c.inner.BatchCreateProduct(products)
+
+	// Produtos novos entram no ranking com zero vendas.
+	keys := []string{"Get5MostSoldProducts"}
+	for _, product := range products {
+		keys = append(keys, "GetProductByCategory:"+product.Category)
+	}
+	c.invalidate(keys...)
+	return err
+}
+
+func (c *CachedRepository) BatchCreateOrder(orders []model.Order) error {
+	err := c.inner.BatchCreateOrder(orders)
+
+	// O total gasto vem dos pagamentos e é descartado por BatchCreatePayment.
+	var keys []string
+	for _, order := range orders {
+		keys = append(keys, fmt.Sprintf("GetDeliveredOrdersByClient:%d", order.ClientID))
+	}
+	c.invalidate(keys...)
+	return err
+}
+
+func (c *CachedRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
+	err := c.inner.BatchCreateOrderItem(orderItems)
+	c.invalidate("Get5MostSoldProducts")
+	return err
+}
+
+func (c *CachedRepository) BatchCreatePayment(payments []model.Payment) error {
+	err := c.inner.BatchCreatePayment(payments)
+
+	// Qualquer pagamento pode ser um pix do último mês; os totais descartados
+	// são só os dos clientes dos pagamentos.
+	keys := []string{"GetLastMonthPixPayments"}
+	for _, payment := range payments {
+		keys = append(keys, fmt.Sprintf("GetClientTotalSpentByPeriod:%d:", payment.ClientID))
+	}
+	c.invalidate(keys...)
+	return err
+}
+
+func (c *CachedRepository) GetClientByEmail(email string) (model.Client, error) {
+	key := "GetClientByEmail:" + email
+	if value, ok := c.get(key); ok {
+		return value.(model.Client), nil
+	}
+
+	client, err := c.inner.GetClientByEmail(email)
+	if err != nil {
+		return model.Client{}, err
+	}
+	c.set(key, client)
+	return client, nil
+}
+
+func (c *CachedRepository) GetProductByCategory(category string) ([]model.Product, error) {
+	key := "GetProductByCategory:" + category
+	if value, ok := c.get(key); ok {
+		return slices.Clone(value.([]model.Product)), nil
+	}
+
+	products, err := c.inner.GetProductByCategory(category)
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, slices.Clone(products))
+	return products, nil
+}
+
+func (c *CachedRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
+	key := fmt.Sprintf("GetDeliveredOrdersByClient:%d", clientID)
+	if value, ok := c.get(key); ok {
+		return slices.Clone(value.([]model.Order)), nil
+	}
+
+	orders, err := c.inner.GetDeliveredOrdersByClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, slices.Clone(orders))
+	return orders, nil
+}
+
+func (c *CachedRepository) Get5MostSoldProducts() ([]model.Product, error) {
+	key := "Get5MostSoldProducts"
+	if value, ok := c.get(key); ok {
+		return slices.Clone(value.([]model.Product)), nil
+	}
+
+	products, err := c.inner.Get5MostSoldProducts()
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, slices.Clone(products))
+	return products, nil
+}
+
+// O resultado depende do instante da consulta, então o TTL é o único limite
+// para a janela de "último mês" ficar desatualizada.
+func (c *CachedRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
+	key := "GetLastMonthPixPayments"
+	if value, ok := c.get(key); ok {
+		return slices.Clone(value.([]model.Payment)), nil
+	}
+
+	payments, err := c.inner.GetLastMonthPixPayments()
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, slices.Clone(payments))
+	return payments, nil
+}
+
+func (c *CachedRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	key := fmt.Sprintf("GetClientTotalSpentByPeriod:%d:%d:%d", clientID, startDate.UnixNano(), endDate.UnixNano())
+	if value, ok := c.get(key); ok {
+		return value.(model.Money), nil
+	}
+
+	total, err := c.inner.GetClientTotalSpentByPeriod(clientID, startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+	c.set(key, total)
+	return total, nil
+}
+
+// RefundPayment repassa o estorno ao repositório decorado, que precisa
+// implementar Refunder. O estorno muda o status e o valor estornado do
+// pagamento e do pedido, então descarta os pix do último mês e as consultas
+// do cliente.
+func (c *CachedRepository) RefundPayment(refund *model.Refund) error {
+	refunder, err := optional[Refunder](c.inner, "registra estornos")
+	if err != nil {
+		return err
+	}
+	err = refunder.RefundPayment(refund)
+
+	// O ClientID é preenchido pelo repositório; sem ele nada foi gravado.
+	if refund.ClientID != 0 {
+		c.invalidate("GetLastMonthPixPayments",
+			fmt.Sprintf("GetDeliveredOrdersByClient:%d", refund.ClientID),
+			fmt.Sprintf("GetClientTotalSpentByPeriod:%d:", refund.ClientID))
+	}
+	return err
+}
+
+// Refunds não é guardado em cache.
+func (c *CachedRepository) Refunds(paymentID uint) ([]model.Refund, error) {
+	refunder, err := optional[Refunder](c.inner, "registra estornos")
+	if err != nil {
+		return nil, err
+	}
+	return refunder.Refunds(paymentID)
+}
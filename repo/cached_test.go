@@ -0,0 +1,175 @@
+package repo_test
+
+import (
+	"path/filepath"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/conformance"
+	"testing"
+	"time"
+)
+
+func newCachedBolt(t *testing.T) (*repo.CachedRepository, *repo.InstrumentedRepository) {
+	t.Setenv("BOLT_PATH", filepath.Join(t.TempDir(), "techmarket.bolt"))
+	inner := repo.NewInstrumentedRepository(repo.NewBoltRepository())
+	return repo.NewCachedRepository(inner), inner
+}
+
+// O bbolt não registra estornos, então o conformance recebe o cache sem
+// repo.Refunder.
+func TestCachedRepositoryConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+		cached, _ := newCachedBolt(t)
+		return struct{ repo.TechMarketRepository }{cached}
+	})
+}
+
+func TestCachedRepositoryInvalidation(t *testing.T) {
+	cached, inner := newCachedBolt(t)
+
+	products := []model.Product{{Name: "Tablet A", Category: "Tablets", Price: 1000, Stock: 1}}
+	if err := cached.BatchCreateProduct(products); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		got, err := cached.GetProductByCategory("Tablets")
+		if err != nil || len(got) != 1 {
+			t.Fatalf("GetProductByCategory = %v, %v", got, err)
+		}
+	}
+	if calls := inner.Stats()["GetProductByCategory"].Calls; calls != 1 {
+		t.Errorf("consultas ao repositório = %d, esperado 1", calls)
+	}
+
+	// Outra categoria não invalida a entrada de Tablets.
+	if err := cached.BatchCreateProduct([]model.Product{{Name: "Mouse", Category: "Periféricos", Price: 50, Stock: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	cached.GetProductByCategory("Tablets")
+	if calls := inner.Stats()["GetProductByCategory"].Calls; calls != 1 {
+		t.Errorf("consultas ao repositório = %d, esperado 1", calls)
+	}
+
+	if err := cached.BatchCreateProduct([]model.Product{{Name: "Tablet B", Category: "Tablets", Price: 2000, Stock: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := cached.GetProductByCategory("Tablets")
+	if len(got) != 2 {
+		t.Errorf("%d produtos após invalidação, esperado 2", len(got))
+	}
+	if calls := inner.Stats()["GetProductByCategory"].Calls; calls != 2 {
+		t.Errorf("consultas ao repositório = %d, esperado 2", calls)
+	}
+
+	stats := cached.Stats()
+	if stats.Hits != 3 || stats.Misses != 2 || stats.Invalidations != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+// Um pagamento descarta apenas os totais do seu cliente.
+func TestCachedRepositoryPaymentInvalidation(t *testing.T) {
+	cached, inner := newCachedBolt(t)
+
+	now := time.Now().Truncate(time.Second)
+	clients := []model.Client{
+		{ID: 1, Nome: "Ana Souza", Email: "ana.souza@techmarket.com", CreatedAt: now, CPF: "529.982.247-25"},
+		{ID: 2, Nome: "Bruno Lima", Email: "bruno.lima@techmarket.com", CreatedAt: now, CPF: "111.444.777-35"},
+	}
+	if err := cached.BatchCreateClient(clients); err != nil {
+		t.Fatal(err)
+	}
+	product := model.Product{ID: 1, Name: "Tablet", Category: "Tablets", Price: model.Reais(1000), Stock: 1}
+	if err := cached.BatchCreateProduct([]model.Product{product}); err != nil {
+		t.Fatal(err)
+	}
+	order := model.Order{ID: 1, ClientID: 2, OrderDate: now, Status: model.OrderStatusDelivered, TotalValue: product.Price,
+		Itens: []model.OrderItem{{OrderID: 1, ProductID: 1, Quantity: 1, Product: product}}}
+	if err := cached.BatchCreateOrder([]model.Order{order}); err != nil {
+		t.Fatal(err)
+	}
+
+	start, end := now.AddDate(0, -1, 0), now.Add(time.Hour)
+	for _, clientID := range []uint{1, 2} {
+		cached.GetClientTotalSpentByPeriod(clientID, start, end)
+	}
+
+	payment := model.Payment{ID: 1, OrderID: 1, ClientID: 2, Type: model.PaymentTypePix, Status: model.PaymentStatusApproved,
+		Amount: product.Price, Installments: 1, PaymentDate: now}
+	if err := cached.BatchCreatePayment([]model.Payment{payment}); err != nil {
+		t.Fatal(err)
+	}
+
+	for clientID, want := range map[uint]model.Money{1: 0, 2: product.Price} {
+		if got, err := cached.GetClientTotalSpentByPeriod(clientID, start, end); err != nil || got != want {
+			t.Errorf("cliente %d: total = %s, %v, esperado %s", clientID, got, err, want)
+		}
+	}
+	if calls := inner.Stats()["GetClientTotalSpentByPeriod"].Calls; calls != 3 {
+		t.Errorf("consultas ao repositório = %d, esperado 3", calls)
+	}
+}
+
+// Um estorno descarta o total gasto e os pedidos entregues do cliente.
+func TestCachedRepositoryRefundInvalidation(t *testing.T) {
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "techmarket.db"))
+	cached := repo.NewCachedRepository(repo.NewSQLiteRepository())
+
+	now := time.Now().Truncate(time.Second)
+	client := model.Client{ID: 1, Nome: "Ana Souza", Email: "ana.souza@techmarket.com", CreatedAt: now, CPF: "529.982.247-25"}
+	if err := cached.BatchCreateClient([]model.Client{client}); err != nil {
+		t.Fatal(err)
+	}
+	product := model.Product{ID: 1, Name: "Tablet", Category: "Tablets", Price: model.Reais(1000), Stock: 1}
+	if err := cached.BatchCreateProduct([]model.Product{product}); err != nil {
+		t.Fatal(err)
+	}
+	order := model.Order{ID: 1, ClientID: 1, OrderDate: now, Status: model.OrderStatusDelivered, TotalValue: product.Price,
+		Itens: []model.OrderItem{{OrderID: 1, ProductID: 1, Quantity: 1, Product: product}}}
+	if err := cached.BatchCreateOrder([]model.Order{order}); err != nil {
+		t.Fatal(err)
+	}
+	payment := model.Payment{ID: 1, OrderID: 1, ClientID: 1, Type: model.PaymentTypePix, Status: model.PaymentStatusApproved,
+		Amount: product.Price, Installments: 1, PaymentDate: now}
+	if err := cached.BatchCreatePayment([]model.Payment{payment}); err != nil {
+		t.Fatal(err)
+	}
+
+	start, end := now.AddDate(0, -1, 0), now.Add(time.Hour)
+	if total, err := cached.GetClientTotalSpentByPeriod(1, start, end); err != nil || total != product.Price {
+		t.Fatalf("total = %s, %v, esperado %s", total, err, product.Price)
+	}
+	if orders, err := cached.GetDeliveredOrdersByClient(1); err != nil || len(orders) != 1 {
+		t.Fatalf("GetDeliveredOrdersByClient = %v, %v", orders, err)
+	}
+
+	refund := model.Refund{PaymentID: 1, Amount: product.Price, Reason: "teste", RefundDate: now}
+	if err := cached.RefundPayment(&refund); err != nil {
+		t.Fatal(err)
+	}
+
+	if total, err := cached.GetClientTotalSpentByPeriod(1, start, end); err != nil || total != 0 {
+		t.Errorf("total após o estorno = %s, %v, esperado R$ 0,00", total, err)
+	}
+	if orders, err := cached.GetDeliveredOrdersByClient(1); err != nil || len(orders) != 0 {
+		t.Errorf("pedidos entregues após o estorno = %v, %v, esperado nenhum", orders, err)
+	}
+}
+
+func TestCachedRepositoryEviction(t *testing.T) {
+	t.Setenv("CACHE_CAPACITY", "2")
+	cached, inner := newCachedBolt(t)
+
+	for _, email := range []string{"a@x.com", "b@x.com", "a@x.com", "c@x.com", "b@x.com"} {
+		cached.GetClientByEmail(email)
+	}
+
+	// a e b entram, a é reaproveitado, c expulsa b (menos usado) e b volta a ser consultado.
+	if calls := inner.Stats()["GetClientByEmail"].Calls; calls != 4 {
+		t.Errorf("consultas ao repositório = %d, esperado 4", calls)
+	}
+	if evictions := cached.Stats().Evictions; evictions != 2 {
+		t.Errorf("evictions = %d, esperado 2", evictions)
+	}
+}
@@ -1,9 +1,13 @@
 package repo
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strconv"
+	"maps"
+	"slices"
+	"strings"
 	"techmarket_showcase/config"
 	"techmarket_showcase/model"
 	"time"
@@ -11,21 +15,35 @@ import (
 	"github.com/gocql/gocql"
 )
 
-var _ TechMarketRepository = &CassandraRepository{}
+var (
+	_ TechMarketRepository = &CassandraRepository{}
+	_ Refunder             = &CassandraRepository{}
+	_ Reviewer             = &CassandraRepository{}
+	_ ShipmentTracker      = &CassandraRepository{}
+	_ AddressBook          = &CassandraRepository{}
+	_ CouponBook           = &CassandraRepository{}
+)
 
 type CassandraRepository struct {
 	db *gocql.Session
+	// concurrentWrites troca os batches UNLOGGED por comandos individuais
+	// enviados em paralelo, com no máximo maxInFlight pendentes.
+	concurrentWrites bool
+	maxInFlight      int
+	writes           WriteOptions
 }
 
 func (c *CassandraRepository) BatchCreateClient(clients []model.Client) error {
-	batchSize := 100
-	for i := 0; i < len(clients); i += batchSize {
-		end := min(i+batchSize, len(clients))
-		batch := c.db.NewBatch(gocql.UnloggedBatch)
-
-		for _, client := range clients[i:end] {
-			uuid := gocql.TimeUUID()
-			batch.Query(`
+	if err := validateRecords(clients); err != nil {
+		return err
+	}
+	var statements []cassandraStatement
+	for i := range clients {
+		client := &clients[i]
+		ensureID(&client.ID)
+
+		statements = append(statements,
+			cassandraStatement{`
 				INSERT INTO clientes_por_email (
 					email,
 					id,
@@ -34,160 +52,218 @@ func (c *CassandraRepository) BatchCreateClient(clients []model.Client) error {
 					data_cadastro,
 					cpf
 				) VALUES (?, ?, ?, ?, ?, ?)`,
-				client.Email,
-				uuid.String(),
-				client.Nome,
-				client.Phone,
-				client.CreatedAt,
-				client.CPF,
-			)
-		}
-
-		if err := c.db.ExecuteBatch(batch); err != nil {
-			return err
-		}
+				[]any{client.Email, model.FormatID(client.ID), client.Nome, client.Phone, client.CreatedAt, client.CPF},
+			},
+			cassandraStatement{insertClientePorID, []any{model.FormatID(client.ID), client.Email}},
+		)
 	}
-	return nil
+	return c.execute(gocql.UnloggedBatch, c.batchStatements(100, 2), statements)
 }
 
 func (c *CassandraRepository) BatchCreateProduct(products []model.Product) error {
-	batchSize := 100
-	for i := 0; i < len(products); i += batchSize {
-		end := min(i+batchSize, len(products))
-		batch := c.db.NewBatch(gocql.UnloggedBatch)
-
-		for _, product := range products[i:end] {
-			uuid := gocql.TimeUUID()
-
-			batch.Query(`
-				INSERT INTO produtos_por_categoria (
-					categoria,
-					preco,
-					id_produto,
-					nome,
-					estoque
-				) VALUES (?, ?, ?, ?, ?)`,
-				product.Category,
-				product.Price,
-				uuid.String(),
-				product.Name,
-				product.Stock,
-			)
-		}
+	if err := validateRecords(products); err != nil {
+		return err
+	}
+	var statements []cassandraStatement
+	for i := range products {
+		product := &products[i]
+		ensureID(&product.ID)
 
-		if err := c.db.ExecuteBatch(batch); err != nil {
-			return err
-		}
+		statements = append(statements,
+			cassandraStatement{insertProdutoPorCategoria, []any{product.Category, product.Price, model.FormatID(product.ID), product.Name, product.Stock}},
+			cassandraStatement{insertProdutoPorID, []any{model.FormatID(product.ID), product.Name, product.Category, product.Price, product.Stock}},
+		)
 	}
-	return nil
+	return c.execute(gocql.UnloggedBatch, c.batchStatements(100, 2), statements)
 }
 
+// BatchCreateOrder grava os pedidos em pedidos_por_cliente e pedidos_por_id,
+// quando o pedido tem endereço e não está cancelado, em pedidos_por_uf com a
+// UF do endereço e, quando tem cupom, em pedidos_por_cupom.
 func (c *CassandraRepository) BatchCreateOrder(orders []model.Order) error {
-	batchSize := 30
-	for i := 0; i < len(orders); i += batchSize {
-		end := min(i+batchSize, len(orders))
-		batch := c.db.NewBatch(gocql.UnloggedBatch)
-
-		for _, order := range orders[i:end] {
-			var itensMap []map[string]string
-			for _, item := range order.Itens {
-				itemMap := map[string]string{
-					"product_id": gocql.TimeUUID().String(),
-					"quantity":   fmt.Sprintf("%d", item.Quantity),
-				}
-				itensMap = append(itensMap, itemMap)
-			}
+	addresses, err := validateOrders(orders, c.existingClients, c.addresses)
+	if err != nil {
+		return err
+	}
+	var statements []cassandraStatement
+	for i := range orders {
+		order := &orders[i]
+		ensureID(&order.ID)
 
-			clientID := gocql.TimeUUID()
-			orderID := gocql.TimeUUID()
+		// Os itens ficam desnormalizados em uma coluna de texto JSON, lida
+		// por GetDeliveredOrdersByClient e por RebuildQueryTables.
+		itens := []cassandraItem{}
+		for _, item := range order.Itens {
+			itens = append(itens, cassandraItem{
+				ProdutoID:     model.FormatID(item.ProductID),
+				Quantidade:    item.Quantity,
+				NomeProduto:   item.Product.Name,
+				PrecoUnitario: item.Product.Price,
+			})
+		}
+		itensJSON, err := json.Marshal(itens)
+		if err != nil {
+			return err
+		}
+
+		var addressID *string
+		if order.AddressID != nil {
+			addressID = new(string)
+			*addressID = model.FormatID(*order.AddressID)
+		}
 
-			batch.Query(`
+		statements = append(statements,
+			cassandraStatement{`
 				INSERT INTO pedidos_por_cliente (
 					id_cliente,
 					data_pedido,
-					id_pedido,
+					pedido_id,
 					status,
 					valor_total,
-					itens
-				) VALUES (?, ?, ?, ?, ?, ?)`,
-				clientID.String(),
-				order.OrderDate,
-				orderID.String(),
-				order.Status,
-				order.TotalValue,
-				itensMap,
-			)
+					itens,
+					id_endereco,
+					codigo_cupom,
+					desconto
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				[]any{model.FormatID(order.ClientID), order.OrderDate, model.FormatID(order.ID), order.Status, order.TotalValue, string(itensJSON), addressID, order.CouponCode, order.Discount},
+			},
+			cassandraStatement{insertPedidoPorID, []any{model.FormatID(order.ID), model.FormatID(order.ClientID), order.TotalValue}},
+		)
+		if order.AddressID != nil && order.Status != model.OrderStatusCancelled {
+			state := addresses[*order.AddressID].State
+			statements = append(statements, cassandraStatement{insertPedidoPorUF, []any{state, model.FormatID(order.ID), order.TotalValue}})
 		}
-
-		if err := c.db.ExecuteBatch(batch); err != nil {
-			return err
+		if order.CouponCode != "" {
+			statements = append(statements, cassandraStatement{insertPedidoPorCupom, []any{order.CouponCode, model.FormatID(order.ID), order.Discount}})
 		}
 	}
-	return nil
+	return c.execute(gocql.UnloggedBatch, c.batchStatements(30, 3), statements)
 }
 
+// BatchCreateOrderItem não grava os itens, que já fazem parte de
+// pedidos_por_cliente, mas soma as quantidades em vendas_por_produto e move os
+// produtos para a nova posição em produtos_por_vendas. Os contadores do
+// Cassandra não são idempotentes: repetir a chamada conta as vendas de novo, e
+// gravações concorrentes podem deixar o ranking desatualizado. Nos dois casos
+// RebuildQueryTables recalcula as tabelas.
 func (c *CassandraRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
-	return nil
-}
+	if err := validateRecords(orderItems); err != nil {
+		return err
+	}
+	sold := make(map[string]int64)
+	for _, item := range orderItems {
+		sold[model.FormatID(item.ProductID)] += int64(item.Quantity)
+	}
 
-func (c *CassandraRepository) BatchCreatePayment(payments []model.Payment) error {
-	batchSize := 100
-	for i := 0; i < len(payments); i += batchSize {
-		end := min(i+batchSize, len(payments))
-		batch := c.db.NewBatch(gocql.UnloggedBatch)
-
-		for _, payment := range payments[i:end] {
-			mesAno := payment.PaymentDate.Format("2006-01")
-			paymentID := gocql.TimeUUID()
-			orderID := gocql.TimeUUID()
-			clientID := gocql.TimeUUID()
-
-			batch.Query(`
-				INSERT INTO pagamentos_por_tipo_e_mes (
-					tipo,
-					mes_ano,
-					data_pagamento,
-					id_pagamento,
-					id_pedido,
-					id_cliente
-				) VALUES (?, ?, ?, ?, ?, ?)`,
-				payment.Type,
-				mesAno,
-				payment.PaymentDate,
-				paymentID.String(),
-				orderID.String(),
-				clientID.String(),
-			)
+	for ids := range slices.Chunk(slices.Sorted(maps.Keys(sold)), 100) {
+		products, err := c.productsByID(ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if _, ok := products[id]; !ok {
+				return fmt.Errorf("produto %s não encontrado em produtos_por_id", id)
+			}
 		}
 
-		if err := c.db.ExecuteBatch(batch); err != nil {
+		counters := make([]cassandraStatement, len(ids))
+		for i, id := range ids {
+			counters[i] = cassandraStatement{`UPDATE vendas_por_produto SET total_vendido = total_vendido + ? WHERE id_produto = ?`, []any{sold[id], id}}
+		}
+		if err := c.execute(gocql.CounterBatch, 100, counters); err != nil {
+			return err
+		}
+
+		totals, err := c.salesByProduct(ids)
+		if err != nil {
+			return err
+		}
+
+		var ranking []cassandraStatement
+		for _, id := range ids {
+			if previous := totals[id] - sold[id]; previous > 0 {
+				ranking = append(ranking, cassandraStatement{`DELETE FROM produtos_por_vendas WHERE partition_key = 'all' AND total_vendido = ? AND id = ?`, []any{previous, id}})
+			}
+			product := products[id]
+			ranking = append(ranking, cassandraStatement{insertProdutoPorVendas, []any{totals[id], id, product.Name, product.Category, product.Price, product.Stock}})
+		}
+		if err := c.execute(gocql.UnloggedBatch, 200, ranking); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// BatchCreatePayment confere em pedidos_por_id que os pedidos existem, já que
+// o Cassandra não tem chaves estrangeiras, e grava o pagamento nas tabelas por
+// tipo e mês e por cliente.
+func (c *CassandraRepository) BatchCreatePayment(payments []model.Payment) error {
+	if err := validatePayments(payments, c.orderClients); err != nil {
+		return err
+	}
+
+	var statements []cassandraStatement
+	for i := range payments {
+		payment := &payments[i]
+		ensureID(&payment.ID)
+
+		id, orderID, clientID := model.FormatID(payment.ID), model.FormatID(payment.OrderID), model.FormatID(payment.ClientID)
+		month := payment.PaymentDate.Format("2006-01")
+		statements = append(statements,
+			cassandraStatement{insertPagamentoPorTipoEMes, []any{
+				payment.Type, month, payment.PaymentDate, id, orderID, clientID,
+				payment.Status, payment.Amount, payment.Installments, payment.Refunded,
+			}},
+			cassandraStatement{insertPagamentoPorCliente, []any{
+				clientID, payment.PaymentDate, id, orderID, payment.Type,
+				payment.Status, payment.Amount, payment.Installments, payment.Refunded,
+			}},
+			cassandraStatement{insertPagamentoPorID, []any{
+				id, payment.Type, month, payment.PaymentDate, orderID, clientID,
+				payment.Status, payment.Amount, payment.Installments, payment.Refunded,
+			}},
+		)
+	}
+	return c.execute(gocql.UnloggedBatch, c.batchStatements(100, 3), statements)
+}
+
 func (c *CassandraRepository) GetClientByEmail(email string) (model.Client, error) {
-	query := `SELECT * FROM clientes_por_email WHERE email = ?`
+	query := `SELECT id, nome, email, telefone, data_cadastro, cpf FROM clientes_por_email WHERE email = ?`
 	var client model.Client
-	err := c.db.Query(query, email).Scan(&client)
+	var idStr string
+	err := c.db.Query(query, email).Scan(&idStr, &client.Nome, &client.Email, &client.Phone, &client.CreatedAt, &client.CPF)
 	if err != nil && err == gocql.ErrNotFound {
 		return model.Client{}, nil
 	}
 	if err != nil {
 		return model.Client{}, err
 	}
+	if client.ID, err = model.ParseID(idStr); err != nil {
+		return model.Client{}, err
+	}
 	return client, nil
 }
 
 func (c *CassandraRepository) GetProductByCategory(category string) ([]model.Product, error) {
-	query := `SELECT * FROM produtos_por_categoria WHERE categoria = ?`
+	query := `SELECT id_produto, nome, categoria, preco, estoque FROM produtos_por_categoria_v2 WHERE categoria = ?`
 	var products []model.Product
-	err := c.db.Query(query, category).Scan(&products)
-	if err != nil && err == gocql.ErrNotFound {
-		return nil, nil
+	iter := c.db.Query(query, category).Iter()
+
+	var (
+		idStr   string
+		product model.Product
+	)
+	for iter.Scan(&idStr, &product.Name, &product.Category, &product.Price, &product.Stock) {
+		id, err := model.ParseID(idStr)
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		product.ID = id
+		products = append(products, product)
 	}
-	if err != nil {
+
+	if err := iter.Close(); err != nil {
 		return nil, err
 	}
 	return products, nil
@@ -195,9 +271,9 @@ func (c *CassandraRepository) GetProductByCategory(category string) ([]model.Pro
 
 func (c *CassandraRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
 	query := `
-		SELECT pedido_id, data_pedido, status, valor_total, itens
+		SELECT pedido_id, data_pedido, status, valor_total, itens, id_endereco, codigo_cupom, desconto
 		FROM pedidos_por_cliente
-		WHERE id_cliente = ? AND status = 'entregue'
+		WHERE id_cliente = ? AND status = ?
 		ALLOW FILTERING
 	`
 
@@ -206,55 +282,68 @@ func (c *CassandraRepository) GetDeliveredOrdersByClient(clientID uint) ([]model
 		pedidoIDStr string
 		dataPedido  time.Time
 		status      string
-		valorTotal  float64
+		valorTotal  model.Money
 		itensJSON   string
+		enderecoID  *string
+		codigoCupom *string
+		desconto    model.Money
 	)
 
-	iter := c.db.Query(query, fmt.Sprintf("%d", clientID)).Iter()
-	for iter.Scan(&pedidoIDStr, &dataPedido, &status, &valorTotal, &itensJSON) {
-		pedidoID, err := strconv.ParseUint(pedidoIDStr, 10, 64)
+	iter := c.db.Query(query, model.FormatID(clientID), model.OrderStatusDelivered).Iter()
+	for iter.Scan(&pedidoIDStr, &dataPedido, &status, &valorTotal, &itensJSON, &enderecoID, &codigoCupom, &desconto) {
+		pedidoID, err := model.ParseID(pedidoIDStr)
 		if err != nil {
+			iter.Close()
 			return nil, fmt.Errorf("erro ao converter pedido_id: %v", err)
 		}
 
-		var itensMap []map[string]interface{}
-		if err := json.Unmarshal([]byte(itensJSON), &itensMap); err != nil {
+		var itensCassandra []cassandraItem
+		if err := json.Unmarshal([]byte(itensJSON), &itensCassandra); err != nil {
+			iter.Close()
 			return nil, fmt.Errorf("erro ao decodificar itens do pedido: %v", err)
 		}
 
 		var itens []model.OrderItem
-		for _, itemMap := range itensMap {
-			produtoIDStr := itemMap["produto_id"].(string)
-			produtoID, err := strconv.ParseUint(produtoIDStr, 10, 64)
+		for _, itemCassandra := range itensCassandra {
+			produtoID, err := model.ParseID(itemCassandra.ProdutoID)
 			if err != nil {
+				iter.Close()
 				return nil, fmt.Errorf("erro ao converter produto_id: %v", err)
 			}
 
-			quantidade := int(itemMap["quantidade"].(float64))
-			nomeProduto := itemMap["nome_produto"].(string)
-			precoUnitario := itemMap["preco_unitario"].(float64)
-
 			item := model.OrderItem{
-				OrderID:   uint(pedidoID),
-				ProductID: uint(produtoID),
-				Quantity:  quantidade,
+				OrderID:   pedidoID,
+				ProductID: produtoID,
+				Quantity:  itemCassandra.Quantidade,
 				Product: model.Product{
-					ID:    uint(produtoID),
-					Name:  nomeProduto,
-					Price: precoUnitario,
+					ID:    produtoID,
+					Name:  itemCassandra.NomeProduto,
+					Price: itemCassandra.PrecoUnitario,
 				},
 			}
 			itens = append(itens, item)
 		}
 
 		order := model.Order{
-			ID:         uint(pedidoID),
+			ID:         pedidoID,
 			ClientID:   clientID,
 			OrderDate:  dataPedido,
 			Status:     status,
 			TotalValue: valorTotal,
+			Discount:   desconto,
 			Itens:      itens,
 		}
+		if codigoCupom != nil {
+			order.CouponCode = *codigoCupom
+		}
+		if enderecoID != nil {
+			addressID, err := model.ParseID(*enderecoID)
+			if err != nil {
+				iter.Close()
+				return nil, fmt.Errorf("erro ao converter id_endereco: %v", err)
+			}
+			order.AddressID = &addressID
+		}
 		orders = append(orders, order)
 	}
 
@@ -274,18 +363,19 @@ func (c *CassandraRepository) Get5MostSoldProducts() ([]model.Product, error) {
 		idStr     string
 		nome      string
 		categoria string
-		preco     float64
+		preco     model.Money
 		estoque   int
 	)
 
 	for iter.Scan(&idStr, &nome, &categoria, &preco, &estoque) {
-		id, err := strconv.ParseUint(idStr, 10, 64)
+		id, err := model.ParseID(idStr)
 		if err != nil {
+			iter.Close()
 			return nil, fmt.Errorf("erro ao converter id do produto: %v", err)
 		}
 
 		product := model.Product{
-			ID:       uint(id),
+			ID:       id,
 			Name:     nome,
 			Category: categoria,
 			Price:    preco,
@@ -304,71 +394,1003 @@ func (c *CassandraRepository) Get5MostSoldProducts() ([]model.Product, error) {
 func (c *CassandraRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
 	startDate := time.Now().AddDate(0, -1, 0)
 	endDate := time.Now()
-	mesAno := startDate.Format("2006-01")
 
 	query := `
-		SELECT id_pagamento, id_pedido, data_pagamento, valor_total
-		FROM pagamentos_por_tipo_e_mes
-		WHERE tipo = 'pix' AND mes_ano = ? AND data_pagamento >= ? AND data_pagamento <= ?
+		SELECT id_pagamento, id_pedido, id_cliente, status, valor, parcelas, valor_estornado, data_pagamento
+		FROM pagamentos_por_tipo_e_mes_v2
+		WHERE tipo = ? AND mes_ano = ? AND data_pagamento >= ? AND data_pagamento <= ?
 	`
 
 	var payments []model.Payment
-	iter := c.db.Query(query, mesAno, startDate, endDate).Iter()
-
 	var (
 		idPagamentoStr string
 		idPedidoStr    string
+		idClienteStr   string
+		status         string
+		valor          model.Money
+		parcelas       int
+		valorEstornado model.Money
 		dataPagamento  time.Time
-		valorTotal     float64
 	)
 
-	for iter.Scan(&idPagamentoStr, &idPedidoStr, &dataPagamento, &valorTotal) {
-		idPagamento, err := strconv.ParseUint(idPagamentoStr, 10, 64)
+	// A tabela é particionada por mês, então o período é lido mês a mês.
+	for month := monthStart(startDate); !month.After(endDate); month = month.AddDate(0, 1, 0) {
+		iter := c.db.Query(query, model.PaymentTypePix, month.Format("2006-01"), startDate, endDate).Iter()
+		for iter.Scan(&idPagamentoStr, &idPedidoStr, &idClienteStr, &status, &valor, &parcelas, &valorEstornado, &dataPagamento) {
+			idPagamento, err := model.ParseID(idPagamentoStr)
+			if err != nil {
+				iter.Close()
+				return nil, fmt.Errorf("erro ao converter id_pagamento: %v", err)
+			}
+
+			idPedido, err := model.ParseID(idPedidoStr)
+			if err != nil {
+				iter.Close()
+				return nil, fmt.Errorf("erro ao converter id_pedido: %v", err)
+			}
+
+			idCliente, err := model.ParseID(idClienteStr)
+			if err != nil {
+				iter.Close()
+				return nil, fmt.Errorf("erro ao converter id_cliente: %v", err)
+			}
+
+			payment := model.Payment{
+				ID:           idPagamento,
+				OrderID:      idPedido,
+				ClientID:     idCliente,
+				Type:         model.PaymentTypePix,
+				Status:       status,
+				Amount:       valor,
+				Installments: parcelas,
+				Refunded:     valorEstornado,
+				PaymentDate:  dataPagamento,
+			}
+			payments = append(payments, payment)
+		}
+
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return payments, nil
+}
+
+// Soma o valor não estornado dos pagamentos do cliente no período, lidos de uma
+// única partição de pagamentos_por_cliente.
+func (c *CassandraRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	query := `
+		SELECT valor, valor_estornado
+		FROM pagamentos_por_cliente
+		WHERE id_cliente = ? AND data_pagamento >= ? AND data_pagamento <= ?
+	`
+
+	var total, valor, estornado model.Money
+	iter := c.db.Query(query, model.FormatID(clientID), startDate, endDate).Iter()
+	for iter.Scan(&valor, &estornado) {
+		total += valor - estornado
+	}
+	if err := iter.Close(); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// refundAttempts limita as releituras do pagamento ou do pedido quando outro
+// estorno vence a lightweight transaction.
+const refundAttempts = 5
+
+// RefundPayment aplica o estorno com uma lightweight transaction em
+// pagamentos_por_id: o UPDATE só vale se valor_estornado ainda for o valor
+// lido, então estornos concorrentes do mesmo pagamento não passam do valor
+// pago e o perdedor relê o pagamento antes de tentar de novo. O estorno e o
+// novo status das outras tabelas de pagamentos são gravados em seguida, em um
+// batch logged; se o batch falhar, o valor é devolvido a pagamentos_por_id. O
+// status do pedido é gravado por último, também com uma lightweight
+// transaction. Repetir a chamada com o mesmo estorno depois que ele foi
+// gravado apenas conclui a atualização do pedido.
+func (c *CassandraRepository) RefundPayment(refund *model.Refund) error {
+	if err := refund.Validate(); err != nil {
+		return err
+	}
+
+	payment, month, err := c.paymentByID(refund.PaymentID)
+	if err != nil {
+		return err
+	}
+	if payment.ID == 0 {
+		return payment.CheckRefund(*refund)
+	}
+	id, orderID, clientID := model.FormatID(payment.ID), model.FormatID(payment.OrderID), model.FormatID(payment.ClientID)
+
+	orders, err := c.ordersByID([]string{orderID})
+	if err != nil {
+		return err
+	}
+	order, ok := orders[orderID]
+	if !ok {
+		return fmt.Errorf("pedido %d do pagamento %d não encontrado", payment.OrderID, payment.ID)
+	}
+
+	recorded := false
+	if refund.ID != 0 {
+		var found string
+		err := c.db.Query(`SELECT id_estorno FROM estornos_por_pagamento WHERE id_pagamento = ? AND data_estorno = ? AND id_estorno = ?`,
+			id, refund.RefundDate, model.FormatID(refund.ID)).Scan(&found)
+		if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+			return err
+		}
+		recorded = err == nil
+	}
+
+	if !recorded {
+		if payment, month, err = c.addRefunded(refund.PaymentID, refund.Amount, func(p model.Payment) error { return p.CheckRefund(*refund) }); err != nil {
+			return err
+		}
+
+		ensureID(&refund.ID)
+		refund.OrderID, refund.ClientID = payment.OrderID, payment.ClientID
+
+		batch := c.db.NewBatch(gocql.LoggedBatch)
+		batch.Query(`INSERT INTO estornos_por_pagamento (id_pagamento, data_estorno, id_estorno, id_pedido, id_cliente, valor, motivo) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, refund.RefundDate, model.FormatID(refund.ID), orderID, clientID, refund.Amount, refund.Reason)
+		batch.Query(`UPDATE pagamentos_por_tipo_e_mes_v2 SET status = ?, valor_estornado = ? WHERE tipo = ? AND mes_ano = ? AND data_pagamento = ? AND id_pagamento = ?`,
+			payment.Status, payment.Refunded, payment.Type, month, payment.PaymentDate, id)
+		batch.Query(`UPDATE pagamentos_por_cliente SET status = ?, valor_estornado = ? WHERE id_cliente = ? AND data_pagamento = ? AND id_pagamento = ?`,
+			payment.Status, payment.Refunded, clientID, payment.PaymentDate, id)
+		if err := c.db.ExecuteBatch(batch); err != nil {
+			if _, _, undoErr := c.addRefunded(refund.PaymentID, -refund.Amount, nil); undoErr != nil {
+				return errors.Join(err, fmt.Errorf("erro ao devolver o estorno ao pagamento %d: %w", payment.ID, undoErr))
+			}
+			return err
+		}
+	}
+	refund.OrderID, refund.ClientID = payment.OrderID, payment.ClientID
+
+	return c.updateRefundedOrderStatus(clientID, orderID, order.total)
+}
+
+// addRefunded soma amount ao valor estornado do pagamento em pagamentos_por_id
+// com uma lightweight transaction, relendo o pagamento quando outro estorno
+// vence a disputa. check, quando informado, confere cada pagamento lido antes
+// da gravação. Retorna o pagamento com o novo valor estornado e status.
+func (c *CassandraRepository) addRefunded(paymentID uint, amount model.Money, check func(model.Payment) error) (model.Payment, string, error) {
+	for range refundAttempts {
+		payment, month, err := c.paymentByID(paymentID)
+		if err != nil {
+			return model.Payment{}, "", err
+		}
+		if check != nil {
+			if err := check(payment); err != nil {
+				return model.Payment{}, "", err
+			}
+		}
+
+		previous := payment.Refunded
+		payment.Refunded += amount
+		payment.Status = model.RefundStatus(payment.Amount, payment.Refunded)
+		if payment.Refunded == 0 {
+			payment.Status = model.PaymentStatusApproved
+		}
+		applied, err := c.db.Query(`UPDATE pagamentos_por_id SET valor_estornado = ?, status = ? WHERE id = ? IF valor_estornado = ?`,
+			payment.Refunded, payment.Status, model.FormatID(payment.ID), previous).MapScanCAS(make(map[string]any))
 		if err != nil {
-			return nil, fmt.Errorf("erro ao converter id_pagamento: %v", err)
+			return model.Payment{}, "", err
+		}
+		if applied {
+			return payment, month, nil
 		}
+	}
+	return model.Payment{}, "", fmt.Errorf("estorno do pagamento %d não aplicado após %d tentativas concorrentes", paymentID, refundAttempts)
+}
 
-		idPedido, err := strconv.ParseUint(idPedidoStr, 10, 64)
+// updateRefundedOrderStatus grava o status do pedido a partir do total estornado
+// dos seus pagamentos, lidos da partição do cliente. O UPDATE só vale se o
+// status ainda for o lido antes da soma: um estorno concorrente de outro
+// pagamento do pedido que grave o status no meio faz esta chamada somar de
+// novo, então o último status gravado inclui os dois estornos.
+func (c *CassandraRepository) updateRefundedOrderStatus(clientID, orderID string, total model.Money) error {
+	for range refundAttempts {
+		var previous string
+		err := c.db.Query(`SELECT status FROM pedidos_por_cliente WHERE id_cliente = ? AND pedido_id = ?`, clientID, orderID).Scan(&previous)
 		if err != nil {
-			return nil, fmt.Errorf("erro ao converter id_pedido: %v", err)
+			return err
 		}
 
-		payment := model.Payment{
-			ID:          uint(idPagamento),
-			OrderID:     uint(idPedido),
-			Type:        "pix",
-			Status:      "aprovado",
-			PaymentDate: dataPagamento,
+		var refunded, paymentRefunded model.Money
+		var paymentOrderID string
+		iter := c.db.Query(`SELECT id_pedido, valor_estornado FROM pagamentos_por_cliente WHERE id_cliente = ?`, clientID).Iter()
+		for iter.Scan(&paymentOrderID, &paymentRefunded) {
+			if paymentOrderID == orderID {
+				refunded += paymentRefunded
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+
+		applied, err := c.db.Query(`UPDATE pedidos_por_cliente SET status = ? WHERE id_cliente = ? AND pedido_id = ? IF status = ?`,
+			model.RefundStatus(total, refunded), clientID, orderID, previous).MapScanCAS(make(map[string]any))
+		if err != nil {
+			return err
+		}
+		if applied {
+			return nil
 		}
-		payments = append(payments, payment)
 	}
+	return fmt.Errorf("status do pedido %s não atualizado após %d tentativas concorrentes", orderID, refundAttempts)
+}
 
+// paymentByID lê o pagamento e o mes_ano da sua partição em
+// pagamentos_por_tipo_e_mes_v2. Um pagamento inexistente volta com valor zero.
+func (c *CassandraRepository) paymentByID(id uint) (model.Payment, string, error) {
+	query := `
+		SELECT tipo, mes_ano, data_pagamento, id_pedido, id_cliente, status, valor, parcelas, valor_estornado
+		FROM pagamentos_por_id
+		WHERE id = ?
+	`
+
+	var (
+		payment           model.Payment
+		month             string
+		orderID, clientID string
+	)
+	err := c.db.Query(query, model.FormatID(id)).Scan(&payment.Type, &month, &payment.PaymentDate, &orderID, &clientID,
+		&payment.Status, &payment.Amount, &payment.Installments, &payment.Refunded)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return model.Payment{}, "", nil
+	}
+	if err != nil {
+		return model.Payment{}, "", err
+	}
+
+	payment.ID = id
+	if payment.OrderID, err = model.ParseID(orderID); err != nil {
+		return model.Payment{}, "", err
+	}
+	if payment.ClientID, err = model.ParseID(clientID); err != nil {
+		return model.Payment{}, "", err
+	}
+	return payment, month, nil
+}
+
+func (c *CassandraRepository) Refunds(paymentID uint) ([]model.Refund, error) {
+	query := `
+		SELECT id_estorno, id_pedido, id_cliente, valor, motivo, data_estorno
+		FROM estornos_por_pagamento
+		WHERE id_pagamento = ?
+	`
+
+	var (
+		refunds               []model.Refund
+		refund                model.Refund
+		id, orderID, clientID string
+	)
+	iter := c.db.Query(query, model.FormatID(paymentID)).Iter()
+	for iter.Scan(&id, &orderID, &clientID, &refund.Amount, &refund.Reason, &refund.RefundDate) {
+		var err error
+		if refund.ID, err = model.ParseID(id); err != nil {
+			iter.Close()
+			return nil, err
+		}
+		if refund.OrderID, err = model.ParseID(orderID); err != nil {
+			iter.Close()
+			return nil, err
+		}
+		if refund.ClientID, err = model.ParseID(clientID); err != nil {
+			iter.Close()
+			return nil, err
+		}
+		refund.PaymentID = paymentID
+		refunds = append(refunds, refund)
+	}
 	if err := iter.Close(); err != nil {
 		return nil, err
 	}
+	return refunds, nil
+}
 
-	return payments, nil
+// BatchCreateReview grava as avaliações em avaliacoes_por_produto e soma a
+// contagem e as notas de cada categoria nos contadores de
+// notas_por_categoria, com um UPDATE por categoria do lote. Como em
+// BatchCreateOrderItem, repetir um lote conta as avaliações de novo.
+func (c *CassandraRepository) BatchCreateReview(reviews []model.Review) error {
+	categories, err := validateReviews(reviews, c.productCategories)
+	if err != nil {
+		return err
+	}
+
+	statements := make([]cassandraStatement, len(reviews))
+	counts := make(map[string]int64)
+	sums := make(map[string]int64)
+	for i := range reviews {
+		review := &reviews[i]
+		ensureID(&review.ID)
+
+		statements[i] = cassandraStatement{`
+			INSERT INTO avaliacoes_por_produto (id_produto, id_avaliacao, id_cliente, nota, comentario, data_avaliacao)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			[]any{model.FormatID(review.ProductID), int64(review.ID), model.FormatID(review.ClientID), review.Rating, review.Comment, review.CreatedAt},
+		}
+		category := categories[review.ProductID]
+		counts[category]++
+		sums[category] += int64(review.Rating)
+	}
+	if err := c.execute(gocql.UnloggedBatch, c.batchStatements(100, 1), statements); err != nil {
+		return err
+	}
+
+	var counters []cassandraStatement
+	for _, category := range slices.Sorted(maps.Keys(counts)) {
+		counters = append(counters, cassandraStatement{updateNotasPorCategoria, []any{counts[category], sums[category], category}})
+	}
+	return c.execute(gocql.CounterBatch, 100, counters)
+}
+
+// productCategories consulta produtos_por_id em blocos de 100 IDs.
+func (c *CassandraRepository) productCategories(productIDs []uint) (map[uint]string, error) {
+	categories := make(map[uint]string)
+	for chunk := range slices.Chunk(productIDs, 100) {
+		ids := make([]string, len(chunk))
+		for i, id := range chunk {
+			ids[i] = model.FormatID(id)
+		}
+		products, err := c.productsByID(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range chunk {
+			if product, ok := products[model.FormatID(id)]; ok {
+				categories[id] = product.Category
+			}
+		}
+	}
+	return categories, nil
 }
 
-func (c *CassandraRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (float64, error) {
-	mesAno := startDate.Format("2006-01")
+// GetProductReviews lê uma fatia da partição do produto, já ordenada por
+// id_avaliacao decrescente.
+func (c *CassandraRepository) GetProductReviews(productID uint, beforeID uint, limit int) ([]model.Review, error) {
 	query := `
-		SELECT SUM(valor_total)
-		FROM pagamentos_por_tipo_e_mes
-		WHERE id_cliente = ? AND mes_ano = ? AND data_pagamento >= ? AND data_pagamento <= ?
-		ALLOW FILTERING
+		SELECT id_avaliacao, id_cliente, nota, comentario, data_avaliacao
+		FROM avaliacoes_por_produto
+		WHERE id_produto = ?`
+	args := []any{model.FormatID(productID)}
+	if beforeID != 0 {
+		query += ` AND id_avaliacao < ?`
+		args = append(args, int64(beforeID))
+	}
+	query += ` LIMIT ?`
+	args = append(args, limit)
+
+	var (
+		reviews  []model.Review
+		review   model.Review
+		id       int64
+		clientID string
+	)
+	iter := c.db.Query(query, args...).Iter()
+	for iter.Scan(&id, &clientID, &review.Rating, &review.Comment, &review.CreatedAt) {
+		var err error
+		if review.ClientID, err = model.ParseID(clientID); err != nil {
+			iter.Close()
+			return nil, err
+		}
+		review.ID, review.ProductID = uint(id), productID
+		reviews = append(reviews, review)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// GetAverageRatingByCategory lê os contadores de notas_por_categoria, uma
+// linha por categoria, e ordena pelo nome.
+func (c *CassandraRepository) GetAverageRatingByCategory() ([]model.CategoryRating, error) {
+	var (
+		ratings          []model.CategoryRating
+		category         string
+		count, ratingSum int64
+	)
+	iter := c.db.Query(`SELECT categoria, avaliacoes, soma_notas FROM notas_por_categoria`).Iter()
+	for iter.Scan(&category, &count, &ratingSum) {
+		ratings = append(ratings, model.CategoryRating{Category: category, Reviews: int(count), RatingSum: int(ratingSum)})
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(ratings, func(a, b model.CategoryRating) int {
+		return strings.Compare(a.Category, b.Category)
+	})
+	return ratings, nil
+}
+
+// BatchCreateShipment confere os pedidos em pedidos_por_id e grava cada envio
+// em envios_por_pedido e envios_por_id.
+func (c *CassandraRepository) BatchCreateShipment(shipments []model.Shipment) error {
+	if err := validateShipments(shipments, c.orderClients); err != nil {
+		return err
+	}
+
+	var statements []cassandraStatement
+	for i := range shipments {
+		shipment := &shipments[i]
+		ensureID(&shipment.ID)
+		id, orderID, address := model.FormatID(shipment.ID), model.FormatID(shipment.OrderID), shipment.Address
+
+		statements = append(statements,
+			cassandraStatement{`
+				INSERT INTO envios_por_pedido (
+					id_pedido, data_envio, id_envio, transportadora, codigo_rastreio,
+					cep, logradouro, numero, complemento, cidade, uf
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				[]any{orderID, shipment.ShippedAt, id, shipment.Carrier, shipment.TrackingCode,
+					address.CEP, address.Street, address.Number, address.Complement, address.City, address.State},
+			},
+			cassandraStatement{`INSERT INTO envios_por_id (id, id_pedido) VALUES (?, ?)`, []any{id, orderID}},
+		)
+	}
+	return c.execute(gocql.UnloggedBatch, c.batchStatements(100, 2), statements)
+}
+
+// AppendTrackingEvents grava os eventos em eventos_por_envio sem ler a
+// partição: a ordem vem da chave de clustering. Os eventos são agrupados por
+// envio, para que cada batch UNLOGGED vá para poucas partições.
+func (c *CassandraRepository) AppendTrackingEvents(events []model.TrackingEvent) error {
+	if err := validateTrackingEvents(events, c.shipmentOrders); err != nil {
+		return err
+	}
+
+	sorted := slices.Clone(events)
+	slices.SortStableFunc(sorted, func(a, b model.TrackingEvent) int {
+		return cmp.Compare(a.ShipmentID, b.ShipmentID)
+	})
+	statements := make([]cassandraStatement, len(sorted))
+	for i, event := range sorted {
+		statements[i] = cassandraStatement{`
+			INSERT INTO eventos_por_envio (id_envio, data_evento, status, local, descricao)
+			VALUES (?, ?, ?, ?, ?)`,
+			[]any{model.FormatID(event.ShipmentID), event.OccurredAt, event.Status, event.Location, event.Description},
+		}
+	}
+	return c.execute(gocql.UnloggedBatch, c.batchStatements(100, 1), statements)
+}
+
+// shipmentOrders consulta envios_por_id em blocos de 100 IDs.
+func (c *CassandraRepository) shipmentOrders(shipmentIDs []uint) (map[uint]uint, error) {
+	orders := make(map[uint]uint)
+	for chunk := range slices.Chunk(shipmentIDs, 100) {
+		ids := make([]string, len(chunk))
+		for i, id := range chunk {
+			ids[i] = model.FormatID(id)
+		}
+
+		var id, orderID string
+		iter := c.db.Query(`SELECT id, id_pedido FROM envios_por_id WHERE id IN ?`, ids).Iter()
+		for iter.Scan(&id, &orderID) {
+			shipmentID, err := model.ParseID(id)
+			if err != nil {
+				iter.Close()
+				return nil, err
+			}
+			if orders[shipmentID], err = model.ParseID(orderID); err != nil {
+				iter.Close()
+				return nil, err
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+// GetTrackingHistory lê a partição do pedido em envios_por_pedido e a de cada
+// envio em eventos_por_envio, já em ordem de data.
+func (c *CassandraRepository) GetTrackingHistory(orderID uint) ([]model.Shipment, error) {
+	query := `
+		SELECT id_envio, data_envio, transportadora, codigo_rastreio, cep, logradouro, numero, complemento, cidade, uf
+		FROM envios_por_pedido
+		WHERE id_pedido = ?
 	`
 
-	var total float64
-	err := c.db.Query(query, fmt.Sprintf("%d", clientID), mesAno, startDate, endDate).Scan(&total)
-	if err != nil && err == gocql.ErrNotFound {
-		return 0, nil
+	var (
+		shipments []model.Shipment
+		shipment  model.Shipment
+		id        string
+	)
+	address := &shipment.Address
+	iter := c.db.Query(query, model.FormatID(orderID)).Iter()
+	for iter.Scan(&id, &shipment.ShippedAt, &shipment.Carrier, &shipment.TrackingCode,
+		&address.CEP, &address.Street, &address.Number, &address.Complement, &address.City, &address.State) {
+		var err error
+		if shipment.ID, err = model.ParseID(id); err != nil {
+			iter.Close()
+			return nil, err
+		}
+		shipment.OrderID = orderID
+		shipments = append(shipments, shipment)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	for i := range shipments {
+		shipment := &shipments[i]
+		var event model.TrackingEvent
+		iter := c.db.Query(`SELECT data_evento, status, local, descricao FROM eventos_por_envio WHERE id_envio = ?`, model.FormatID(shipment.ID)).Iter()
+		for iter.Scan(&event.OccurredAt, &event.Status, &event.Location, &event.Description) {
+			event.ShipmentID = shipment.ID
+			shipment.Events = append(shipment.Events, event)
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
 	}
+	return shipments, nil
+}
+
+// BatchCreateAddress grava os endereços em enderecos_por_cliente e
+// enderecos_por_id. O padrão é a coluna estática endereco_padrao da partição
+// do cliente, então gravar um novo padrão já substitui o anterior.
+func (c *CassandraRepository) BatchCreateAddress(addresses []model.Address) error {
+	if err := validateAddresses(addresses, c.existingClients); err != nil {
+		return err
+	}
+
+	var statements []cassandraStatement
+	for i := range addresses {
+		address := &addresses[i]
+		ensureID(&address.ID)
+		id, clientID := model.FormatID(address.ID), model.FormatID(address.ClientID)
+
+		statements = append(statements,
+			cassandraStatement{`
+				INSERT INTO enderecos_por_cliente (
+					id_cliente, id_endereco, cep, logradouro, numero, complemento, cidade, uf
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				[]any{clientID, id, address.CEP, address.Street, address.Number, address.Complement, address.City, address.State},
+			},
+			cassandraStatement{`INSERT INTO enderecos_por_id (id, id_cliente, uf) VALUES (?, ?, ?)`, []any{id, clientID, address.State}},
+		)
+		if address.Default {
+			statements = append(statements, cassandraStatement{updateEnderecoPadrao, []any{id, clientID}})
+		}
+	}
+	return c.execute(gocql.UnloggedBatch, c.batchStatements(100, 3), statements)
+}
+
+// SetDefaultAddress confere o dono do endereço em enderecos_por_id e troca a
+// coluna estática endereco_padrao do cliente.
+func (c *CassandraRepository) SetDefaultAddress(clientID uint, addressID uint) error {
+	addresses, err := c.addresses([]uint{addressID})
 	if err != nil {
-		return 0, err
+		return err
+	}
+	if address, ok := addresses[addressID]; !ok || address.ClientID != clientID {
+		return addressNotFound(clientID, addressID)
 	}
+	return c.db.Query(updateEnderecoPadrao, model.FormatID(addressID), model.FormatID(clientID)).Exec()
+}
 
-	return total, nil
+// GetClientAddresses lê a partição do cliente em enderecos_por_cliente, que
+// traz endereco_padrao em todas as linhas.
+func (c *CassandraRepository) GetClientAddresses(clientID uint) ([]model.Address, error) {
+	query := `
+		SELECT id_endereco, cep, logradouro, numero, complemento, cidade, uf, endereco_padrao
+		FROM enderecos_por_cliente
+		WHERE id_cliente = ?
+	`
+
+	var (
+		addresses []model.Address
+		address   model.Address
+		id        string
+		defaultID *string
+	)
+	iter := c.db.Query(query, model.FormatID(clientID)).Iter()
+	for iter.Scan(&id, &address.CEP, &address.Street, &address.Number, &address.Complement, &address.City, &address.State, &defaultID) {
+		var err error
+		if address.ID, err = model.ParseID(id); err != nil {
+			iter.Close()
+			return nil, err
+		}
+		address.ClientID = clientID
+		address.Default = defaultID != nil && *defaultID == id
+		addresses = append(addresses, address)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	sortAddresses(addresses)
+	return addresses, nil
+}
+
+// GetRevenueByState soma a partição de cada UF em pedidos_por_uf. As UFs sem
+// pedidos ficam de fora, como nos outros bancos.
+func (c *CassandraRepository) GetRevenueByState() ([]model.StateRevenue, error) {
+	var revenue []model.StateRevenue
+	for _, state := range model.States {
+		var (
+			count int64
+			sum   model.Money
+		)
+		err := c.db.Query(`SELECT COUNT(*), SUM(valor_total) FROM pedidos_por_uf WHERE uf = ?`, state).Scan(&count, &sum)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			revenue = append(revenue, model.StateRevenue{State: state, Orders: int(count), Revenue: sum})
+		}
+	}
+
+	slices.SortFunc(revenue, func(a, b model.StateRevenue) int {
+		return strings.Compare(a.State, b.State)
+	})
+	return revenue, nil
+}
+
+// existingClients consulta clientes_por_id em blocos de 100 IDs.
+func (c *CassandraRepository) existingClients(clientIDs []uint) (map[uint]bool, error) {
+	clients := make(map[uint]bool)
+	for chunk := range slices.Chunk(clientIDs, 100) {
+		ids := make([]string, len(chunk))
+		for i, id := range chunk {
+			ids[i] = model.FormatID(id)
+		}
+
+		var id string
+		iter := c.db.Query(`SELECT id FROM clientes_por_id WHERE id IN ?`, ids).Iter()
+		for iter.Scan(&id) {
+			clientID, err := model.ParseID(id)
+			if err != nil {
+				iter.Close()
+				return nil, err
+			}
+			clients[clientID] = true
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return clients, nil
+}
+
+// addresses consulta enderecos_por_id em blocos de 100 IDs.
+func (c *CassandraRepository) addresses(addressIDs []uint) (map[uint]model.Address, error) {
+	addresses := make(map[uint]model.Address)
+	for chunk := range slices.Chunk(addressIDs, 100) {
+		ids := make([]string, len(chunk))
+		for i, id := range chunk {
+			ids[i] = model.FormatID(id)
+		}
+
+		var id, clientID, state string
+		iter := c.db.Query(`SELECT id, id_cliente, uf FROM enderecos_por_id WHERE id IN ?`, ids).Iter()
+		for iter.Scan(&id, &clientID, &state) {
+			var address model.Address
+			var err error
+			if address.ID, err = model.ParseID(id); err != nil {
+				iter.Close()
+				return nil, err
+			}
+			if address.ClientID, err = model.ParseID(clientID); err != nil {
+				iter.Close()
+				return nil, err
+			}
+			address.State = state
+			addresses[address.ID] = address
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return addresses, nil
+}
+
+func (c *CassandraRepository) BatchCreateCoupon(coupons []model.Coupon) error {
+	if err := validateRecords(coupons); err != nil {
+		return err
+	}
+	statements := make([]cassandraStatement, len(coupons))
+	for i, coupon := range coupons {
+		statements[i] = cassandraStatement{`
+			INSERT INTO cupons_por_codigo (
+				codigo,
+				tipo,
+				percentual,
+				valor,
+				valor_minimo,
+				inicio_validade,
+				fim_validade,
+				limite_por_cliente,
+				categorias
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			[]any{coupon.Code, coupon.Type, coupon.Percent, coupon.Amount, coupon.MinOrderValue, coupon.ValidFrom, coupon.ValidUntil, coupon.UsageLimit, coupon.Categories},
+		}
+	}
+	return c.execute(gocql.UnloggedBatch, c.batchStatements(100, 1), statements)
+}
+
+func (c *CassandraRepository) coupon(code string) (model.Coupon, bool, error) {
+	query := `
+		SELECT codigo, tipo, percentual, valor, valor_minimo, inicio_validade, fim_validade, limite_por_cliente, categorias
+		FROM cupons_por_codigo
+		WHERE codigo = ?`
+	var coupon model.Coupon
+	err := c.db.Query(query, code).Scan(&coupon.Code, &coupon.Type, &coupon.Percent, &coupon.Amount, &coupon.MinOrderValue,
+		&coupon.ValidFrom, &coupon.ValidUntil, &coupon.UsageLimit, &coupon.Categories)
+	if err == gocql.ErrNotFound {
+		return model.Coupon{}, false, nil
+	}
+	if err != nil {
+		return model.Coupon{}, false, err
+	}
+	slices.Sort(coupon.Categories)
+	return coupon, true, nil
+}
+
+// couponUseAttempts limita as releituras dos usos de um cupom quando outro
+// pedido do mesmo cliente vence a lightweight transaction.
+const couponUseAttempts = 10
+
+// CreateOrderWithCoupon conta o uso com uma lightweight transaction em
+// usos_cupom_por_cliente e só então grava o pedido, como BatchCreateOrder. Se
+// a gravação falha o uso é devolvido, também com uma lightweight transaction.
+func (c *CassandraRepository) CreateOrderWithCoupon(order *model.Order) error {
+	coupon, err := prepareCouponOrder(order, c.addresses, c.existingClients, c.coupon, c.productCategories)
+	if err != nil {
+		return err
+	}
+
+	counted, err := c.addCouponUse(coupon, order.ClientID, 1)
+	if err != nil {
+		return err
+	}
+	if !counted {
+		return coupon.CheckUses(*order, coupon.UsageLimit)
+	}
+
+	ensureID(&order.ID)
+	if err := c.BatchCreateOrder([]model.Order{*order}); err != nil {
+		if _, undo := c.addCouponUse(coupon, order.ClientID, -1); undo != nil {
+			return errors.Join(err, undo)
+		}
+		return err
+	}
+	return nil
+}
+
+// addCouponUse soma delta aos usos do cupom pelo cliente: o INSERT IF NOT
+// EXISTS cria a contagem no primeiro uso e o UPDATE só vale se usos ainda for
+// o valor lido, então usos concorrentes nunca passam do limite. Retorna false,
+// sem gravar, quando delta é positivo e o cliente já chegou ao limite.
+func (c *CassandraRepository) addCouponUse(coupon model.Coupon, clientID uint, delta int) (bool, error) {
+	id := model.FormatID(clientID)
+	for attempt := 0; attempt < couponUseAttempts; attempt++ {
+		uses, exists := 0, true
+		err := c.db.Query(`SELECT usos FROM usos_cupom_por_cliente WHERE codigo = ? AND id_cliente = ?`, coupon.Code, id).Scan(&uses)
+		if err == gocql.ErrNotFound {
+			exists = false
+		} else if err != nil {
+			return false, err
+		}
+		if delta > 0 && uses >= coupon.UsageLimit {
+			return false, nil
+		}
+
+		var applied bool
+		if exists {
+			applied, err = c.db.Query(`UPDATE usos_cupom_por_cliente SET usos = ? WHERE codigo = ? AND id_cliente = ? IF usos = ?`,
+				uses+delta, coupon.Code, id, uses).MapScanCAS(make(map[string]any))
+		} else {
+			applied, err = c.db.Query(`INSERT INTO usos_cupom_por_cliente (codigo, id_cliente, usos) VALUES (?, ?, ?) IF NOT EXISTS`,
+				coupon.Code, id, delta).MapScanCAS(make(map[string]any))
+		}
+		if err != nil || applied {
+			return applied, err
+		}
+	}
+	return false, fmt.Errorf("uso do cupom %q pelo cliente %d não contado após %d tentativas concorrentes", coupon.Code, clientID, couponUseAttempts)
+}
+
+func (c *CassandraRepository) GetCouponUsage(code string) (model.CouponUsage, error) {
+	var usage model.CouponUsage
+	var discount model.Money
+	iter := c.db.Query(`SELECT desconto FROM pedidos_por_cupom WHERE codigo = ?`, code).Iter()
+	for iter.Scan(&discount) {
+		usage.Orders++
+		usage.Discount += discount
+	}
+	if err := iter.Close(); err != nil {
+		return model.CouponUsage{}, err
+	}
+	return usage, nil
+}
+
+// Comandos compartilhados entre as gravações e RebuildQueryTables.
+const (
+	insertClientePorID = `
+		INSERT INTO clientes_por_id (id, email)
+		VALUES (?, ?)`
+	updateEnderecoPadrao = `
+		UPDATE enderecos_por_cliente SET endereco_padrao = ?
+		WHERE id_cliente = ?`
+	insertPedidoPorUF = `
+		INSERT INTO pedidos_por_uf (uf, pedido_id, valor_total)
+		VALUES (?, ?, ?)`
+	insertPedidoPorCupom = `
+		INSERT INTO pedidos_por_cupom (codigo, pedido_id, desconto)
+		VALUES (?, ?, ?)`
+	insertProdutoPorCategoria = `
+		INSERT INTO produtos_por_categoria_v2 (categoria, preco, id_produto, nome, estoque)
+		VALUES (?, ?, ?, ?, ?)`
+	insertProdutoPorID = `
+		INSERT INTO produtos_por_id (id, nome, categoria, preco, estoque)
+		VALUES (?, ?, ?, ?, ?)`
+	insertPedidoPorID = `
+		INSERT INTO pedidos_por_id (id, id_cliente, valor_total)
+		VALUES (?, ?, ?)`
+	updateNotasPorCategoria = `
+		UPDATE notas_por_categoria SET avaliacoes = avaliacoes + ?, soma_notas = soma_notas + ?
+		WHERE categoria = ?`
+	insertProdutoPorVendas = `
+		INSERT INTO produtos_por_vendas (partition_key, total_vendido, id, nome, categoria, preco, estoque)
+		VALUES ('all', ?, ?, ?, ?, ?, ?)`
+	insertPagamentoPorTipoEMes = `
+		INSERT INTO pagamentos_por_tipo_e_mes_v2 (tipo, mes_ano, data_pagamento, id_pagamento, id_pedido, id_cliente, status, valor, parcelas, valor_estornado)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertPagamentoPorCliente = `
+		INSERT INTO pagamentos_por_cliente (id_cliente, data_pagamento, id_pagamento, id_pedido, tipo, status, valor, parcelas, valor_estornado)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertPagamentoPorID = `
+		INSERT INTO pagamentos_por_id (id, tipo, mes_ano, data_pagamento, id_pedido, id_cliente, status, valor, parcelas, valor_estornado)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+)
+
+// cassandraItem é o formato de cada item na coluna itens de pedidos_por_cliente.
+type cassandraItem struct {
+	ProdutoID     string      `json:"produto_id"`
+	Quantidade    int         `json:"quantidade"`
+	NomeProduto   string      `json:"nome_produto"`
+	PrecoUnitario model.Money `json:"preco_unitario"`
+}
+
+// cassandraOrder é a parte de um pedido usada pelos pagamentos: a existência do
+// pedido na gravação e, no reparo, o cliente e o valor dos pagamentos antigos.
+type cassandraOrder struct {
+	clientID string
+	total    model.Money
+}
+
+type cassandraStatement struct {
+	cql  string
+	args []any
+}
+
+// execute grava os comandos em batches de batchSize comandos do tipo kind, com
+// até WriteOptions.Parallelism batches ao mesmo tempo, ou, com
+// CASSANDRA_WRITE_STRATEGY=concorrente, um a um em paralelo. Batches com
+// linhas de partições diferentes sobrecarregam o coordenador; os comandos
+// individuais são preparados pelo gocql e enviados direto a uma réplica da
+// partição (token aware), com no máximo maxInFlight pendentes. Em caso de erro
+// os comandos já enviados terminam, mas nenhum outro é enviado.
+func (c *CassandraRepository) execute(kind gocql.BatchType, batchSize int, statements []cassandraStatement) error {
+	if !c.concurrentWrites {
+		chunks := slices.Collect(slices.Chunk(statements, batchSize))
+		return forEachParallel(chunks, c.writes.parallelism(), func(chunk []cassandraStatement) error {
+			batch := c.db.NewBatch(kind)
+			for _, s := range chunk {
+				batch.Query(s.cql, s.args...)
+			}
+			return c.db.ExecuteBatch(batch)
+		})
+	}
+
+	inFlight := c.maxInFlight
+	if c.writes.Parallelism > 0 {
+		inFlight = c.writes.Parallelism
+	}
+	return forEachParallel(statements, inFlight, func(s cassandraStatement) error {
+		return c.db.Query(s.cql, s.args...).Exec()
+	})
+}
+
+// SetWriteOptions define quantas linhas vão em cada batch e quantos batches
+// são enviados ao mesmo tempo. No modo concorrente, Parallelism substitui
+// CASSANDRA_MAX_IN_FLIGHT. TransactionPerBatch e Unordered não se aplicam.
+func (c *CassandraRepository) SetWriteOptions(opts WriteOptions) {
+	c.writes = opts
+}
+
+// batchStatements converte o tamanho de lote em linhas (WriteOptions.BatchSize
+// ou defaultRows) no número de comandos por batch de uma gravação que gera
+// statementsPerRow comandos por linha.
+func (c *CassandraRepository) batchStatements(defaultRows, statementsPerRow int) int {
+	return c.writes.batchSizeOr(defaultRows) * statementsPerRow
+}
+
+func (c *CassandraRepository) productsByID(ids []string) (map[string]model.Product, error) {
+	products := make(map[string]model.Product)
+	var (
+		idStr   string
+		product model.Product
+	)
+
+	iter := c.db.Query(`SELECT id, nome, categoria, preco, estoque FROM produtos_por_id WHERE id IN ?`, ids).Iter()
+	for iter.Scan(&idStr, &product.Name, &product.Category, &product.Price, &product.Stock) {
+		products[idStr] = product
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (c *CassandraRepository) ordersByID(ids []string) (map[string]cassandraOrder, error) {
+	orders := make(map[string]cassandraOrder)
+	var (
+		idStr string
+		order cassandraOrder
+	)
+
+	iter := c.db.Query(`SELECT id, id_cliente, valor_total FROM pedidos_por_id WHERE id IN ?`, ids).Iter()
+	for iter.Scan(&idStr, &order.clientID, &order.total) {
+		orders[idStr] = order
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// orderClients consulta pedidos_por_id em blocos de 100 IDs.
+func (c *CassandraRepository) orderClients(orderIDs []uint) (map[uint]uint, error) {
+	clients := make(map[uint]uint)
+	for chunk := range slices.Chunk(orderIDs, 100) {
+		ids := make([]string, len(chunk))
+		for i, id := range chunk {
+			ids[i] = model.FormatID(id)
+		}
+		orders, err := c.ordersByID(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range chunk {
+			order, ok := orders[model.FormatID(id)]
+			if !ok {
+				continue
+			}
+			clientID, err := model.ParseID(order.clientID)
+			if err != nil {
+				return nil, err
+			}
+			clients[id] = clientID
+		}
+	}
+	return clients, nil
+}
+
+func (c *CassandraRepository) salesByProduct(ids []string) (map[string]int64, error) {
+	totals := make(map[string]int64)
+	var (
+		idStr string
+		total int64
+	)
+
+	iter := c.db.Query(`SELECT id_produto, total_vendido FROM vendas_por_produto WHERE id_produto IN ?`, ids).Iter()
+	for iter.Scan(&idStr, &total) {
+		totals[idStr] = total
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return totals, nil
 }
 
 func NewCassandraRepository() *CassandraRepository {
@@ -381,10 +1403,18 @@ func NewCassandraRepository() *CassandraRepository {
 	cluster.NumConns = config.PoolSize
 	cluster.RetryPolicy = &gocql.SimpleRetryPolicy{NumRetries: config.Retries}
 
+	// Envia cada comando a uma réplica dona da partição, evitando um salto
+	// pelo coordenador nas gravações individuais.
+	cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy())
+
 	session, err := cluster.CreateSession()
 	if err != nil {
 		panic(fmt.Sprintf("Erro ao conectar com o Cassandra: %v", err))
 	}
 
-	return &CassandraRepository{db: session}
+	return &CassandraRepository{
+		db:               session,
+		concurrentWrites: config.WriteStrategy == "concorrente",
+		maxInFlight:      config.MaxInFlight,
+	}
 }
@@ -0,0 +1,281 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"techmarket_showcase/model"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// CassandraRepairReport resume o que RebuildQueryTables recalculou.
+type CassandraRepairReport struct {
+	Clients      int
+	Products     int
+	Orders       int
+	SoldProducts int
+	// AddressedOrders conta os pedidos gravados em pedidos_por_uf: os não
+	// cancelados com endereço.
+	AddressedOrders int
+	// CouponOrders conta os pedidos gravados em pedidos_por_cupom.
+	CouponOrders int
+	Payments     int
+	// OrphanPayments conta os pagamentos cujo pedido não existe; eles ficam
+	// fora de pagamentos_por_cliente.
+	OrphanPayments int
+	Reviews        int
+}
+
+// RebuildQueryTables recalcula as tabelas de consulta (clientes_por_id,
+// produtos_por_id, pedidos_por_id, vendas_por_produto, produtos_por_vendas,
+// pedidos_por_uf, pedidos_por_cupom, pagamentos_por_cliente,
+// pagamentos_por_id e notas_por_categoria) a partir de clientes_por_email,
+// produtos_por_categoria_v2, pedidos_por_cliente, enderecos_por_id,
+// pagamentos_por_tipo_e_mes_v2 e avaliacoes_por_produto. Os produtos e
+// pagamentos que só estão em produtos_por_categoria e
+// pagamentos_por_tipo_e_mes, gravados antes da versão 9 do schema, são
+// copiados para as tabelas _v2, com o cliente, o valor e o valor estornado dos
+// pagamentos antigos preenchidos. As tabelas derivadas são esvaziadas antes,
+// então o comando deve rodar sem gravações em andamento.
+func (c *CassandraRepository) RebuildQueryTables() (CassandraRepairReport, error) {
+	var report CassandraRepairReport
+
+	for _, table := range []string{"clientes_por_id", "produtos_por_id", "pedidos_por_id", "vendas_por_produto", "produtos_por_vendas", "pedidos_por_uf", "pedidos_por_cupom", "pagamentos_por_cliente", "pagamentos_por_id", "notas_por_categoria"} {
+		if err := c.db.Query("TRUNCATE " + table).Exec(); err != nil {
+			return report, fmt.Errorf("erro ao esvaziar %s: %w", table, err)
+		}
+	}
+
+	// Clientes
+	var statements []cassandraStatement
+	var id, email string
+	iter := c.db.Query(`SELECT email, id FROM clientes_por_email`).Iter()
+	for iter.Scan(&email, &id) {
+		statements = append(statements, cassandraStatement{insertClientePorID, []any{id, email}})
+	}
+	if err := iter.Close(); err != nil {
+		return report, err
+	}
+	if err := c.execute(gocql.UnloggedBatch, 100, statements); err != nil {
+		return report, err
+	}
+	report.Clients = len(statements)
+
+	// Produtos
+	type product struct {
+		name, category string
+		price          model.Money
+		stock          int
+	}
+	products := make(map[string]product)
+	statements = nil
+	var p product
+	for _, table := range []string{"produtos_por_categoria_v2", "produtos_por_categoria"} {
+		iter = c.db.Query(`SELECT id_produto, nome, categoria, preco, estoque FROM ` + table).Iter()
+		for iter.Scan(&id, &p.name, &p.category, &p.price, &p.stock) {
+			if _, ok := products[id]; ok {
+				continue
+			}
+			products[id] = p
+			if table == "produtos_por_categoria" {
+				statements = append(statements, cassandraStatement{insertProdutoPorCategoria, []any{p.category, p.price, id, p.name, p.stock}})
+			}
+			statements = append(statements, cassandraStatement{insertProdutoPorID, []any{id, p.name, p.category, p.price, p.stock}})
+		}
+		if err := iter.Close(); err != nil {
+			return report, err
+		}
+	}
+	if err := c.execute(gocql.UnloggedBatch, 100, statements); err != nil {
+		return report, err
+	}
+	report.Products = len(products)
+
+	// Pedidos e vendas por produto
+	orders := make(map[string]cassandraOrder)
+	sold := make(map[string]int64)
+	statements = nil
+	addressed := make(map[string]string)
+	var coupons []cassandraStatement
+	var (
+		order       cassandraOrder
+		itensJSON   string
+		orderStatus string
+		addressID   *string
+		couponCode  *string
+		discount    model.Money
+	)
+	iter = c.db.Query(`SELECT pedido_id, id_cliente, valor_total, itens, status, id_endereco, codigo_cupom, desconto FROM pedidos_por_cliente`).Iter()
+	for iter.Scan(&id, &order.clientID, &order.total, &itensJSON, &orderStatus, &addressID, &couponCode, &discount) {
+		var itens []cassandraItem
+		if err := json.Unmarshal([]byte(itensJSON), &itens); err != nil {
+			iter.Close()
+			return report, fmt.Errorf("erro ao decodificar itens do pedido %s: %w", id, err)
+		}
+		for _, item := range itens {
+			sold[item.ProdutoID] += int64(item.Quantidade)
+		}
+
+		if addressID != nil && orderStatus != model.OrderStatusCancelled {
+			addressed[id] = *addressID
+		}
+		if couponCode != nil && *couponCode != "" {
+			coupons = append(coupons, cassandraStatement{insertPedidoPorCupom, []any{*couponCode, id, discount}})
+		}
+		orders[id] = order
+		statements = append(statements, cassandraStatement{insertPedidoPorID, []any{id, order.clientID, order.total}})
+	}
+	if err := iter.Close(); err != nil {
+		return report, err
+	}
+	if err := c.execute(gocql.UnloggedBatch, 100, statements); err != nil {
+		return report, err
+	}
+	report.Orders = len(orders)
+
+	if err := c.execute(gocql.UnloggedBatch, 100, coupons); err != nil {
+		return report, err
+	}
+	report.CouponOrders = len(coupons)
+
+	// Pedidos por UF, com a UF de enderecos_por_id
+	statements = nil
+	orderIDs := slices.Sorted(maps.Keys(addressed))
+	for chunk := range slices.Chunk(orderIDs, 100) {
+		var addressIDs []string
+		for _, orderID := range chunk {
+			addressIDs = append(addressIDs, addressed[orderID])
+		}
+		states := make(map[string]string)
+		var state string
+		iter = c.db.Query(`SELECT id, uf FROM enderecos_por_id WHERE id IN ?`, addressIDs).Iter()
+		for iter.Scan(&id, &state) {
+			states[id] = state
+		}
+		if err := iter.Close(); err != nil {
+			return report, err
+		}
+		for _, orderID := range chunk {
+			if state, ok := states[addressed[orderID]]; ok {
+				statements = append(statements, cassandraStatement{insertPedidoPorUF, []any{state, orderID, orders[orderID].total}})
+			}
+		}
+	}
+	if err := c.execute(gocql.UnloggedBatch, 100, statements); err != nil {
+		return report, err
+	}
+	report.AddressedOrders = len(statements)
+
+	var counters, ranking []cassandraStatement
+	for _, id := range slices.Sorted(maps.Keys(sold)) {
+		counters = append(counters, cassandraStatement{
+			`UPDATE vendas_por_produto SET total_vendido = total_vendido + ? WHERE id_produto = ?`,
+			[]any{sold[id], id},
+		})
+		if p, ok := products[id]; ok {
+			ranking = append(ranking, cassandraStatement{insertProdutoPorVendas, []any{sold[id], id, p.name, p.category, p.price, p.stock}})
+		}
+	}
+	if err := c.execute(gocql.CounterBatch, 100, counters); err != nil {
+		return report, err
+	}
+	if err := c.execute(gocql.UnloggedBatch, 100, ranking); err != nil {
+		return report, err
+	}
+	report.SoldProducts = len(sold)
+
+	// Pagamentos. Os que já estão em pagamentos_por_tipo_e_mes_v2 não são
+	// regravados lá, para não desfazer estornos aplicados depois da cópia.
+	statements = nil
+	paid := make(map[string]bool)
+	var (
+		paymentType, month, orderID string
+		clientID, status            *string
+		amount, refunded            *model.Money
+		installments                *int
+		paymentDate                 time.Time
+	)
+	for _, table := range []string{"pagamentos_por_tipo_e_mes_v2", "pagamentos_por_tipo_e_mes"} {
+		iter = c.db.Query(`SELECT tipo, mes_ano, data_pagamento, id_pagamento, id_pedido, id_cliente, status, valor, parcelas, valor_estornado FROM ` + table).Iter()
+		for iter.Scan(&paymentType, &month, &paymentDate, &id, &orderID, &clientID, &status, &amount, &installments, &refunded) {
+			if paid[id] {
+				continue
+			}
+			paid[id] = true
+			report.Payments++
+
+			// Pagamentos gravados antes das colunas próprias ficam com o cliente e
+			// o valor total do pedido, em uma parcela.
+			order, ok := orders[orderID]
+			if ok && (clientID == nil || amount == nil) {
+				clientID, amount = &order.clientID, &order.total
+			}
+			if installments == nil {
+				installments = new(int)
+				*installments = 1
+			}
+			// Antes dos estornos parciais, um pagamento Estornado devolvia o valor
+			// inteiro.
+			if refunded == nil {
+				refunded = new(model.Money)
+				if status != nil && *status == model.StatusRefunded && amount != nil {
+					*refunded = *amount
+				}
+			}
+			if table == "pagamentos_por_tipo_e_mes" {
+				statements = append(statements, cassandraStatement{
+					insertPagamentoPorTipoEMes,
+					[]any{paymentType, month, paymentDate, id, orderID, clientID, status, amount, *installments, *refunded},
+				})
+			}
+
+			if !ok {
+				report.OrphanPayments++
+				continue
+			}
+			statements = append(statements,
+				cassandraStatement{
+					insertPagamentoPorCliente,
+					[]any{*clientID, paymentDate, id, orderID, paymentType, status, *amount, *installments, *refunded},
+				},
+				cassandraStatement{
+					insertPagamentoPorID,
+					[]any{id, paymentType, month, paymentDate, orderID, *clientID, status, *amount, *installments, *refunded},
+				},
+			)
+		}
+		if err := iter.Close(); err != nil {
+			return report, err
+		}
+	}
+	if err := c.execute(gocql.UnloggedBatch, 100, statements); err != nil {
+		return report, err
+	}
+
+	// Notas por categoria
+	counts := make(map[string]int64)
+	sums := make(map[string]int64)
+	var rating int64
+	iter = c.db.Query(`SELECT id_produto, nota FROM avaliacoes_por_produto`).Iter()
+	for iter.Scan(&id, &rating) {
+		report.Reviews++
+		category := products[id].category
+		counts[category]++
+		sums[category] += rating
+	}
+	if err := iter.Close(); err != nil {
+		return report, err
+	}
+	counters = nil
+	for _, category := range slices.Sorted(maps.Keys(counts)) {
+		counters = append(counters, cassandraStatement{updateNotasPorCategoria, []any{counts[category], sums[category], category}})
+	}
+	if err := c.execute(gocql.CounterBatch, 100, counters); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
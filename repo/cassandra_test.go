@@ -0,0 +1,54 @@
+package repo_test
+
+import (
+	"os"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/conformance"
+	"testing"
+)
+
+// Exige um banco recém-criado pelo docker-compose, já que a suíte grava IDs fixos.
+func TestCassandraRepositoryConformance(t *testing.T) {
+	if os.Getenv("CASSANDRA_HOST") == "" {
+		t.Skip("CASSANDRA_HOST não definido")
+	}
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewCassandraRepository()
+	})
+}
+
+// Após reconstruir as tabelas de consulta a partir das tabelas de origem, as
+// consultas devem retornar os mesmos resultados mantidos pelas gravações.
+func TestCassandraRebuildQueryTables(t *testing.T) {
+	if os.Getenv("CASSANDRA_HOST") == "" {
+		t.Skip("CASSANDRA_HOST não definido")
+	}
+	conformance.RunAfter(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewCassandraRepository()
+	}, func(t *testing.T, loaded repo.TechMarketRepository) repo.TechMarketRepository {
+		report, err := loaded.(*repo.CassandraRepository).RebuildQueryTables()
+		if err != nil {
+			t.Fatalf("RebuildQueryTables: %v", err)
+		}
+		if report.OrphanPayments != 0 {
+			t.Errorf("%d pagamentos sem pedido", report.OrphanPayments)
+		}
+		return loaded
+	})
+}
+
+// Exige outro keyspace recém-criado em CASSANDRA_CONCURRENT_KEYSPACE, já que a
+// suíte grava IDs fixos e os contadores de vendas não são idempotentes.
+func TestCassandraRepositoryConcurrentWrites(t *testing.T) {
+	keyspace := os.Getenv("CASSANDRA_CONCURRENT_KEYSPACE")
+	if os.Getenv("CASSANDRA_HOST") == "" || keyspace == "" {
+		t.Skip("CASSANDRA_HOST ou CASSANDRA_CONCURRENT_KEYSPACE não definido")
+	}
+	t.Setenv("CASSANDRA_KEYSPACE", keyspace)
+	t.Setenv("CASSANDRA_WRITE_STRATEGY", "concorrente")
+	t.Setenv("CASSANDRA_MAX_IN_FLIGHT", "8")
+
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewCassandraRepository()
+	})
+}
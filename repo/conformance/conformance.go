@@ -0,0 +1,998 @@
+// Package conformance contém uma suíte de testes que qualquer implementação de
+// repo.TechMarketRepository deve passar. Cada backend chama Run a partir do seu
+// próprio _test.go, garantindo que todos respondem às mesmas consultas da mesma
+// forma.
+package conformance
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"testing"
+	"time"
+)
+
+// Factory cria um repositório vazio. A suíte assume que os IDs informados no
+// conjunto de dados podem ser gravados sem conflito.
+type Factory func(t *testing.T) repo.TechMarketRepository
+
+type dataset struct {
+	now      time.Time
+	clients  []model.Client
+	products []model.Product
+	orders   []model.Order
+	items    []model.OrderItem
+	payments []model.Payment
+}
+
+// newDataset monta um conjunto pequeno e determinístico. As datas são relativas
+// a now e truncadas em segundos para que todos os bancos consigam compará-las
+// exatamente.
+func newDataset(now time.Time) dataset {
+	now = now.Truncate(time.Second)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+
+	return dataset{
+		now: now,
+		clients: []model.Client{
+			{ID: 1, Nome: "Ana Souza", Email: "ana.souza@techmarket.com", Phone: "11987654321", CreatedAt: daysAgo(100), CPF: "529.982.247-25"},
+			{ID: 2, Nome: "Bruno Lima", Email: "bruno.lima@techmarket.com", Phone: "21987654321", CreatedAt: daysAgo(50), CPF: "111.444.777-35"},
+			{ID: 3, Nome: "Carla Dias", Email: "carla.dias@techmarket.com", Phone: "31987654321", CreatedAt: daysAgo(10), CPF: "390.533.447-05"},
+		},
+		// Os valores em centavos verificam que os totais são exatos.
+		products: []model.Product{
+			{ID: 1, Name: "TechPro Notebooks Pro 1000", Category: "Notebooks", Price: model.Reais(5000), Stock: 10},
+			{ID: 2, Name: "NextGen Notebooks Lite 2000", Category: "Notebooks", Price: model.Reais(4000), Stock: 20},
+			{ID: 3, Name: "SmartTech Smartphones Max 3000", Category: "Smartphones", Price: model.Reais(3000), Stock: 30},
+			{ID: 4, Name: "MaxTech Fones de Ouvido Plus 4000", Category: "Fones de Ouvido", Price: 19999, Stock: 40},
+			{ID: 5, Name: "ProTech Periféricos Smart 5000", Category: "Periféricos", Price: model.Reais(100), Stock: 50},
+			{ID: 6, Name: "EliteTech Periféricos Elite 6000", Category: "Periféricos", Price: model.Reais(300), Stock: 60},
+			// Mesmo preço do produto 5 na mesma categoria: um não pode sobrescrever
+			// o outro.
+			{ID: 7, Name: "ProTech Periféricos Ultra 7000", Category: "Periféricos", Price: model.Reais(100), Stock: 70},
+		},
+		orders: []model.Order{
+			{ID: 1, ClientID: 1, OrderDate: daysAgo(6), Status: model.OrderStatusDelivered, TotalValue: 2059997},
+			{ID: 2, ClientID: 1, OrderDate: daysAgo(41), Status: model.OrderStatusDelivered, TotalValue: model.Reais(26000)},
+			{ID: 3, ClientID: 1, OrderDate: daysAgo(46), Status: "Em Transporte", TotalValue: 99995},
+			{ID: 4, ClientID: 2, OrderDate: daysAgo(3), Status: model.OrderStatusDelivered, TotalValue: model.Reais(1000)},
+			{ID: 5, ClientID: 2, OrderDate: daysAgo(2), Status: "Cancelado", TotalValue: model.Reais(300)},
+		},
+		// Totais vendidos: produto 5 = 10, 4 = 8, 3 = 6, 1 = 4, 2 = 2, 6 = 1.
+		items: []model.OrderItem{
+			{OrderID: 1, ProductID: 1, Quantity: 4},
+			{OrderID: 1, ProductID: 4, Quantity: 3},
+			{OrderID: 2, ProductID: 3, Quantity: 6},
+			{OrderID: 2, ProductID: 2, Quantity: 2},
+			{OrderID: 3, ProductID: 4, Quantity: 5},
+			{OrderID: 4, ProductID: 5, Quantity: 10},
+			{OrderID: 5, ProductID: 6, Quantity: 1},
+		},
+		payments: []model.Payment{
+			{ID: 1, OrderID: 1, ClientID: 1, Type: model.PaymentTypePix, Status: "Aprovado", Amount: 2059997, Installments: 1, PaymentDate: daysAgo(5)},
+			{ID: 2, OrderID: 2, ClientID: 1, Type: model.PaymentTypeCreditCard, Status: "Aprovado", Amount: model.Reais(25000), Installments: 10, PaymentDate: daysAgo(40)},
+			{ID: 3, OrderID: 3, ClientID: 1, Type: model.PaymentTypePix, Status: "Pendente", Amount: 99995, Installments: 1, PaymentDate: daysAgo(45)},
+			{ID: 4, OrderID: 4, ClientID: 2, Type: model.PaymentTypePix, Status: "Aprovado", Amount: model.Reais(1000), Installments: 1, PaymentDate: daysAgo(2)},
+			{ID: 5, OrderID: 5, ClientID: 2, Type: "Boleto", Status: "Recusado", Amount: model.Reais(300), Installments: 1, PaymentDate: daysAgo(1)},
+			// Restante do pedido 2, pago bem depois do cartão.
+			{ID: 6, OrderID: 2, ClientID: 1, Type: "Boleto", Status: "Aprovado", Amount: model.Reais(1000), Installments: 1, PaymentDate: daysAgo(20)},
+			// Pix recusado no mesmo instante do pagamento 4: um não pode sobrescrever
+			// o outro.
+			{ID: 7, OrderID: 5, ClientID: 2, Type: model.PaymentTypePix, Status: "Recusado", Amount: model.Reais(300), Installments: 1, PaymentDate: daysAgo(2)},
+		},
+	}
+}
+
+func (d dataset) load(t *testing.T, r repo.TechMarketRepository) {
+	t.Helper()
+
+	if err := r.BatchCreateClient(slices.Clone(d.clients)); err != nil {
+		t.Fatalf("BatchCreateClient: %v", err)
+	}
+	if err := r.BatchCreateProduct(slices.Clone(d.products)); err != nil {
+		t.Fatalf("BatchCreateProduct: %v", err)
+	}
+
+	orders := slices.Clone(d.orders)
+	for i := range orders {
+		for _, item := range d.items {
+			if item.OrderID == orders[i].ID {
+				orders[i].Itens = append(orders[i].Itens, item)
+			}
+		}
+	}
+	if err := r.BatchCreateOrder(orders); err != nil {
+		t.Fatalf("BatchCreateOrder: %v", err)
+	}
+	if err := r.BatchCreateOrderItem(slices.Clone(d.items)); err != nil {
+		t.Fatalf("BatchCreateOrderItem: %v", err)
+	}
+	if err := r.BatchCreatePayment(slices.Clone(d.payments)); err != nil {
+		t.Fatalf("BatchCreatePayment: %v", err)
+	}
+}
+
+// Run popula o repositório criado por newRepo e verifica o resultado de cada
+// método de consulta, incluindo casos de borda.
+func Run(t *testing.T, newRepo Factory) {
+	RunAfter(t, newRepo, func(t *testing.T, r repo.TechMarketRepository) repo.TechMarketRepository {
+		return r
+	})
+}
+
+// RunAfter popula o repositório criado por newRepo e verifica as consultas no
+// repositório retornado por then, que recebe o repositório já populado. Serve
+// para validar processos que copiam dados entre backends.
+func RunAfter(t *testing.T, newRepo Factory, then func(t *testing.T, loaded repo.TechMarketRepository) repo.TechMarketRepository) {
+	d := newDataset(time.Now())
+	loaded := newRepo(t)
+	d.load(t, loaded)
+	r := then(t, loaded)
+
+	t.Run("GetClientByEmail", func(t *testing.T) {
+		want := d.clients[1]
+		got, err := r.GetClientByEmail(want.Email)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		if got.ID != want.ID || got.Nome != want.Nome || got.Email != want.Email || got.Phone != want.Phone || got.CPF != want.CPF {
+			t.Errorf("cliente = %+v, esperado %+v", got, want)
+		}
+		if !got.CreatedAt.Equal(want.CreatedAt) {
+			t.Errorf("data_cadastro = %v, esperado %v", got.CreatedAt, want.CreatedAt)
+		}
+	})
+
+	t.Run("GetClientByEmail/unknown", func(t *testing.T) {
+		got, err := r.GetClientByEmail("ninguem@techmarket.com")
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		if got != (model.Client{}) {
+			t.Errorf("cliente = %+v, esperado valor zero", got)
+		}
+	})
+
+	t.Run("GetProductByCategory", func(t *testing.T) {
+		got, err := r.GetProductByCategory("Periféricos")
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		assertIDs(t, productIDs(got), []uint{5, 6, 7})
+
+		for _, p := range got {
+			want := d.products[p.ID-1]
+			if p.Name != want.Name || p.Category != want.Category || p.Price != want.Price || p.Stock != want.Stock {
+				t.Errorf("produto = %+v, esperado %+v", p, want)
+			}
+		}
+	})
+
+	t.Run("GetProductByCategory/empty", func(t *testing.T) {
+		for _, category := range []string{"Tablets", ""} {
+			got, err := r.GetProductByCategory(category)
+			if err != nil {
+				t.Fatalf("categoria %q: erro inesperado: %v", category, err)
+			}
+			if len(got) != 0 {
+				t.Errorf("categoria %q: %d produtos, esperado nenhum", category, len(got))
+			}
+		}
+	})
+
+	t.Run("GetDeliveredOrdersByClient", func(t *testing.T) {
+		got, err := r.GetDeliveredOrdersByClient(1)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		assertIDs(t, orderIDs(got), []uint{1, 2})
+
+		for _, o := range got {
+			want := d.orders[o.ID-1]
+			if o.ClientID != want.ClientID || o.Status != want.Status || o.TotalValue != want.TotalValue {
+				t.Errorf("pedido = %+v, esperado %+v", o, want)
+			}
+		}
+	})
+
+	t.Run("GetDeliveredOrdersByClient/none", func(t *testing.T) {
+		for _, clientID := range []uint{3, 999} {
+			got, err := r.GetDeliveredOrdersByClient(clientID)
+			if err != nil {
+				t.Fatalf("cliente %d: erro inesperado: %v", clientID, err)
+			}
+			if len(got) != 0 {
+				t.Errorf("cliente %d: %d pedidos, esperado nenhum", clientID, len(got))
+			}
+		}
+	})
+
+	t.Run("Get5MostSoldProducts", func(t *testing.T) {
+		got, err := r.Get5MostSoldProducts()
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		if ids, want := productIDs(got), []uint{5, 4, 3, 1, 2}; !slices.Equal(ids, want) {
+			t.Errorf("produtos = %v, esperado %v nessa ordem", ids, want)
+		}
+	})
+
+	t.Run("GetLastMonthPixPayments", func(t *testing.T) {
+		got, err := r.GetLastMonthPixPayments()
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		assertIDs(t, paymentIDs(got), []uint{1, 4, 7})
+
+		for _, p := range got {
+			want := d.payments[p.ID-1]
+			if p.OrderID != want.OrderID || p.ClientID != want.ClientID || p.Type != want.Type || p.Status != want.Status ||
+				p.Amount != want.Amount || p.Installments != want.Installments || !p.PaymentDate.Equal(want.PaymentDate) {
+				t.Errorf("pagamento = %+v, esperado %+v", p, want)
+			}
+		}
+	})
+
+	// O total soma o valor dos pagamentos do cliente no período, não o total dos
+	// pedidos: o pedido 2 conta 25000 ou 1000 conforme o pagamento que cai no
+	// período.
+	t.Run("GetClientTotalSpentByPeriod", func(t *testing.T) {
+		cases := []struct {
+			name     string
+			clientID uint
+			start    time.Time
+			end      time.Time
+			want     model.Money
+		}{
+			{"último mês", 1, d.now.AddDate(0, -1, 0), d.now, 2159997},
+			{"início inclusivo", 1, d.payments[1].PaymentDate, d.now, 4659997},
+			{"fim inclusivo", 1, d.now.AddDate(0, 0, -60), d.payments[1].PaymentDate, 2599995},
+			{"logo após o início", 1, d.payments[1].PaymentDate.Add(time.Second), d.now, 2159997},
+			{"período vazio", 1, d.now.AddDate(-1, 0, 0), d.now.AddDate(0, -11, 0), 0},
+			{"cliente sem pedidos", 3, d.now.AddDate(-1, 0, 0), d.now, 0},
+			{"cliente inexistente", 999, d.now.AddDate(-1, 0, 0), d.now, 0},
+		}
+
+		for _, c := range cases {
+			got, err := r.GetClientTotalSpentByPeriod(c.clientID, c.start, c.end)
+			if err != nil {
+				t.Fatalf("%s: erro inesperado: %v", c.name, err)
+			}
+			if got != c.want {
+				t.Errorf("%s: total = %s, esperado %s", c.name, got, c.want)
+			}
+		}
+	})
+
+	// Registros inválidos são recusados antes de qualquer gravação, com o mesmo
+	// *model.ValidationError em todos os bancos.
+	t.Run("Validation", func(t *testing.T) {
+		client := d.clients[0]
+		client.ID, client.Email, client.CPF = 10, "novo@techmarket.com", "529.982.247-24"
+		product := d.products[0]
+		product.ID, product.Stock = 10, -1
+		order := d.orders[0]
+		order.ID, order.ClientID = 10, 3
+		orphan := d.orders[0]
+		orphan.ID, orphan.ClientID = 11, 999
+		orphan.Itens = []model.OrderItem{{OrderID: 11, ProductID: 1, Quantity: 1, Product: d.products[0]}}
+		payment := d.payments[0]
+		payment.ID, payment.OrderID, payment.ClientID = 10, 999, 3
+		wrongClient := d.payments[3]
+		wrongClient.ID, wrongClient.ClientID = 11, 1
+
+		cases := []struct {
+			name  string
+			write func() error
+			field string
+		}{
+			{"cpf", func() error { return r.BatchCreateClient([]model.Client{client}) }, "cpf"},
+			{"estoque", func() error { return r.BatchCreateProduct([]model.Product{product}) }, "estoque"},
+			{"pedido sem itens", func() error { return r.BatchCreateOrder([]model.Order{order}) }, "itens"},
+			{"pedido de cliente inexistente", func() error { return r.BatchCreateOrder([]model.Order{orphan}) }, "id_cliente"},
+			{"pedido inexistente", func() error { return r.BatchCreatePayment([]model.Payment{payment}) }, "id_pedido"},
+			{"pedido de outro cliente", func() error { return r.BatchCreatePayment([]model.Payment{wrongClient}) }, "id_cliente"},
+		}
+		for _, c := range cases {
+			err := c.write()
+			var v *model.ValidationError
+			if !errors.As(err, &v) {
+				t.Errorf("%s: erro = %v, esperado *model.ValidationError", c.name, err)
+				continue
+			}
+			if _, ok := v.Field(c.field); !ok {
+				t.Errorf("%s: campo %s não reportado em %v", c.name, c.field, v)
+			}
+		}
+
+		got, err := r.GetClientByEmail(client.Email)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		if got != (model.Client{}) {
+			t.Errorf("cliente inválido gravado: %+v", got)
+		}
+		orders, err := r.GetDeliveredOrdersByClient(orphan.ClientID)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		if len(orders) != 0 {
+			t.Errorf("pedido de cliente inexistente gravado: %+v", orders)
+		}
+		total, err := r.GetClientTotalSpentByPeriod(1, d.now.AddDate(-1, 0, 0), d.now)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		if total != 4759992 {
+			t.Errorf("total = %s, esperado R$ 47.599,92: pagamento inválido gravado", total)
+		}
+	})
+
+	// As avaliações não alteram os demais dados e rodam apenas nos bancos que
+	// implementam repo.Reviewer.
+	t.Run("Reviews", func(t *testing.T) {
+		reviewer, ok := r.(repo.Reviewer)
+		if !ok {
+			t.Skip("repositório não guarda avaliações")
+		}
+
+		review := func(id, productID, clientID uint, rating int) model.Review {
+			return model.Review{ID: id, ProductID: productID, ClientID: clientID, Rating: rating, Comment: "comentário", CreatedAt: d.now.Add(-time.Duration(id) * time.Hour)}
+		}
+		// Notebooks: 5 + 4 + 3 (produto 1) + 2 (produto 2). Smartphones: 5.
+		reviews := []model.Review{
+			review(1, 1, 1, 5),
+			review(2, 1, 2, 4),
+			review(3, 1, 3, 3),
+			review(4, 2, 1, 2),
+			review(5, 3, 2, 5),
+		}
+		if err := reviewer.BatchCreateReview(slices.Clone(reviews)); err != nil {
+			t.Fatalf("BatchCreateReview: %v", err)
+		}
+
+		invalid := []struct {
+			name   string
+			review model.Review
+			field  string
+		}{
+			{"nota", review(10, 1, 1, 6), "nota"},
+			{"produto inexistente", review(11, 999, 1, 5), "id_produto"},
+		}
+		for _, c := range invalid {
+			err := reviewer.BatchCreateReview([]model.Review{c.review})
+			var v *model.ValidationError
+			if !errors.As(err, &v) {
+				t.Errorf("%s: erro = %v, esperado *model.ValidationError", c.name, err)
+				continue
+			}
+			if _, ok := v.Field(c.field); !ok {
+				t.Errorf("%s: campo %s não reportado em %v", c.name, c.field, v)
+			}
+		}
+
+		pages := []struct {
+			beforeID uint
+			want     []uint
+		}{
+			{0, []uint{3, 2}},
+			{2, []uint{1}},
+			{1, nil},
+		}
+		for _, page := range pages {
+			got, err := reviewer.GetProductReviews(1, page.beforeID, 2)
+			if err != nil {
+				t.Fatalf("página antes de %d: erro inesperado: %v", page.beforeID, err)
+			}
+			ids := make([]uint, len(got))
+			for i, review := range got {
+				ids[i] = review.ID
+			}
+			if !slices.Equal(ids, page.want) {
+				t.Errorf("página antes de %d = %v, esperado %v nessa ordem", page.beforeID, ids, page.want)
+			}
+			for _, review := range got {
+				want := reviews[review.ID-1]
+				if review.ProductID != want.ProductID || review.ClientID != want.ClientID || review.Rating != want.Rating ||
+					review.Comment != want.Comment || !review.CreatedAt.Equal(want.CreatedAt) {
+					t.Errorf("avaliação = %+v, esperado %+v", review, want)
+				}
+			}
+		}
+		if got, err := reviewer.GetProductReviews(999, 0, 10); err != nil || len(got) != 0 {
+			t.Errorf("produto sem avaliações = %+v, %v, esperado nenhuma", got, err)
+		}
+
+		ratings, err := reviewer.GetAverageRatingByCategory()
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		want := []model.CategoryRating{
+			{Category: "Notebooks", Reviews: 4, RatingSum: 14},
+			{Category: "Smartphones", Reviews: 1, RatingSum: 5},
+		}
+		if !slices.Equal(ratings, want) {
+			t.Errorf("notas = %+v, esperado %+v", ratings, want)
+		}
+	})
+
+	// Os envios não alteram os demais dados e rodam apenas nos bancos que
+	// implementam repo.ShipmentTracker.
+	t.Run("Shipments", func(t *testing.T) {
+		tracker, ok := r.(repo.ShipmentTracker)
+		if !ok {
+			t.Skip("repositório não rastreia envios")
+		}
+
+		address := model.PostalAddress{CEP: "01310-100", Street: "Avenida Paulista", Number: "1000", Complement: "Apto 12", City: "São Paulo", State: "SP"}
+		shipments := []model.Shipment{
+			{ID: 1, OrderID: 3, Carrier: "Correios", TrackingCode: "AA000000001BR", Address: address, ShippedAt: d.now.AddDate(0, 0, -45)},
+			{ID: 2, OrderID: 1, Carrier: "Jadlog", TrackingCode: "AA000000002BR", Address: address, ShippedAt: d.now.AddDate(0, 0, -5)},
+			{ID: 3, OrderID: 1, Carrier: "Loggi", TrackingCode: "AA000000003BR", Address: address, ShippedAt: d.now.AddDate(0, 0, -4)},
+		}
+		if err := tracker.BatchCreateShipment(slices.Clone(shipments)); err != nil {
+			t.Fatalf("BatchCreateShipment: %v", err)
+		}
+
+		event := func(shipmentID uint, status string, at time.Time) model.TrackingEvent {
+			return model.TrackingEvent{ShipmentID: shipmentID, Status: status, Location: "São Paulo/SP", Description: status, OccurredAt: at}
+		}
+		shipped := shipments[1].ShippedAt
+		// O segundo lote chega fora de ordem e intercalado com o primeiro.
+		batches := [][]model.TrackingEvent{
+			{
+				event(2, model.TrackingPosted, shipped),
+				event(1, model.TrackingPosted, shipments[0].ShippedAt),
+				event(2, model.TrackingInTransit, shipped.Add(10*time.Hour)),
+				event(3, model.TrackingPosted, shipments[2].ShippedAt),
+			},
+			{
+				event(2, model.OrderStatusDelivered, shipped.Add(50*time.Hour)),
+				event(2, model.TrackingOutForDelivery, shipped.Add(48*time.Hour)),
+			},
+		}
+		for _, batch := range batches {
+			if err := tracker.AppendTrackingEvents(batch); err != nil {
+				t.Fatalf("AppendTrackingEvents: %v", err)
+			}
+		}
+
+		invalidShipment := shipments[0]
+		invalidShipment.ID, invalidShipment.OrderID = 10, 999
+		invalidAddress := shipments[0]
+		invalidAddress.ID, invalidAddress.Address.State = 11, "XX"
+		cases := []struct {
+			name  string
+			write func() error
+			field string
+		}{
+			{"pedido inexistente", func() error { return tracker.BatchCreateShipment([]model.Shipment{invalidShipment}) }, "id_pedido"},
+			{"uf", func() error { return tracker.BatchCreateShipment([]model.Shipment{invalidAddress}) }, "endereco.uf"},
+			{"envio inexistente", func() error {
+				return tracker.AppendTrackingEvents([]model.TrackingEvent{event(2, model.TrackingInTransit, d.now), event(999, model.TrackingPosted, d.now)})
+			}, "id_envio"},
+		}
+		for _, c := range cases {
+			err := c.write()
+			var v *model.ValidationError
+			if !errors.As(err, &v) {
+				t.Errorf("%s: erro = %v, esperado *model.ValidationError", c.name, err)
+				continue
+			}
+			if _, ok := v.Field(c.field); !ok {
+				t.Errorf("%s: campo %s não reportado em %v", c.name, c.field, v)
+			}
+		}
+
+		got, err := tracker.GetTrackingHistory(1)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("%d envios do pedido 1, esperado 2: %+v", len(got), got)
+		}
+		for i, want := range shipments[1:] {
+			shipment := got[i]
+			if shipment.ID != want.ID || shipment.OrderID != want.OrderID || shipment.Carrier != want.Carrier ||
+				shipment.TrackingCode != want.TrackingCode || shipment.Address != want.Address || !shipment.ShippedAt.Equal(want.ShippedAt) {
+				t.Errorf("envio = %+v, esperado %+v", shipment, want)
+			}
+		}
+
+		wantStatus := []string{model.TrackingPosted, model.TrackingInTransit, model.TrackingOutForDelivery, model.OrderStatusDelivered}
+		var status []string
+		for _, e := range got[0].Events {
+			status = append(status, e.Status)
+			if e.ShipmentID != 2 || e.Location != "São Paulo/SP" || e.Description != e.Status {
+				t.Errorf("evento = %+v do envio 2", e)
+			}
+		}
+		if !slices.Equal(status, wantStatus) {
+			t.Errorf("eventos do envio 2 = %v, esperado %v nessa ordem", status, wantStatus)
+		}
+		if len(got[1].Events) != 1 || !got[1].Events[0].OccurredAt.Equal(shipments[2].ShippedAt) {
+			t.Errorf("eventos do envio 3 = %+v, esperado apenas a postagem", got[1].Events)
+		}
+
+		if got, err := tracker.GetTrackingHistory(4); err != nil || len(got) != 0 {
+			t.Errorf("pedido sem envios = %+v, %v, esperado nenhum", got, err)
+		}
+	})
+
+	// Os endereços e os pedidos com endereço são dos clientes 2 e 3, que os
+	// estornos não consultam. Rodam apenas nos bancos que implementam
+	// repo.AddressBook.
+	t.Run("Addresses", func(t *testing.T) {
+		book, ok := r.(repo.AddressBook)
+		if !ok {
+			t.Skip("repositório não guarda endereços")
+		}
+
+		address := func(id, clientID uint, city, state, cep string, isDefault bool) model.Address {
+			return model.Address{
+				ID:            id,
+				ClientID:      clientID,
+				PostalAddress: model.PostalAddress{CEP: cep, Street: "Rua das Flores", Number: "100", City: city, State: state},
+				Default:       isDefault,
+			}
+		}
+		addresses := []model.Address{
+			address(1, 3, "São Paulo", "SP", "01310-100", true),
+			address(2, 3, "Belo Horizonte", "MG", "30130-000", false),
+			address(3, 2, "Rio de Janeiro", "RJ", "20040-002", true),
+		}
+		if err := book.BatchCreateAddress(slices.Clone(addresses)); err != nil {
+			t.Fatalf("BatchCreateAddress: %v", err)
+		}
+		// Um novo endereço padrão substitui o anterior.
+		if err := book.BatchCreateAddress([]model.Address{address(4, 3, "Niterói", "RJ", "24020-000", true)}); err != nil {
+			t.Fatalf("BatchCreateAddress: %v", err)
+		}
+
+		listed := func(clientID uint) []model.Address {
+			t.Helper()
+			got, err := book.GetClientAddresses(clientID)
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			return got
+		}
+		got := listed(3)
+		var ids []uint
+		for _, a := range got {
+			ids = append(ids, a.ID)
+		}
+		if !slices.Equal(ids, []uint{4, 1, 2}) {
+			t.Fatalf("endereços do cliente 3 = %v, esperado [4 1 2]: o padrão primeiro", ids)
+		}
+		for i, want := range []model.Address{address(4, 3, "Niterói", "RJ", "24020-000", true), addresses[0], addresses[1]} {
+			if i == 1 {
+				want.Default = false
+			}
+			if got[i] != want {
+				t.Errorf("endereço = %+v, esperado %+v", got[i], want)
+			}
+		}
+
+		invalidState := address(5, 3, "São Paulo", "XX", "01310-100", false)
+		addressCases := []struct {
+			name  string
+			write func() error
+			field string
+		}{
+			{"cliente inexistente", func() error {
+				return book.BatchCreateAddress([]model.Address{address(5, 999, "Recife", "PE", "50030-000", false)})
+			}, "id_cliente"},
+			{"uf", func() error { return book.BatchCreateAddress([]model.Address{invalidState}) }, "uf"},
+			{"dois padrões", func() error {
+				return book.BatchCreateAddress([]model.Address{
+					address(5, 2, "Recife", "PE", "50030-000", true),
+					address(6, 2, "Natal", "RN", "59010-000", true),
+				})
+			}, "padrao"},
+			{"endereço de outro cliente", func() error { return book.SetDefaultAddress(3, 3) }, "id"},
+			{"endereço inexistente", func() error { return book.SetDefaultAddress(3, 999) }, "id"},
+		}
+		for _, c := range addressCases {
+			err := c.write()
+			var v *model.ValidationError
+			if !errors.As(err, &v) {
+				t.Errorf("%s: erro = %v, esperado *model.ValidationError", c.name, err)
+				continue
+			}
+			if _, ok := v.Field(c.field); !ok {
+				t.Errorf("%s: campo %s não reportado em %v", c.name, c.field, v)
+			}
+		}
+		if got := listed(2); len(got) != 1 || got[0].ID != 3 || !got[0].Default {
+			t.Errorf("endereços do cliente 2 = %+v, esperado apenas o 3, padrão", got)
+		}
+
+		if err := book.SetDefaultAddress(3, 2); err != nil {
+			t.Fatalf("SetDefaultAddress: %v", err)
+		}
+		got = listed(3)
+		ids = nil
+		for _, a := range got {
+			ids = append(ids, a.ID)
+			if a.Default != (a.ID == 2) {
+				t.Errorf("endereço %d com padrao = %t", a.ID, a.Default)
+			}
+		}
+		if !slices.Equal(ids, []uint{2, 1, 4}) {
+			t.Errorf("endereços do cliente 3 = %v, esperado [2 1 4]", ids)
+		}
+		if got := listed(999); len(got) != 0 {
+			t.Errorf("endereços de cliente inexistente = %+v, esperado nenhum", got)
+		}
+
+		addressID := func(id uint) *uint { return &id }
+		order := func(id, clientID, address uint, status string, total model.Money, productID uint) model.Order {
+			return model.Order{
+				ID: id, ClientID: clientID, AddressID: addressID(address), OrderDate: d.now.AddDate(0, 0, -1), Status: status, TotalValue: total,
+				Itens: []model.OrderItem{{OrderID: id, ProductID: productID, Quantity: 1, Product: d.products[productID-1]}},
+			}
+		}
+		orders := []model.Order{
+			order(6, 3, 1, "Pago", model.Reais(5000), 1),
+			order(7, 3, 4, model.OrderStatusDelivered, 19999, 4),
+			order(8, 3, 1, model.OrderStatusCancelled, model.Reais(300), 6),
+			order(9, 2, 3, "Em Transporte", model.Reais(3000), 3),
+		}
+		if err := r.BatchCreateOrder(slices.Clone(orders)); err != nil {
+			t.Fatalf("BatchCreateOrder: %v", err)
+		}
+
+		orderCases := []struct {
+			name  string
+			order model.Order
+		}{
+			{"endereço de outro cliente", order(10, 2, 1, "Pago", model.Reais(5000), 1)},
+			{"endereço inexistente", order(11, 3, 999, "Pago", model.Reais(5000), 1)},
+		}
+		for _, c := range orderCases {
+			err := r.BatchCreateOrder([]model.Order{c.order})
+			var v *model.ValidationError
+			if !errors.As(err, &v) {
+				t.Errorf("%s: erro = %v, esperado *model.ValidationError", c.name, err)
+				continue
+			}
+			if _, ok := v.Field("id_endereco"); !ok {
+				t.Errorf("%s: campo id_endereco não reportado em %v", c.name, v)
+			}
+		}
+
+		delivered, err := r.GetDeliveredOrdersByClient(3)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		if len(delivered) != 1 || delivered[0].AddressID == nil || *delivered[0].AddressID != 4 {
+			t.Errorf("pedidos entregues do cliente 3 = %+v, esperado o pedido 7 com o endereço 4", delivered)
+		}
+
+		// O pedido cancelado fica de fora; o endereço 4 é do RJ como o 3.
+		revenue, err := book.GetRevenueByState()
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		want := []model.StateRevenue{
+			{State: "RJ", Orders: 2, Revenue: 319999},
+			{State: "SP", Orders: 1, Revenue: model.Reais(5000)},
+		}
+		if !slices.Equal(revenue, want) {
+			t.Errorf("receita por UF = %+v, esperado %+v", revenue, want)
+		}
+	})
+
+	// Os pedidos com cupom são dos clientes 2 e 3, com IDs a partir de 20, e
+	// não têm endereço. Rodam apenas nos bancos que implementam
+	// repo.CouponBook.
+	t.Run("Coupons", func(t *testing.T) {
+		book, ok := r.(repo.CouponBook)
+		if !ok {
+			t.Skip("repositório não guarda cupons")
+		}
+
+		validFrom, validUntil := d.now.AddDate(0, 0, -10), d.now.AddDate(0, 0, 10)
+		coupons := []model.Coupon{
+			{Code: "DEZ10", Type: model.CouponPercentage, Percent: 10, MinOrderValue: model.Reais(1000), ValidFrom: validFrom, ValidUntil: validUntil, UsageLimit: 2},
+			{Code: "FONE20", Type: model.CouponFixed, Amount: model.Reais(20), ValidFrom: validFrom, ValidUntil: validUntil, UsageLimit: 1, Categories: []string{"Fones de Ouvido"}},
+			{Code: "EXPIRADO", Type: model.CouponPercentage, Percent: 5, ValidFrom: d.now.AddDate(0, 0, -30), ValidUntil: d.now.AddDate(0, 0, -20), UsageLimit: 1},
+			{Code: "UNICO", Type: model.CouponFixed, Amount: model.Reais(1), ValidFrom: validFrom, ValidUntil: validUntil, UsageLimit: 1},
+		}
+		if err := book.BatchCreateCoupon(slices.Clone(coupons)); err != nil {
+			t.Fatalf("BatchCreateCoupon: %v", err)
+		}
+		var v *model.ValidationError
+		if err := book.BatchCreateCoupon([]model.Coupon{{Code: "BRINDE", Type: "Brinde", ValidFrom: validFrom, ValidUntil: validUntil, UsageLimit: 1}}); !errors.As(err, &v) {
+			t.Errorf("cupom inválido: erro = %v, esperado *model.ValidationError", err)
+		} else if _, ok := v.Field("tipo"); !ok || v.Key != "BRINDE" {
+			t.Errorf("cupom inválido: campo tipo do cupom BRINDE não reportado em %v", v)
+		}
+
+		item := func(productID uint, quantity int) model.OrderItem {
+			return model.OrderItem{ProductID: productID, Quantity: quantity, Product: d.products[productID-1]}
+		}
+		order := func(id, clientID uint, code string, items ...model.OrderItem) model.Order {
+			for i := range items {
+				items[i].OrderID = id
+			}
+			return model.Order{ID: id, ClientID: clientID, OrderDate: d.now.AddDate(0, 0, -1), Status: "Pago", CouponCode: code, Itens: items}
+		}
+		create := func(o model.Order, discount, total model.Money) {
+			t.Helper()
+			if err := book.CreateOrderWithCoupon(&o); err != nil {
+				t.Fatalf("CreateOrderWithCoupon(%d): %v", o.ID, err)
+			}
+			if o.Discount != discount || o.TotalValue != total {
+				t.Errorf("pedido %d com desconto %s e total %s, esperado %s e %s", o.ID, o.Discount, o.TotalValue, discount, total)
+			}
+		}
+
+		// 10% de R$ 5100; o valor fixo vale só para os dois fones.
+		delivered := order(20, 2, "DEZ10", item(1, 1), item(5, 1))
+		delivered.Status = model.OrderStatusDelivered
+		create(delivered, 51000, 459000)
+
+		rejected := []struct {
+			name  string
+			order model.Order
+			field string
+		}{
+			{"abaixo do mínimo", order(21, 2, "DEZ10", item(5, 1)), "valor_total"},
+			{"sem itens da categoria", order(22, 3, "FONE20", item(1, 1)), "itens"},
+			{"fora da validade", order(23, 3, "EXPIRADO", item(1, 1)), "codigo_cupom"},
+			{"cupom inexistente", order(24, 3, "NENHUM", item(1, 1)), "codigo_cupom"},
+			{"cliente inexistente", order(25, 999, "DEZ10", item(1, 1)), "id_cliente"},
+		}
+		for _, c := range rejected {
+			err := book.CreateOrderWithCoupon(&c.order)
+			if !errors.As(err, &v) {
+				t.Errorf("%s: erro = %v, esperado *model.ValidationError", c.name, err)
+				continue
+			}
+			if _, ok := v.Field(c.field); !ok {
+				t.Errorf("%s: campo %s não reportado em %v", c.name, c.field, v)
+			}
+		}
+
+		// O pedido recusado abaixo do mínimo não contou uso.
+		create(order(26, 2, "DEZ10", item(3, 1)), 30000, 270000)
+		create(order(27, 2, "FONE20", item(4, 2), item(5, 1)), 2000, 47998)
+		limit := order(28, 2, "DEZ10", item(3, 1))
+		if err := book.CreateOrderWithCoupon(&limit); !errors.As(err, &v) {
+			t.Errorf("terceiro uso: erro = %v, esperado *model.ValidationError", err)
+		} else if _, ok := v.Field("codigo_cupom"); !ok {
+			t.Errorf("terceiro uso: campo codigo_cupom não reportado em %v", v)
+		}
+
+		// Pedidos concorrentes do mesmo cliente disputam o único uso.
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				o := order(uint(30+i), 3, "UNICO", item(5, 1))
+				errs[i] = book.CreateOrderWithCoupon(&o)
+			}()
+		}
+		wg.Wait()
+		created := 0
+		for _, err := range errs {
+			if err == nil {
+				created++
+			} else if !errors.As(err, &v) {
+				t.Errorf("uso concorrente: erro = %v, esperado *model.ValidationError", err)
+			}
+		}
+		if created != 1 {
+			t.Errorf("%d pedidos concorrentes com o cupom UNICO gravados, esperado 1", created)
+		}
+
+		usages := []struct {
+			code string
+			want model.CouponUsage
+		}{
+			{"DEZ10", model.CouponUsage{Orders: 2, Discount: 81000}},
+			{"FONE20", model.CouponUsage{Orders: 1, Discount: 2000}},
+			{"UNICO", model.CouponUsage{Orders: 1, Discount: model.Reais(1)}},
+			{"EXPIRADO", model.CouponUsage{}},
+		}
+		for _, u := range usages {
+			got, err := book.GetCouponUsage(u.code)
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			if got != u.want {
+				t.Errorf("uso do cupom %s = %+v, esperado %+v", u.code, got, u.want)
+			}
+		}
+
+		orders, err := r.GetDeliveredOrdersByClient(2)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		i := slices.IndexFunc(orders, func(o model.Order) bool { return o.ID == 20 })
+		if i < 0 || orders[i].CouponCode != "DEZ10" || orders[i].Discount != 51000 || orders[i].TotalValue != 459000 {
+			t.Errorf("pedidos entregues do cliente 2 = %+v, esperado o pedido 20 com o cupom DEZ10", orders)
+		}
+	})
+
+	// Os estornos alteram pagamentos e pedidos, então rodam por último e apenas
+	// nos bancos que implementam repo.Refunder.
+	t.Run("Refunds", func(t *testing.T) {
+		refunder, ok := r.(repo.Refunder)
+		if !ok {
+			t.Skip("repositório não registra estornos")
+		}
+
+		refund := func(paymentID uint, amount model.Money, date time.Time) error {
+			return refunder.RefundPayment(&model.Refund{PaymentID: paymentID, Amount: amount, Reason: "teste", RefundDate: date})
+		}
+		total := func() model.Money {
+			t.Helper()
+			total, err := r.GetClientTotalSpentByPeriod(1, d.now.AddDate(-1, 0, 0), d.now)
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			return total
+		}
+
+		// Estorno parcial: o pedido 2 deixa de constar como entregue.
+		if err := refund(2, model.Reais(5000), d.now.Add(-time.Hour)); err != nil {
+			t.Fatalf("estorno parcial: %v", err)
+		}
+		if got := total(); got != 4259992 {
+			t.Errorf("total após estorno parcial = %s, esperado R$ 42.599,92", got)
+		}
+		delivered, err := r.GetDeliveredOrdersByClient(1)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		assertIDs(t, orderIDs(delivered), []uint{1})
+
+		cases := []struct {
+			name      string
+			paymentID uint
+			amount    model.Money
+			field     string
+		}{
+			{"acima do saldo", 2, model.Reais(20000) + 1, "valor"},
+			{"pagamento pendente", 3, 1, "id_pagamento"},
+			{"pagamento inexistente", 999, 1, "id_pagamento"},
+			{"valor zero", 2, 0, "valor"},
+		}
+		for _, c := range cases {
+			err := refund(c.paymentID, c.amount, d.now)
+			var v *model.ValidationError
+			if !errors.As(err, &v) {
+				t.Errorf("%s: erro = %v, esperado *model.ValidationError", c.name, err)
+				continue
+			}
+			if _, ok := v.Field(c.field); !ok {
+				t.Errorf("%s: campo %s não reportado em %v", c.name, c.field, v)
+			}
+		}
+
+		// Estorno do saldo dos dois pagamentos do pedido 2 e do pix do pedido 4.
+		for _, paymentID := range []uint{2, 6} {
+			amount := model.Reais(20000)
+			if paymentID == 6 {
+				amount = model.Reais(1000)
+			}
+			if err := refund(paymentID, amount, d.now); err != nil {
+				t.Fatalf("estorno do pagamento %d: %v", paymentID, err)
+			}
+		}
+		if got := total(); got != 2159992 {
+			t.Errorf("total após estorno integral = %s, esperado R$ 21.599,92", got)
+		}
+		if err := refund(4, model.Reais(1000), d.now); err != nil {
+			t.Fatalf("estorno do pix: %v", err)
+		}
+		pix, err := r.GetLastMonthPixPayments()
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		// O pagamento 7 tem o mesmo tipo e instante do 4 e não é afetado.
+		for _, p := range pix {
+			if p.ID == 4 && (p.Status != model.StatusRefunded || p.Refunded != model.Reais(1000)) {
+				t.Errorf("pix estornado = %+v, esperado status %q e valor estornado R$ 1.000,00", p, model.StatusRefunded)
+			}
+			if p.ID == 7 && (p.Status != "Recusado" || p.Refunded != 0) {
+				t.Errorf("pix não estornado = %+v, esperado status %q sem valor estornado", p, "Recusado")
+			}
+		}
+
+		refunds, err := refunder.Refunds(2)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		if len(refunds) != 2 {
+			t.Fatalf("%d estornos do pagamento 2, esperado 2: %+v", len(refunds), refunds)
+		}
+		for i, want := range []model.Money{model.Reais(5000), model.Reais(20000)} {
+			got := refunds[i]
+			if got.ID == 0 || got.PaymentID != 2 || got.OrderID != 2 || got.ClientID != 1 || got.Amount != want || got.Reason != "teste" {
+				t.Errorf("estorno %d = %+v, esperado %s do pagamento 2", i, got, want)
+			}
+		}
+		if refunds, err := refunder.Refunds(999); err != nil || len(refunds) != 0 {
+			t.Errorf("Refunds(999) = %+v, %v, esperado nenhum", refunds, err)
+		}
+
+		// Estornos concorrentes do pix do pedido 1: só cabem 4 de R$ 5.000,00 no
+		// saldo de R$ 20.599,97, e os demais são recusados pela validação.
+		const workers = 16
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			applied  int
+			rejected int
+		)
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := refund(1, model.Reais(5000), d.now)
+				var v *model.ValidationError
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					applied++
+				case errors.As(err, &v):
+					rejected++
+				default:
+					t.Errorf("estorno concorrente: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if applied != 4 || rejected != workers-4 {
+			t.Errorf("estornos concorrentes: %d aplicados e %d recusados, esperado 4 e %d", applied, rejected, workers-4)
+		}
+		if refunds, err := refunder.Refunds(1); err != nil || len(refunds) != 4 {
+			t.Errorf("Refunds(1) = %d estornos, %v, esperado 4", len(refunds), err)
+		}
+		if got := total(); got != 159992 {
+			t.Errorf("total após estornos concorrentes = %s, esperado R$ 1.599,92", got)
+		}
+	})
+}
+
+func assertIDs(t *testing.T, got []uint, want []uint) {
+	t.Helper()
+	got = slices.Clone(got)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("ids = %v, esperado %v", got, want)
+	}
+}
+
+func productIDs(products []model.Product) []uint {
+	ids := make([]uint, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func orderIDs(orders []model.Order) []uint {
+	ids := make([]uint, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	return ids
+}
+
+func paymentIDs(payments []model.Payment) []uint {
+	ids := make([]uint, len(payments))
+	for i, p := range payments {
+		ids[i] = p.ID
+	}
+	return ids
+}
@@ -0,0 +1,163 @@
+package repo
+
+import (
+	"maps"
+	"slices"
+	"techmarket_showcase/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	_ CouponBook = &PostgresRepository{}
+	_ CouponBook = &SQLiteRepository{}
+)
+
+// couponFunc retorna o cupom com o código informado e se ele existe.
+type couponFunc func(code string) (model.Coupon, bool, error)
+
+// prepareCouponOrder faz as conferências de CreateOrderWithCoupon que não
+// dependem dos usos: valida o pedido com validateOrders, que também confere o
+// cliente, confere que o cupom existe e calcula o desconto, preenchendo Discount e
+// TotalValue em order. Retorna o cupom para que o repositório confira e conte
+// o uso junto com a gravação do pedido.
+func prepareCouponOrder(order *model.Order, addresses addressesFunc, existingClients existingClientsFunc, coupon couponFunc, productCategories productCategoriesFunc) (model.Coupon, error) {
+	if order.CouponCode == "" {
+		v := &model.ValidationError{Entity: "pedido", ID: order.ID}
+		v.Add("codigo_cupom", "obrigatório")
+		return model.Coupon{}, v
+	}
+	if _, err := validateOrders([]model.Order{*order}, existingClients, addresses); err != nil {
+		return model.Coupon{}, err
+	}
+
+	found, ok, err := coupon(order.CouponCode)
+	if err != nil {
+		return model.Coupon{}, err
+	}
+	if !ok {
+		return model.Coupon{}, model.CouponNotFound(*order)
+	}
+
+	ids := make(map[uint]bool)
+	for _, item := range order.Itens {
+		ids[item.ProductID] = true
+	}
+	categories, err := productCategories(slices.Sorted(maps.Keys(ids)))
+	if err != nil {
+		return model.Coupon{}, err
+	}
+	discount, err := found.Discount(*order, categories)
+	if err != nil {
+		return model.Coupon{}, err
+	}
+	order.Discount = discount
+	order.TotalValue = order.Subtotal() - discount
+	return found, nil
+}
+
+// couponCategory é uma linha de cupom_categoria.
+type couponCategory struct {
+	Code     string `gorm:"column:codigo"`
+	Category string `gorm:"column:categoria"`
+}
+
+func gormCreateCoupons(db *gorm.DB, coupons []model.Coupon) error {
+	if err := validateRecords(coupons); err != nil {
+		return err
+	}
+	var categories []couponCategory
+	for _, coupon := range coupons {
+		for _, category := range coupon.Categories {
+			categories = append(categories, couponCategory{coupon.Code, category})
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("cupom").CreateInBatches(coupons, 100).Error; err != nil {
+			return err
+		}
+		if len(categories) == 0 {
+			return nil
+		}
+		return tx.Table("cupom_categoria").CreateInBatches(categories, 100).Error
+	})
+}
+
+func gormCoupon(db *gorm.DB) couponFunc {
+	return func(code string) (model.Coupon, bool, error) {
+		var coupon model.Coupon
+		if err := db.Raw(`SELECT * FROM cupom WHERE codigo = ?`, code).Scan(&coupon).Error; err != nil {
+			return model.Coupon{}, false, err
+		}
+		if coupon.Code == "" {
+			return model.Coupon{}, false, nil
+		}
+		query := `SELECT categoria FROM cupom_categoria WHERE codigo = ? ORDER BY categoria`
+		if err := db.Raw(query, code).Scan(&coupon.Categories).Error; err != nil {
+			return model.Coupon{}, false, err
+		}
+		return coupon, true, nil
+	}
+}
+
+// gormCreateOrderWithCoupon conta o uso e grava o pedido na mesma transação.
+// O uso é contado por um único upsert condicional em uso_cupom, que só soma
+// enquanto o cliente está abaixo do limite: no PostgreSQL o ON CONFLICT trava a
+// linha, então upserts concorrentes do mesmo cliente esperam um pelo outro e
+// reavaliam o limite, e no SQLite, por ser a primeira escrita da transação,
+// ele espera a trava de escrita do banco.
+func gormCreateOrderWithCoupon(db *gorm.DB, order *model.Order) error {
+	coupon, err := prepareCouponOrder(order, gormAddresses(db), gormExistingClients(db, false), gormCoupon(db), gormProductCategories(db))
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		use := tx.Exec(`
+			INSERT INTO uso_cupom (codigo, id_cliente, usos) VALUES (?, ?, 1)
+			ON CONFLICT (codigo, id_cliente) DO UPDATE SET usos = uso_cupom.usos + 1
+			WHERE uso_cupom.usos < ?`,
+			coupon.Code, order.ClientID, coupon.UsageLimit)
+		if use.Error != nil {
+			return use.Error
+		}
+		if use.RowsAffected == 0 {
+			return coupon.CheckUses(*order, coupon.UsageLimit)
+		}
+		return tx.Table("pedido").Create(order).Error
+	})
+}
+
+func gormCouponUsage(db *gorm.DB, code string) (model.CouponUsage, error) {
+	query := `SELECT COUNT(*) AS pedidos, COALESCE(SUM(desconto), 0) AS desconto FROM pedido WHERE codigo_cupom = ?`
+	var usage model.CouponUsage
+	if err := db.Raw(query, code).Scan(&usage).Error; err != nil {
+		return model.CouponUsage{}, err
+	}
+	return usage, nil
+}
+
+func (p *PostgresRepository) BatchCreateCoupon(coupons []model.Coupon) error {
+	return gormCreateCoupons(p.db, coupons)
+}
+
+func (p *PostgresRepository) CreateOrderWithCoupon(order *model.Order) error {
+	return gormCreateOrderWithCoupon(p.db, order)
+}
+
+func (p *PostgresRepository) GetCouponUsage(code string) (model.CouponUsage, error) {
+	return gormCouponUsage(p.db, code)
+}
+
+func (s *SQLiteRepository) BatchCreateCoupon(coupons []model.Coupon) error {
+	return gormCreateCoupons(s.db, coupons)
+}
+
+func (s *SQLiteRepository) CreateOrderWithCoupon(order *model.Order) error {
+	return gormCreateOrderWithCoupon(s.db, order)
+}
+
+func (s *SQLiteRepository) GetCouponUsage(code string) (model.CouponUsage, error) {
+	return gormCouponUsage(s.db, code)
+}
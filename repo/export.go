@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"techmarket_showcase/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	_ Exporter = &PostgresRepository{}
+	_ Exporter = &SQLiteRepository{}
+)
+
+// O PostgreSQL e o SQLite compartilham o mesmo schema, então a leitura paginada
+// usada pelas migrações é a mesma para os dois.
+
+func exportPage[T any](db *gorm.DB, table string, afterID uint, limit int) ([]T, error) {
+	query := `SELECT * FROM ` + table + ` WHERE id > ? ORDER BY id LIMIT ?`
+	var records []T
+	if err := db.Raw(query, afterID, limit).Scan(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func exportOrders(db *gorm.DB, afterID uint, limit int) ([]model.Order, error) {
+	orders, err := exportPage[model.Order](db, "pedido", afterID, limit)
+	if err != nil || len(orders) == 0 {
+		return orders, err
+	}
+
+	ids := make([]uint, len(orders))
+	byID := make(map[uint]*model.Order, len(orders))
+	for i := range orders {
+		ids[i] = orders[i].ID
+		byID[orders[i].ID] = &orders[i]
+	}
+
+	query := `
+		SELECT ip.id_pedido, ip.id_produto, ip.quantidade, ip.preco_unitario, p.nome, p.categoria
+		FROM item_pedido ip
+		JOIN produto p ON p.id = ip.id_produto
+		WHERE ip.id_pedido IN ?
+		ORDER BY ip.id_pedido, ip.id_produto
+	`
+	var rows []struct {
+		IDPedido      uint        `gorm:"column:id_pedido"`
+		IDProduto     uint        `gorm:"column:id_produto"`
+		Quantidade    int         `gorm:"column:quantidade"`
+		PrecoUnitario model.Money `gorm:"column:preco_unitario"`
+		Nome          string      `gorm:"column:nome"`
+		Categoria     string      `gorm:"column:categoria"`
+	}
+	if err := db.Raw(query, ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		order := byID[row.IDPedido]
+		order.Itens = append(order.Itens, model.OrderItem{
+			OrderID:   row.IDPedido,
+			ProductID: row.IDProduto,
+			Quantity:  row.Quantidade,
+			Product: model.Product{
+				ID:       row.IDProduto,
+				Name:     row.Nome,
+				Category: row.Categoria,
+				Price:    row.PrecoUnitario,
+			},
+		})
+	}
+	return orders, nil
+}
+
+func (p *PostgresRepository) ExportClients(afterID uint, limit int) ([]model.Client, error) {
+	return exportPage[model.Client](p.db, "cliente", afterID, limit)
+}
+
+func (p *PostgresRepository) ExportAddresses(afterID uint, limit int) ([]model.Address, error) {
+	return exportPage[model.Address](p.db, "endereco", afterID, limit)
+}
+
+func (p *PostgresRepository) ExportProducts(afterID uint, limit int) ([]model.Product, error) {
+	return exportPage[model.Product](p.db, "produto", afterID, limit)
+}
+
+func (p *PostgresRepository) ExportOrders(afterID uint, limit int) ([]model.Order, error) {
+	return exportOrders(p.db, afterID, limit)
+}
+
+func (p *PostgresRepository) ExportPayments(afterID uint, limit int) ([]model.Payment, error) {
+	return exportPage[model.Payment](p.db, "pagamento", afterID, limit)
+}
+
+func (s *SQLiteRepository) ExportClients(afterID uint, limit int) ([]model.Client, error) {
+	return exportPage[model.Client](s.db, "cliente", afterID, limit)
+}
+
+func (s *SQLiteRepository) ExportAddresses(afterID uint, limit int) ([]model.Address, error) {
+	return exportPage[model.Address](s.db, "endereco", afterID, limit)
+}
+
+func (s *SQLiteRepository) ExportProducts(afterID uint, limit int) ([]model.Product, error) {
+	return exportPage[model.Product](s.db, "produto", afterID, limit)
+}
+
+func (s *SQLiteRepository) ExportOrders(afterID uint, limit int) ([]model.Order, error) {
+	return exportOrders(s.db, afterID, limit)
+}
+
+func (s *SQLiteRepository) ExportPayments(afterID uint, limit int) ([]model.Payment, error) {
+	return exportPage[model.Payment](s.db, "pagamento", afterID, limit)
+}
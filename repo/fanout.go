@@ -0,0 +1,621 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"time"
+)
+
+var (
+	_ TechMarketRepository = &FanoutRepository{}
+	_ Refunder             = &FanoutRepository{}
+	_ Reviewer             = &FanoutRepository{}
+	_ ShipmentTracker      = &FanoutRepository{}
+	_ AddressBook          = &FanoutRepository{}
+	_ CouponBook           = &FanoutRepository{}
+)
+
+// Backend dá nome a um repositório participante do FanoutRepository.
+type Backend struct {
+	Name string
+	Repo TechMarketRepository
+}
+
+// Divergence registra uma leitura sombra cujo resultado difere do primário.
+type Divergence struct {
+	Method  string
+	Backend string
+	Primary []string
+	Shadow  []string
+	Err     error
+}
+
+// FanoutRepository grava cada Batch* em todos os backends e atende as leituras
+// pelo primário. Com leituras sombra ativadas, a mesma consulta é repetida em
+// segundo plano nos demais backends e qualquer diferença é registrada.
+//
+// Os registros sem ID recebem um do gerador compartilhado antes de qualquer
+// gravação, então o mesmo ID identifica o mesmo registro em todos os backends.
+// No modo sequencial o primário grava primeiro; no modo paralelo cada backend
+// sombra recebe uma cópia do lote, já com os IDs.
+type FanoutRepository struct {
+	primary     Backend
+	shadows     []Backend
+	parallel    bool
+	shadowReads bool
+
+	// OnDivergence é chamado para cada divergência encontrada. Por padrão
+	// apenas registra no log.
+	OnDivergence func(Divergence)
+
+	pending     sync.WaitGroup
+	mu          sync.Mutex
+	divergences int
+
+	// conditional serializa RefundPayment e CreateOrderWithCoupon, que gravam
+	// ou recusam conforme o que já foi gravado (saldo do pagamento, usos do
+	// cupom). Na mesma ordem, todos os backends tomam a mesma decisão.
+	conditional sync.Mutex
+}
+
+func NewFanoutRepository(backends []Backend) *FanoutRepository {
+	config := config.LoadFanoutConfig()
+
+	f := &FanoutRepository{
+		parallel:    config.Parallel,
+		shadowReads: config.ShadowReads,
+		OnDivergence: func(d Divergence) {
+			if d.Err != nil {
+				log.Printf("Divergência em %s (%s): erro na leitura sombra: %v", d.Method, d.Backend, d.Err)
+				return
+			}
+			log.Printf("Divergência em %s (%s): primário %v, sombra %v", d.Method, d.Backend, d.Primary, d.Shadow)
+		},
+	}
+
+	for _, b := range backends {
+		if b.Name == config.Primary {
+			f.primary = b
+		} else {
+			f.shadows = append(f.shadows, b)
+		}
+	}
+	if f.primary.Repo == nil {
+		panic(fmt.Sprintf("Backend primário %q não está entre os backends do fan-out", config.Primary))
+	}
+
+	return f
+}
+
+// Divergences retorna quantas leituras sombra divergiram do primário.
+func (f *FanoutRepository) Divergences() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.divergences
+}
+
+// Wait aguarda as leituras sombra em andamento.
+func (f *FanoutRepository) Wait() {
+	f.pending.Wait()
+}
+
+func fanoutWrite[T any](f *FanoutRepository, batch []T, write func(TechMarketRepository, []T) error) error {
+	errs := make([]error, len(f.shadows)+1)
+	wrap := func(name string, err error) error {
+		if err == nil {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	if !f.parallel {
+		errs[0] = wrap(f.primary.Name, write(f.primary.Repo, batch))
+		for i, b := range f.shadows {
+			errs[i+1] = wrap(b.Name, write(b.Repo, batch))
+		}
+		return errors.Join(errs...)
+	}
+
+	var wg sync.WaitGroup
+	for i, b := range f.shadows {
+		clone := slices.Clone(batch)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i+1] = wrap(b.Name, write(b.Repo, clone))
+		}()
+	}
+	errs[0] = wrap(f.primary.Name, write(f.primary.Repo, batch))
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// fanoutOne grava um único registro em todos os backends, como fanoutWrite, e
+// devolve em v os campos preenchidos pelo primário.
+func fanoutOne[T any](f *FanoutRepository, v *T, write func(TechMarketRepository, *T) error) error {
+	batch := []T{*v}
+	err := fanoutWrite(f, batch, func(r TechMarketRepository, batch []T) error {
+		return write(r, &batch[0])
+	})
+	*v = batch[0]
+	return err
+}
+
+func shadowRead[T any](f *FanoutRepository, method string, primary T, fingerprint func(T) []string, read func(TechMarketRepository) (T, error)) {
+	if !f.shadowReads {
+		return
+	}
+
+	want := fingerprint(primary)
+	for _, b := range f.shadows {
+		f.pending.Add(1)
+		go func() {
+			defer f.pending.Done()
+
+			result, err := read(b.Repo)
+			var got []string
+			if err == nil {
+				got = fingerprint(result)
+				if slices.Equal(got, want) {
+					return
+				}
+			}
+
+			f.mu.Lock()
+			f.divergences++
+			f.mu.Unlock()
+			f.OnDivergence(Divergence{Method: method, Backend: b.Name, Primary: want, Shadow: got, Err: err})
+		}()
+	}
+}
+
+func (f *FanoutRepository) BatchCreateClient(clients []model.Client) error {
+	for i := range clients {
+		ensureID(&clients[i].ID)
+	}
+	return fanoutWrite(f, clients, TechMarketRepository.BatchCreateClient)
+}
+
+func (f *FanoutRepository) BatchCreateProduct(products []model.Product) error {
+	for i := range products {
+		ensureID(&products[i].ID)
+	}
+	return fanoutWrite(f, products, TechMarketRepository.BatchCreateProduct)
+}
+
+func (f *FanoutRepository) BatchCreateOrder(orders []model.Order) error {
+	for i := range orders {
+		ensureID(&orders[i].ID)
+	}
+	return fanoutWrite(f, orders, TechMarketRepository.BatchCreateOrder)
+}
+
+func (f *FanoutRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
+	return fanoutWrite(f, orderItems, TechMarketRepository.BatchCreateOrderItem)
+}
+
+func (f *FanoutRepository) BatchCreatePayment(payments []model.Payment) error {
+	for i := range payments {
+		ensureID(&payments[i].ID)
+	}
+	return fanoutWrite(f, payments, TechMarketRepository.BatchCreatePayment)
+}
+
+func (f *FanoutRepository) GetClientByEmail(email string) (model.Client, error) {
+	client, err := f.primary.Repo.GetClientByEmail(email)
+	if err != nil {
+		return model.Client{}, err
+	}
+
+	shadowRead(f, "GetClientByEmail", client, clientFingerprint, func(r TechMarketRepository) (model.Client, error) {
+		return r.GetClientByEmail(email)
+	})
+	return client, nil
+}
+
+func (f *FanoutRepository) GetProductByCategory(category string) ([]model.Product, error) {
+	products, err := f.primary.Repo.GetProductByCategory(category)
+	if err != nil {
+		return nil, err
+	}
+
+	shadowRead(f, "GetProductByCategory", products, sorted(productsFingerprint), func(r TechMarketRepository) ([]model.Product, error) {
+		return r.GetProductByCategory(category)
+	})
+	return products, nil
+}
+
+func (f *FanoutRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
+	orders, err := f.primary.Repo.GetDeliveredOrdersByClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	shadowRead(f, "GetDeliveredOrdersByClient", orders, sorted(ordersFingerprint), func(r TechMarketRepository) ([]model.Order, error) {
+		return r.GetDeliveredOrdersByClient(clientID)
+	})
+	return orders, nil
+}
+
+func (f *FanoutRepository) Get5MostSoldProducts() ([]model.Product, error) {
+	products, err := f.primary.Repo.Get5MostSoldProducts()
+	if err != nil {
+		return nil, err
+	}
+
+	// Aqui a ordem faz parte do resultado.
+	shadowRead(f, "Get5MostSoldProducts", products, productsFingerprint, TechMarketRepository.Get5MostSoldProducts)
+	return products, nil
+}
+
+func (f *FanoutRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
+	payments, err := f.primary.Repo.GetLastMonthPixPayments()
+	if err != nil {
+		return nil, err
+	}
+
+	shadowRead(f, "GetLastMonthPixPayments", payments, sorted(paymentsFingerprint), TechMarketRepository.GetLastMonthPixPayments)
+	return payments, nil
+}
+
+func (f *FanoutRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	total, err := f.primary.Repo.GetClientTotalSpentByPeriod(clientID, startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+
+	shadowRead(f, "GetClientTotalSpentByPeriod", total, totalFingerprint, func(r TechMarketRepository) (model.Money, error) {
+		return r.GetClientTotalSpentByPeriod(clientID, startDate, endDate)
+	})
+	return total, nil
+}
+
+// Os métodos das interfaces opcionais passam pelo fan-out como os demais: os
+// registros de cada backend referenciam os pedidos, pagamentos e endereços
+// dele. Um backend que não implementa a interface faz a chamada falhar.
+
+func (f *FanoutRepository) BatchCreateAddress(addresses []model.Address) error {
+	for i := range addresses {
+		ensureID(&addresses[i].ID)
+	}
+	return fanoutWrite(f, addresses, func(r TechMarketRepository, batch []model.Address) error {
+		book, err := optional[AddressBook](r, "guarda endereços")
+		if err != nil {
+			return err
+		}
+		return book.BatchCreateAddress(batch)
+	})
+}
+
+// SetDefaultAddress altera endereços já gravados, então antes espera as
+// leituras sombra em andamento, que de outro modo poderiam ver o novo padrão
+// nos backends sombra e acusar uma divergência.
+func (f *FanoutRepository) SetDefaultAddress(clientID uint, addressID uint) error {
+	f.Wait()
+	return fanoutWrite(f, []uint{addressID}, func(r TechMarketRepository, ids []uint) error {
+		book, err := optional[AddressBook](r, "guarda endereços")
+		if err != nil {
+			return err
+		}
+		return book.SetDefaultAddress(clientID, ids[0])
+	})
+}
+
+func (f *FanoutRepository) GetClientAddresses(clientID uint) ([]model.Address, error) {
+	book, err := optional[AddressBook](f.primary.Repo, "guarda endereços")
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := book.GetClientAddresses(clientID)
+	if err != nil {
+		return nil, err
+	}
+	shadowRead(f, "GetClientAddresses", addresses, addressesFingerprint, func(r TechMarketRepository) ([]model.Address, error) {
+		book, err := optional[AddressBook](r, "guarda endereços")
+		if err != nil {
+			return nil, err
+		}
+		return book.GetClientAddresses(clientID)
+	})
+	return addresses, nil
+}
+
+func (f *FanoutRepository) GetRevenueByState() ([]model.StateRevenue, error) {
+	book, err := optional[AddressBook](f.primary.Repo, "guarda endereços")
+	if err != nil {
+		return nil, err
+	}
+	revenue, err := book.GetRevenueByState()
+	if err != nil {
+		return nil, err
+	}
+	shadowRead(f, "GetRevenueByState", revenue, revenueFingerprint, func(r TechMarketRepository) ([]model.StateRevenue, error) {
+		book, err := optional[AddressBook](r, "guarda endereços")
+		if err != nil {
+			return nil, err
+		}
+		return book.GetRevenueByState()
+	})
+	return revenue, nil
+}
+
+// RefundPayment e AppendTrackingEvents alteram registros já gravados e, como
+// SetDefaultAddress, esperam antes as leituras sombra em andamento.
+func (f *FanoutRepository) RefundPayment(refund *model.Refund) error {
+	f.conditional.Lock()
+	defer f.conditional.Unlock()
+
+	f.Wait()
+	ensureID(&refund.ID)
+	return fanoutOne(f, refund, func(r TechMarketRepository, refund *model.Refund) error {
+		refunder, err := optional[Refunder](r, "registra estornos")
+		if err != nil {
+			return err
+		}
+		return refunder.RefundPayment(refund)
+	})
+}
+
+func (f *FanoutRepository) Refunds(paymentID uint) ([]model.Refund, error) {
+	refunder, err := optional[Refunder](f.primary.Repo, "registra estornos")
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := refunder.Refunds(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	shadowRead(f, "Refunds", refunds, sorted(refundsFingerprint), func(r TechMarketRepository) ([]model.Refund, error) {
+		refunder, err := optional[Refunder](r, "registra estornos")
+		if err != nil {
+			return nil, err
+		}
+		return refunder.Refunds(paymentID)
+	})
+	return refunds, nil
+}
+
+func (f *FanoutRepository) BatchCreateReview(reviews []model.Review) error {
+	for i := range reviews {
+		ensureID(&reviews[i].ID)
+	}
+	return fanoutWrite(f, reviews, func(r TechMarketRepository, batch []model.Review) error {
+		reviewer, err := optional[Reviewer](r, "guarda avaliações")
+		if err != nil {
+			return err
+		}
+		return reviewer.BatchCreateReview(batch)
+	})
+}
+
+func (f *FanoutRepository) GetProductReviews(productID uint, beforeID uint, limit int) ([]model.Review, error) {
+	reviewer, err := optional[Reviewer](f.primary.Repo, "guarda avaliações")
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := reviewer.GetProductReviews(productID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	shadowRead(f, "GetProductReviews", reviews, reviewsFingerprint, func(r TechMarketRepository) ([]model.Review, error) {
+		reviewer, err := optional[Reviewer](r, "guarda avaliações")
+		if err != nil {
+			return nil, err
+		}
+		return reviewer.GetProductReviews(productID, beforeID, limit)
+	})
+	return reviews, nil
+}
+
+func (f *FanoutRepository) GetAverageRatingByCategory() ([]model.CategoryRating, error) {
+	reviewer, err := optional[Reviewer](f.primary.Repo, "guarda avaliações")
+	if err != nil {
+		return nil, err
+	}
+	ratings, err := reviewer.GetAverageRatingByCategory()
+	if err != nil {
+		return nil, err
+	}
+	shadowRead(f, "GetAverageRatingByCategory", ratings, ratingsFingerprint, func(r TechMarketRepository) ([]model.CategoryRating, error) {
+		reviewer, err := optional[Reviewer](r, "guarda avaliações")
+		if err != nil {
+			return nil, err
+		}
+		return reviewer.GetAverageRatingByCategory()
+	})
+	return ratings, nil
+}
+
+func (f *FanoutRepository) BatchCreateShipment(shipments []model.Shipment) error {
+	for i := range shipments {
+		ensureID(&shipments[i].ID)
+	}
+	return fanoutWrite(f, shipments, func(r TechMarketRepository, batch []model.Shipment) error {
+		tracker, err := optional[ShipmentTracker](r, "rastreia envios")
+		if err != nil {
+			return err
+		}
+		return tracker.BatchCreateShipment(batch)
+	})
+}
+
+func (f *FanoutRepository) AppendTrackingEvents(events []model.TrackingEvent) error {
+	f.Wait()
+	return fanoutWrite(f, events, func(r TechMarketRepository, batch []model.TrackingEvent) error {
+		tracker, err := optional[ShipmentTracker](r, "rastreia envios")
+		if err != nil {
+			return err
+		}
+		return tracker.AppendTrackingEvents(batch)
+	})
+}
+
+func (f *FanoutRepository) GetTrackingHistory(orderID uint) ([]model.Shipment, error) {
+	tracker, err := optional[ShipmentTracker](f.primary.Repo, "rastreia envios")
+	if err != nil {
+		return nil, err
+	}
+	shipments, err := tracker.GetTrackingHistory(orderID)
+	if err != nil {
+		return nil, err
+	}
+	shadowRead(f, "GetTrackingHistory", shipments, shipmentsFingerprint, func(r TechMarketRepository) ([]model.Shipment, error) {
+		tracker, err := optional[ShipmentTracker](r, "rastreia envios")
+		if err != nil {
+			return nil, err
+		}
+		return tracker.GetTrackingHistory(orderID)
+	})
+	return shipments, nil
+}
+
+func (f *FanoutRepository) BatchCreateCoupon(coupons []model.Coupon) error {
+	return fanoutWrite(f, coupons, func(r TechMarketRepository, batch []model.Coupon) error {
+		book, err := optional[CouponBook](r, "guarda cupons")
+		if err != nil {
+			return err
+		}
+		return book.BatchCreateCoupon(batch)
+	})
+}
+
+func (f *FanoutRepository) CreateOrderWithCoupon(order *model.Order) error {
+	f.conditional.Lock()
+	defer f.conditional.Unlock()
+
+	ensureID(&order.ID)
+	return fanoutOne(f, order, func(r TechMarketRepository, order *model.Order) error {
+		book, err := optional[CouponBook](r, "guarda cupons")
+		if err != nil {
+			return err
+		}
+		return book.CreateOrderWithCoupon(order)
+	})
+}
+
+func (f *FanoutRepository) GetCouponUsage(code string) (model.CouponUsage, error) {
+	book, err := optional[CouponBook](f.primary.Repo, "guarda cupons")
+	if err != nil {
+		return model.CouponUsage{}, err
+	}
+	usage, err := book.GetCouponUsage(code)
+	if err != nil {
+		return model.CouponUsage{}, err
+	}
+	shadowRead(f, "GetCouponUsage", usage, usageFingerprint, func(r TechMarketRepository) (model.CouponUsage, error) {
+		book, err := optional[CouponBook](r, "guarda cupons")
+		if err != nil {
+			return model.CouponUsage{}, err
+		}
+		return book.GetCouponUsage(code)
+	})
+	return usage, nil
+}
+
+// As impressões digitais abaixo ignoram datas, cuja precisão varia entre os
+// bancos.
+
+func clientFingerprint(c model.Client) []string {
+	return []string{fmt.Sprintf("%d|%s|%s|%s|%s", c.ID, c.Nome, c.Email, c.Phone, c.CPF)}
+}
+
+func productsFingerprint(products []model.Product) []string {
+	keys := make([]string, len(products))
+	for i, p := range products {
+		keys[i] = fmt.Sprintf("%d|%s|%s|%s|%d", p.ID, p.Name, p.Category, p.Price.Decimal(), p.Stock)
+	}
+	return keys
+}
+
+func ordersFingerprint(orders []model.Order) []string {
+	keys := make([]string, len(orders))
+	for i, o := range orders {
+		var addressID uint
+		if o.AddressID != nil {
+			addressID = *o.AddressID
+		}
+		keys[i] = fmt.Sprintf("%d|%d|%d|%s|%s|%s|%s", o.ID, o.ClientID, addressID, o.Status, o.TotalValue.Decimal(), o.CouponCode, o.Discount.Decimal())
+	}
+	return keys
+}
+
+func addressesFingerprint(addresses []model.Address) []string {
+	keys := make([]string, len(addresses))
+	for i, a := range addresses {
+		keys[i] = fmt.Sprintf("%d|%d|%s|%s|%s|%s|%s|%s|%t", a.ID, a.ClientID, a.CEP, a.Street, a.Number, a.Complement, a.City, a.State, a.Default)
+	}
+	return keys
+}
+
+func revenueFingerprint(revenue []model.StateRevenue) []string {
+	keys := make([]string, len(revenue))
+	for i, r := range revenue {
+		keys[i] = fmt.Sprintf("%s|%d|%s", r.State, r.Orders, r.Revenue.Decimal())
+	}
+	return keys
+}
+
+func paymentsFingerprint(payments []model.Payment) []string {
+	keys := make([]string, len(payments))
+	for i, p := range payments {
+		keys[i] = fmt.Sprintf("%d|%d|%d|%s|%s|%s|%d|%s", p.ID, p.OrderID, p.ClientID, p.Type, p.Status, p.Amount.Decimal(), p.Installments, p.Refunded.Decimal())
+	}
+	return keys
+}
+
+func totalFingerprint(total model.Money) []string {
+	return []string{total.Decimal()}
+}
+
+func refundsFingerprint(refunds []model.Refund) []string {
+	keys := make([]string, len(refunds))
+	for i, r := range refunds {
+		keys[i] = fmt.Sprintf("%d|%d|%d|%d|%s|%s", r.ID, r.PaymentID, r.OrderID, r.ClientID, r.Amount.Decimal(), r.Reason)
+	}
+	return keys
+}
+
+func reviewsFingerprint(reviews []model.Review) []string {
+	keys := make([]string, len(reviews))
+	for i, r := range reviews {
+		keys[i] = fmt.Sprintf("%d|%d|%d|%d|%s", r.ID, r.ProductID, r.ClientID, r.Rating, r.Comment)
+	}
+	return keys
+}
+
+func ratingsFingerprint(ratings []model.CategoryRating) []string {
+	keys := make([]string, len(ratings))
+	for i, r := range ratings {
+		keys[i] = fmt.Sprintf("%s|%d|%d", r.Category, r.Reviews, r.RatingSum)
+	}
+	return keys
+}
+
+func shipmentsFingerprint(shipments []model.Shipment) []string {
+	var keys []string
+	for _, s := range shipments {
+		keys = append(keys, fmt.Sprintf("%d|%d|%s|%s|%s|%s", s.ID, s.OrderID, s.Carrier, s.TrackingCode, s.Address.CEP, s.Address.State))
+		for _, e := range s.Events {
+			keys = append(keys, fmt.Sprintf("%d|%s|%s|%s", e.ShipmentID, e.Status, e.Location, e.Description))
+		}
+	}
+	return keys
+}
+
+func usageFingerprint(usage model.CouponUsage) []string {
+	return []string{fmt.Sprintf("%d|%s", usage.Orders, usage.Discount.Decimal())}
+}
+
+func sorted[T any](fingerprint func(T) []string) func(T) []string {
+	return func(v T) []string {
+		keys := fingerprint(v)
+		slices.Sort(keys)
+		return keys
+	}
+}
@@ -0,0 +1,150 @@
+package repo_test
+
+import (
+	"path/filepath"
+	"slices"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/conformance"
+	"testing"
+)
+
+func newFanout(t *testing.T, mode string) (*repo.FanoutRepository, repo.TechMarketRepository, repo.TechMarketRepository) {
+	dir := t.TempDir()
+	t.Setenv("SQLITE_PATH", filepath.Join(dir, "techmarket.db"))
+	t.Setenv("BOLT_PATH", filepath.Join(dir, "techmarket.bolt"))
+	t.Setenv("FANOUT_PRIMARY", "sqlite")
+	t.Setenv("FANOUT_MODE", mode)
+	t.Setenv("FANOUT_SHADOW_READS", "true")
+
+	sqlite, bolt := repo.NewSQLiteRepository(), repo.NewBoltRepository()
+	fanout := repo.NewFanoutRepository([]repo.Backend{
+		{Name: "sqlite", Repo: sqlite},
+		{Name: "bolt", Repo: bolt},
+	})
+	return fanout, sqlite, bolt
+}
+
+// newSQLiteFanout grava em dois arquivos SQLite, que implementam todas as
+// interfaces opcionais.
+func newSQLiteFanout(t *testing.T, mode string) *repo.FanoutRepository {
+	dir := t.TempDir()
+	t.Setenv("FANOUT_PRIMARY", "sqlite")
+	t.Setenv("FANOUT_MODE", mode)
+	t.Setenv("FANOUT_SHADOW_READS", "true")
+
+	t.Setenv("SQLITE_PATH", filepath.Join(dir, "primario.db"))
+	primary := repo.NewSQLiteRepository()
+	t.Setenv("SQLITE_PATH", filepath.Join(dir, "sombra.db"))
+	shadow := repo.NewSQLiteRepository()
+
+	return repo.NewFanoutRepository([]repo.Backend{
+		{Name: "sqlite", Repo: primary},
+		{Name: "sqlite-sombra", Repo: shadow},
+	})
+}
+
+// addressOnly esconde do conformance as interfaces opcionais que o bbolt não
+// implementa.
+type addressOnly struct {
+	repo.TechMarketRepository
+	repo.AddressBook
+}
+
+func TestFanoutRepositoryConformance(t *testing.T) {
+	for _, mode := range []string{"sequencial", "paralelo"} {
+		backends := map[string]func(t *testing.T) (*repo.FanoutRepository, repo.TechMarketRepository){
+			"bolt": func(t *testing.T) (*repo.FanoutRepository, repo.TechMarketRepository) {
+				fanout, _, _ := newFanout(t, mode)
+				return fanout, addressOnly{fanout, fanout}
+			},
+			"sqlite": func(t *testing.T) (*repo.FanoutRepository, repo.TechMarketRepository) {
+				fanout := newSQLiteFanout(t, mode)
+				return fanout, fanout
+			},
+		}
+		for shadow, newRepo := range backends {
+			t.Run(mode+"/"+shadow, func(t *testing.T) {
+				var fanouts []*repo.FanoutRepository
+				conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+					fanout, r := newRepo(t)
+					fanouts = append(fanouts, fanout)
+					return r
+				})
+
+				for _, fanout := range fanouts {
+					fanout.Wait()
+					if n := fanout.Divergences(); n != 0 {
+						t.Errorf("divergências = %d, esperado 0", n)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFanoutRepositoryReportsDivergence(t *testing.T) {
+	fanout, _, bolt := newFanout(t, "sequencial")
+
+	var divergences []repo.Divergence
+	fanout.OnDivergence = func(d repo.Divergence) { divergences = append(divergences, d) }
+
+	products := []model.Product{{ID: 1, Name: "Tablet A", Category: "Tablets", Price: 1000, Stock: 1}}
+	if err := fanout.BatchCreateProduct(products); err != nil {
+		t.Fatal(err)
+	}
+	// Grava direto no backend sombra, fora do fan-out.
+	if err := bolt.BatchCreateProduct([]model.Product{{ID: 2, Name: "Tablet B", Category: "Tablets", Price: 900, Stock: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := fanout.GetProductByCategory("Tablets")
+	if err != nil || len(got) != 1 {
+		t.Fatalf("GetProductByCategory = %v, %v; esperado apenas o produto do primário", got, err)
+	}
+	fanout.Wait()
+
+	if len(divergences) != 1 {
+		t.Fatalf("divergências = %v, esperado 1", divergences)
+	}
+	if d := divergences[0]; d.Method != "GetProductByCategory" || d.Backend != "bolt" || len(d.Shadow) != 2 {
+		t.Errorf("divergência = %+v", d)
+	}
+}
+
+// Registros sem ID recebem o mesmo ID em todos os backends, também no modo
+// paralelo, em que os backends gravam ao mesmo tempo.
+func TestFanoutRepositoryAssignsSharedIDs(t *testing.T) {
+	fanout, sqlite, bolt := newFanout(t, "paralelo")
+	// Adianta a sequência do bbolt, que de outro modo coincidiria com a do SQLite.
+	if err := bolt.BatchCreateProduct([]model.Product{{Name: "Mouse", Category: "Periféricos", Price: 50, Stock: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	products := []model.Product{
+		{Name: "Tablet A", Category: "Tablets", Price: 1000, Stock: 1},
+		{Name: "Tablet B", Category: "Tablets", Price: 900, Stock: 1},
+	}
+	if err := fanout.BatchCreateProduct(products); err != nil {
+		t.Fatal(err)
+	}
+	want := []uint{products[0].ID, products[1].ID}
+	if want[0] == 0 || want[1] == 0 {
+		t.Fatalf("IDs não atribuídos: %v", want)
+	}
+
+	for name, r := range map[string]repo.TechMarketRepository{"sqlite": sqlite, "bolt": bolt} {
+		got, err := r.GetProductByCategory("Tablets")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		ids := []uint{}
+		for _, p := range got {
+			ids = append(ids, p.ID)
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, slices.Sorted(slices.Values(want))) {
+			t.Errorf("%s: IDs = %v, esperado %v", name, ids, want)
+		}
+	}
+}
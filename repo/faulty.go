@@ -0,0 +1,188 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"time"
+)
+
+var (
+	_ TechMarketRepository = &FaultyRepository{}
+	_ RetrySafeWriter      = &FaultyRepository{}
+)
+
+// ErrPartialWrite indica que apenas parte de um lote foi gravada. Não é um
+// erro transitório: repetir a chamada gravaria de novo o início do lote.
+var ErrPartialWrite = errors.New("escrita parcial")
+
+type FaultStats struct {
+	Errors        int
+	Timeouts      int
+	PartialWrites int
+}
+
+// FaultyRepository injeta latência, erros, timeouts e gravações parciais de
+// lote segundo um config.FaultConfig. O sorteio usa a semente do perfil, então
+// a mesma sequência de chamadas produz sempre as mesmas falhas.
+type FaultyRepository struct {
+	inner   TechMarketRepository
+	profile config.FaultConfig
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	stats FaultStats
+}
+
+func NewFaultyRepository(inner TechMarketRepository, profile config.FaultConfig) *FaultyRepository {
+	return &FaultyRepository{
+		inner:   inner,
+		profile: profile,
+		rng:     rand.New(rand.NewPCG(profile.Seed, profile.Seed)),
+	}
+}
+
+func (f *FaultyRepository) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+func (f *FaultyRepository) fault(method string) config.MethodFault {
+	if fault, ok := f.profile.Methods[method]; ok {
+		return fault
+	}
+	return f.profile.Methods["*"]
+}
+
+func (f *FaultyRepository) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rng.Float64() < rate
+}
+
+// inject aplica latência, timeout e erro, nessa ordem. Um erro retornado aqui
+// significa que o repositório envolvido não chegou a ser chamado.
+func (f *FaultyRepository) inject(method string) error {
+	fault := f.fault(method)
+
+	if fault.LatencyMS > 0 {
+		time.Sleep(time.Duration(fault.LatencyMS) * time.Millisecond)
+	}
+
+	if f.roll(fault.TimeoutRate) {
+		time.Sleep(time.Duration(fault.TimeoutMS) * time.Millisecond)
+		f.mu.Lock()
+		f.stats.Timeouts++
+		f.mu.Unlock()
+		return fmt.Errorf("timeout injetado em %s: %w", method, context.DeadlineExceeded)
+	}
+
+	if f.roll(fault.ErrorRate) {
+		f.mu.Lock()
+		f.stats.Errors++
+		f.mu.Unlock()
+		return fmt.Errorf("falha injetada em %s: %w", method, ErrTransient)
+	}
+
+	return nil
+}
+
+func injectWrite[T any](f *FaultyRepository, method string, batch []T, write func([]T) error) error {
+	if err := f.inject(method); err != nil {
+		return err
+	}
+
+	if len(batch) == 0 || !f.roll(f.fault(method).PartialFailureRate) {
+		return write(batch)
+	}
+
+	f.mu.Lock()
+	written := f.rng.IntN(len(batch))
+	f.stats.PartialWrites++
+	f.mu.Unlock()
+
+	if written > 0 {
+		if err := write(batch[:written]); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%w em %s (%d de %d registros gravados)", ErrPartialWrite, method, written, len(batch))
+}
+
+// As falhas injetadas antes da escrita não gravam nada e as escritas parciais
+// não são transitórias, então a segurança de repetir as escritas é a do
+// repositório envolvido.
+func (f *FaultyRepository) RetrySafeWrites() bool {
+	safe, ok := f.inner.(RetrySafeWriter)
+	return ok && safe.RetrySafeWrites()
+}
+
+func (f *FaultyRepository) BatchCreateClient(clients []model.Client) error {
+	return injectWrite(f, "BatchCreateClient", clients, f.inner.BatchCreateClient)
+}
+
+func (f *FaultyRepository) BatchCreateProduct(products []model.Product) error {
+	return injectWrite(f, "BatchCreateProduct", products, f.inner.BatchCreateProduct)
+}
+
+func (f *FaultyRepository) BatchCreateOrder(orders []model.Order) error {
+	return injectWrite(f, "BatchCreateOrder", orders, f.inner.BatchCreateOrder)
+}
+
+func (f *FaultyRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
+	return injectWrite(f, "BatchCreateOrderItem", orderItems, f.inner.BatchCreateOrderItem)
+}
+
+func (f *FaultyRepository) BatchCreatePayment(payments []model.Payment) error {
+	return injectWrite(f, "BatchCreatePayment", payments, f.inner.BatchCreatePayment)
+}
+
+func (f *FaultyRepository) GetClientByEmail(email string) (model.Client, error) {
+	if err := f.inject("GetClientByEmail"); err != nil {
+		return model.Client{}, err
+	}
+	return f.inner.GetClientByEmail(email)
+}
+
+func (f *FaultyRepository) GetProductByCategory(category string) ([]model.Product, error) {
+	if err := f.inject("GetProductByCategory"); err != nil {
+		return nil, err
+	}
+	return f.inner.GetProductByCategory(category)
+}
+
+func (f *FaultyRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
+	if err := f.inject("GetDeliveredOrdersByClient"); err != nil {
+		return nil, err
+	}
+	return f.inner.GetDeliveredOrdersByClient(clientID)
+}
+
+func (f *FaultyRepository) Get5MostSoldProducts() ([]model.Product, error) {
+	if err := f.inject("Get5MostSoldProducts"); err != nil {
+		return nil, err
+	}
+	return f.inner.Get5MostSoldProducts()
+}
+
+func (f *FaultyRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
+	if err := f.inject("GetLastMonthPixPayments"); err != nil {
+		return nil, err
+	}
+	return f.inner.GetLastMonthPixPayments()
+}
+
+func (f *FaultyRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	if err := f.inject("GetClientTotalSpentByPeriod"); err != nil {
+		return 0, err
+	}
+	return f.inner.GetClientTotalSpentByPeriod(clientID, startDate, endDate)
+}
@@ -0,0 +1,78 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"testing"
+)
+
+func TestFaultyRepositoryIsDeterministic(t *testing.T) {
+	profile := config.FaultConfig{
+		Seed: 7,
+		Methods: map[string]config.MethodFault{
+			"*": {ErrorRate: 0.5},
+		},
+	}
+
+	run := func() []bool {
+		t.Setenv("BOLT_PATH", filepath.Join(t.TempDir(), "techmarket.bolt"))
+		r := repo.NewFaultyRepository(repo.NewBoltRepository(), profile)
+
+		failed := make([]bool, 20)
+		for i := range failed {
+			_, err := r.GetClientByEmail("ana@techmarket.com")
+			failed[i] = errors.Is(err, repo.ErrTransient)
+		}
+		return failed
+	}
+
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("chamada %d: falhas diferentes com a mesma semente: %v x %v", i, first, second)
+		}
+	}
+}
+
+func TestFaultyRepositoryPartialWrite(t *testing.T) {
+	t.Setenv("BOLT_PATH", filepath.Join(t.TempDir(), "techmarket.bolt"))
+	inner := repo.NewInstrumentedRepository(repo.NewBoltRepository())
+	r := repo.NewFaultyRepository(inner, config.FaultConfig{
+		Seed: 1,
+		Methods: map[string]config.MethodFault{
+			"BatchCreateProduct":   {PartialFailureRate: 1},
+			"Get5MostSoldProducts": {TimeoutRate: 1},
+		},
+	})
+
+	products := make([]model.Product, 10)
+	for i := range products {
+		products[i] = model.Product{Name: "Produto", Category: "Acessórios", Price: 10, Stock: 1}
+	}
+
+	err := r.BatchCreateProduct(products)
+	if !errors.Is(err, repo.ErrPartialWrite) || repo.IsTransient(err) {
+		t.Fatalf("err = %v, esperado escrita parcial não transitória", err)
+	}
+
+	written := inner.Stats()["BatchCreateProduct"].Cardinality
+	stored, _ := r.GetProductByCategory("Acessórios")
+	if written >= len(products) || len(stored) != written {
+		t.Errorf("gravados %d de %d, encontrados %d", written, len(products), len(stored))
+	}
+
+	if _, err := r.Get5MostSoldProducts(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, esperado timeout", err)
+	}
+	if calls := inner.Stats()["Get5MostSoldProducts"].Calls; calls != 0 {
+		t.Errorf("repositório chamado %d vezes após timeout injetado", calls)
+	}
+
+	if stats := r.Stats(); stats.PartialWrites != 1 || stats.Timeouts != 1 || stats.Errors != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
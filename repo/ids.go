@@ -0,0 +1,20 @@
+package repo
+
+import (
+	"sync"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+)
+
+// O PostgreSQL, o SQLite e o bbolt geram um ID quando o registro chega sem um.
+// O MongoDB e o Cassandra não têm IDs numéricos próprios, então usam este
+// gerador e, como os outros, devolvem o ID no slice recebido.
+var fallbackIDs = sync.OnceValue(func() *model.IDGenerator {
+	return model.NewIDGenerator(config.LoadIDConfig().Node)
+})
+
+func ensureID(id *uint) {
+	if *id == 0 {
+		*id = fallbackIDs().Next()
+	}
+}
@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"sync"
+	"techmarket_showcase/model"
+	"time"
+)
+
+var _ TechMarketRepository = &InstrumentedRepository{}
+
+// Call descreve uma chamada feita através do InstrumentedRepository.
+// Cardinality é o número de registros enviados (escritas) ou retornados
+// (consultas).
+type Call struct {
+	Method      string
+	Write       bool
+	Duration    time.Duration
+	Cardinality int
+	Err         error
+}
+
+type MethodStats struct {
+	Calls       int
+	Errors      int
+	Cardinality int
+	Total       time.Duration
+	Min         time.Duration
+	Max         time.Duration
+}
+
+func (s MethodStats) Mean() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// InstrumentedRepository envolve qualquer TechMarketRepository e registra
+// latência, erros e cardinalidade de cada método, repassando cada chamada aos
+// observers informados.
+type InstrumentedRepository struct {
+	inner     TechMarketRepository
+	observers []func(Call)
+
+	mu    sync.Mutex
+	stats map[string]*MethodStats
+}
+
+func NewInstrumentedRepository(inner TechMarketRepository, observers ...func(Call)) *InstrumentedRepository {
+	return &InstrumentedRepository{
+		inner:     inner,
+		observers: observers,
+		stats:     make(map[string]*MethodStats),
+	}
+}
+
+// Stats retorna uma cópia das estatísticas acumuladas por método.
+func (r *InstrumentedRepository) Stats() map[string]MethodStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make(map[string]MethodStats, len(r.stats))
+	for method, s := range r.stats {
+		stats[method] = *s
+	}
+	return stats
+}
+
+func (r *InstrumentedRepository) record(method string, write bool, start time.Time, cardinality int, err error) {
+	call := Call{
+		Method:      method,
+		Write:       write,
+		Duration:    time.Since(start),
+		Cardinality: cardinality,
+		Err:         err,
+	}
+
+	r.mu.Lock()
+	s, ok := r.stats[method]
+	if !ok {
+		s = &MethodStats{Min: call.Duration}
+		r.stats[method] = s
+	}
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	s.Cardinality += cardinality
+	s.Total += call.Duration
+	s.Min = min(s.Min, call.Duration)
+	s.Max = max(s.Max, call.Duration)
+	r.mu.Unlock()
+
+	for _, observe := range r.observers {
+		observe(call)
+	}
+}
+
+func (r *InstrumentedRepository) BatchCreateClient(clients []model.Client) error {
+	start := time.Now()
+	err := r.inner.BatchCreateClient(clients)
+	r.record("BatchCreateClient", true, start, len(clients), err)
+	return err
+}
+
+func (r *InstrumentedRepository) BatchCreateProduct(products []model.Product) error {
+	start := time.Now()
+	err := r.inner.BatchCreateProduct(products)
+	r.record("BatchCreateProduct", true, start, len(products), err)
+	return err
+}
+
+func (r *InstrumentedRepository) BatchCreateOrder(orders []model.Order) error {
+	start := time.Now()
+	err := r.inner.BatchCreateOrder(orders)
+	r.record("BatchCreateOrder", true, start, len(orders), err)
+	return err
+}
+
+func (r *InstrumentedRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
+	start := time.Now()
+	err := r.inner.BatchCreateOrderItem(orderItems)
+	r.record("BatchCreateOrderItem", true, start, len(orderItems), err)
+	return err
+}
+
+func (r *InstrumentedRepository) BatchCreatePayment(payments []model.Payment) error {
+	start := time.Now()
+	err := r.inner.BatchCreatePayment(payments)
+	r.record("BatchCreatePayment", true, start, len(payments), err)
+	return err
+}
+
+func (r *InstrumentedRepository) GetClientByEmail(email string) (model.Client, error) {
+	start := time.Now()
+	client, err := r.inner.GetClientByEmail(email)
+
+	found := 0
+	if client != (model.Client{}) {
+		found = 1
+	}
+	r.record("GetClientByEmail", false, start, found, err)
+	return client, err
+}
+
+func (r *InstrumentedRepository) GetProductByCategory(category string) ([]model.Product, error) {
+	start := time.Now()
+	products, err := r.inner.GetProductByCategory(category)
+	r.record("GetProductByCategory", false, start, len(products), err)
+	return products, err
+}
+
+func (r *InstrumentedRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
+	start := time.Now()
+	orders, err := r.inner.GetDeliveredOrdersByClient(clientID)
+	r.record("GetDeliveredOrdersByClient", false, start, len(orders), err)
+	return orders, err
+}
+
+func (r *InstrumentedRepository) Get5MostSoldProducts() ([]model.Product, error) {
+	start := time.Now()
+	products, err := r.inner.Get5MostSoldProducts()
+	r.record("Get5MostSoldProducts", false, start, len(products), err)
+	return products, err
+}
+
+func (r *InstrumentedRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
+	start := time.Now()
+	payments, err := r.inner.GetLastMonthPixPayments()
+	r.record("GetLastMonthPixPayments", false, start, len(payments), err)
+	return payments, err
+}
+
+func (r *InstrumentedRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	start := time.Now()
+	total, err := r.inner.GetClientTotalSpentByPeriod(clientID, startDate, endDate)
+
+	cardinality := 1
+	if err != nil {
+		cardinality = 0
+	}
+	r.record("GetClientTotalSpentByPeriod", false, start, cardinality, err)
+	return total, err
+}
@@ -0,0 +1,55 @@
+package repo_test
+
+import (
+	"path/filepath"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/conformance"
+	"testing"
+)
+
+func TestInstrumentedRepository(t *testing.T) {
+	t.Setenv("BOLT_PATH", filepath.Join(t.TempDir(), "techmarket.bolt"))
+
+	var calls []repo.Call
+	r := repo.NewInstrumentedRepository(repo.NewBoltRepository(), func(c repo.Call) {
+		calls = append(calls, c)
+	})
+
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository { return r })
+
+	duplicate := []model.Client{{Nome: "Ana", Email: "ana.souza@techmarket.com", CPF: "123.456.789-09"}}
+	if err := r.BatchCreateClient(duplicate); err == nil {
+		t.Fatal("esperado erro ao inserir email duplicado")
+	}
+
+	stats := r.Stats()
+	cases := []struct {
+		method      string
+		calls       int
+		errors      int
+		cardinality int
+	}{
+		{"BatchCreateClient", 3, 2, 5},
+		{"BatchCreateOrderItem", 1, 0, 7},
+		{"GetClientByEmail", 3, 0, 1},
+		{"GetProductByCategory", 3, 0, 3},
+		{"Get5MostSoldProducts", 1, 0, 5},
+		{"GetClientTotalSpentByPeriod", 8, 0, 8},
+	}
+	for _, c := range cases {
+		s := stats[c.method]
+		if s.Calls != c.calls || s.Errors != c.errors || s.Cardinality != c.cardinality {
+			t.Errorf("%s: calls=%d errors=%d cardinality=%d, esperado %d/%d/%d",
+				c.method, s.Calls, s.Errors, s.Cardinality, c.calls, c.errors, c.cardinality)
+		}
+		if s.Min > s.Max || s.Mean() > s.Max {
+			t.Errorf("%s: latências inconsistentes: %+v", c.method, s)
+		}
+	}
+
+	last := calls[len(calls)-1]
+	if last.Method != "BatchCreateClient" || !last.Write || last.Err == nil {
+		t.Errorf("última chamada observada = %+v", last)
+	}
+}
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"errors"
 	"techmarket_showcase/model"
 	"time"
 )
@@ -17,5 +18,103 @@ type TechMarketRepository interface {
 	GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error)
 	Get5MostSoldProducts() ([]model.Product, error)
 	GetLastMonthPixPayments() ([]model.Payment, error)
-	GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (float64, error)
+	GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error)
+}
+
+// Exporter é implementado pelos repositórios que podem servir de origem para
+// uma migração. Cada método retorna até limit registros com ID maior que
+// afterID, em ordem crescente de ID. ExportOrders preenche Itens, incluindo
+// nome e preço unitário do produto, e ExportAddresses marca o endereço padrão
+// de cada cliente.
+type Exporter interface {
+	ExportClients(afterID uint, limit int) ([]model.Client, error)
+	ExportAddresses(afterID uint, limit int) ([]model.Address, error)
+	ExportProducts(afterID uint, limit int) ([]model.Product, error)
+	ExportOrders(afterID uint, limit int) ([]model.Order, error)
+	ExportPayments(afterID uint, limit int) ([]model.Payment, error)
+}
+
+// Refunder é implementado pelos repositórios que registram estornos.
+// RefundPayment grava o estorno de refund.Amount do pagamento refund.PaymentID,
+// preenchendo ID, OrderID e ClientID, soma o valor em Payment.Refunded e
+// atualiza o status do pagamento e do pedido com model.RefundStatus; o total
+// gasto pelo cliente passa a descontar o valor estornado. Um estorno inválido,
+// de pagamento inexistente ou não aprovado, ou acima do saldo retorna
+// *model.ValidationError. Refunds lista os estornos de um pagamento pela data.
+type Refunder interface {
+	RefundPayment(refund *model.Refund) error
+	Refunds(paymentID uint) ([]model.Refund, error)
+}
+
+// Reviewer é implementado pelos repositórios que guardam avaliações de
+// produtos. BatchCreateReview recusa, com *model.ValidationError, avaliações
+// inválidas ou de produto inexistente. GetProductReviews pagina as avaliações
+// de um produto da mais recente para a mais antiga (ordem decrescente de ID):
+// retorna até limit avaliações com ID menor que beforeID, ou as mais recentes
+// quando beforeID é zero. GetAverageRatingByCategory resume as notas de cada
+// categoria com avaliações, em ordem de categoria.
+type Reviewer interface {
+	BatchCreateReview(reviews []model.Review) error
+	GetProductReviews(productID uint, beforeID uint, limit int) ([]model.Review, error)
+	GetAverageRatingByCategory() ([]model.CategoryRating, error)
+}
+
+// ShipmentTracker é implementado pelos repositórios que rastreiam envios.
+// BatchCreateShipment grava envios de pedidos existentes, sem eventos.
+// AppendTrackingEvents acrescenta eventos às linhas do tempo de envios
+// existentes, em qualquer ordem de chegada. Os dois recusam registros
+// inválidos com *model.ValidationError. GetTrackingHistory retorna os envios
+// do pedido, por data de envio, cada um com os eventos em ordem de data.
+type ShipmentTracker interface {
+	BatchCreateShipment(shipments []model.Shipment) error
+	AppendTrackingEvents(events []model.TrackingEvent) error
+	GetTrackingHistory(orderID uint) ([]model.Shipment, error)
+}
+
+// AddressBook é implementado pelos repositórios que guardam os endereços de
+// entrega dos clientes. BatchCreateAddress recusa, com *model.ValidationError,
+// endereços inválidos, de cliente inexistente ou mais de um padrão do mesmo
+// cliente no lote; um endereço gravado com Default passa a ser o padrão do
+// cliente no lugar do anterior. SetDefaultAddress troca o endereço padrão do
+// cliente por um endereço dele. GetClientAddresses lista os endereços do
+// cliente, o padrão primeiro e os demais em ordem de ID. GetRevenueByState
+// soma os pedidos pela UF do endereço de entrega (ver model.StateRevenue), em
+// ordem de UF.
+//
+// Nesses repositórios BatchCreateOrder também confere que o endereço de cada
+// pedido existe e é do cliente do pedido.
+type AddressBook interface {
+	BatchCreateAddress(addresses []model.Address) error
+	SetDefaultAddress(clientID uint, addressID uint) error
+	GetClientAddresses(clientID uint) ([]model.Address, error)
+	GetRevenueByState() ([]model.StateRevenue, error)
+}
+
+// CouponBook é implementado pelos repositórios que guardam cupons de desconto.
+// BatchCreateCoupon recusa cupons inválidos com *model.ValidationError.
+// CreateOrderWithCoupon grava um pedido com o cupom de order.CouponCode: confere
+// o cupom (ver model.Coupon.Discount) e o limite de usos do cliente, conta o
+// uso e grava o pedido com Discount e TotalValue calculados a partir dos
+// itens, preenchendo-os também em order. Os itens continuam sendo gravados por
+// BatchCreateOrderItem. Usos concorrentes do mesmo cliente nunca passam do
+// limite, e um pedido recusado, com *model.ValidationError, não conta uso.
+// GetCouponUsage resume todos os pedidos gravados com o cupom.
+//
+// BatchCreateOrder grava CouponCode e Discount como informados, sem conferir o
+// cupom nem contar usos, como na cópia de pedidos já feitos.
+type CouponBook interface {
+	BatchCreateCoupon(coupons []model.Coupon) error
+	CreateOrderWithCoupon(order *model.Order) error
+	GetCouponUsage(code string) (model.CouponUsage, error)
+}
+
+// optional converte r em uma das interfaces opcionais, para os decoradores que
+// repassam as chamadas; what completa a mensagem de erro quando r não a
+// implementa.
+func optional[T any](r TechMarketRepository, what string) (T, error) {
+	v, ok := r.(T)
+	if !ok {
+		return v, errors.New("o repositório não " + what)
+	}
+	return v, nil
 }
@@ -2,7 +2,10 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"techmarket_showcase/config"
 	"techmarket_showcase/model"
 	"time"
@@ -12,10 +15,25 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var _ TechMarketRepository = &MongoDBRepository{}
+var (
+	_ TechMarketRepository = &MongoDBRepository{}
+	_ Refunder             = &MongoDBRepository{}
+	_ Reviewer             = &MongoDBRepository{}
+	_ ShipmentTracker      = &MongoDBRepository{}
+	_ AddressBook          = &MongoDBRepository{}
+	_ CouponBook           = &MongoDBRepository{}
+)
 
 type MongoDBRepository struct {
-	db *mongo.Client
+	db       *mongo.Client
+	database string
+	// salesCollection mantém a coleção pré-agregada vendas_por_produto a cada
+	// pedido gravado.
+	salesCollection bool
+	// readPrecomputed faz Get5MostSoldProducts ler vendas_por_produto em vez de
+	// agregar os itens dos pedidos.
+	readPrecomputed bool
+	writes          WriteOptions
 }
 
 func NewMongoDBRepository() *MongoDBRepository {
@@ -26,37 +44,76 @@ func NewMongoDBRepository() *MongoDBRepository {
 		panic(fmt.Sprintf("Erro ao conectar com o MongoDB: %v", err))
 	}
 
-	return &MongoDBRepository{db: client}
+	return &MongoDBRepository{db: client, database: "techmarket_db", salesCollection: config.SalesCollection}
+}
+
+// PrecomputedSales é implementado pelas duas variantes do MongoDB, que mantêm a
+// coleção vendas_por_produto.
+type PrecomputedSales interface {
+	Precomputed() *MongoDBRepository
+}
+
+// Precomputed retorna uma visão do mesmo banco cujo Get5MostSoldProducts lê a
+// coleção vendas_por_produto, para comparar com a agregação feita na hora. A
+// coleção só é mantida com MONGODB_SALES_COLLECTION=true.
+func (m *MongoDBRepository) Precomputed() *MongoDBRepository {
+	view := *m
+	view.readPrecomputed = true
+	return &view
+}
+
+// SetWriteOptions define o tamanho dos InsertMany/BulkWrite, se eles são
+// ordenados e quantos rodam ao mesmo tempo. TransactionPerBatch não se aplica:
+// as gravações do MongoDB não usam transações.
+func (m *MongoDBRepository) SetWriteOptions(opts WriteOptions) {
+	m.writes = opts
 }
 
 func (m *MongoDBRepository) BatchCreateClient(clients []model.Client) error {
-	collection := m.db.Database("techmarket_db").Collection("clientes")
+	if err := validateRecords(clients); err != nil {
+		return err
+	}
+	collection := m.db.Database(m.database).Collection("clientes")
 	ctx := context.Background()
 
 	var documents []any
-	for _, client := range clients {
-		doc := bson.M{
-			"nome":          client.Nome,
-			"email":         client.Email,
-			"telefone":      client.Phone,
-			"data_cadastro": client.CreatedAt,
-			"cpf":           client.CPF,
-			"pedidos":       []any{},
-		}
+	for i := range clients {
+		client := &clients[i]
+		ensureID(&client.ID)
+
+		doc := clientDocument(*client)
+		doc["pedidos"] = []any{}
 		documents = append(documents, doc)
 	}
 
-	_, err := collection.InsertMany(ctx, documents)
-	return err
+	return m.insertMany(ctx, collection, documents)
+}
+
+func clientDocument(client model.Client) bson.M {
+	return bson.M{
+		"_id":           int64(client.ID),
+		"nome":          client.Nome,
+		"email":         client.Email,
+		"telefone":      client.Phone,
+		"data_cadastro": client.CreatedAt,
+		"cpf":           client.CPF,
+	}
 }
 
 func (m *MongoDBRepository) BatchCreateProduct(products []model.Product) error {
-	collection := m.db.Database("techmarket_db").Collection("produtos")
+	if err := validateRecords(products); err != nil {
+		return err
+	}
+	collection := m.db.Database(m.database).Collection("produtos")
 	ctx := context.Background()
 
 	var documents []any
-	for _, product := range products {
+	for i := range products {
+		product := &products[i]
+		ensureID(&product.ID)
+
 		doc := bson.M{
+			"_id":       int64(product.ID),
 			"nome":      product.Name,
 			"categoria": product.Category,
 			"preco":     product.Price,
@@ -65,77 +122,261 @@ func (m *MongoDBRepository) BatchCreateProduct(products []model.Product) error {
 		documents = append(documents, doc)
 	}
 
-	_, err := collection.InsertMany(ctx, documents)
-	return err
+	return m.insertMany(ctx, collection, documents)
 }
 
 func (m *MongoDBRepository) BatchCreateOrder(orders []model.Order) error {
-	clientsCollection := m.db.Database("techmarket_db").Collection("clientes")
+	if _, err := validateOrders(orders, m.existingClients, m.addresses); err != nil {
+		return err
+	}
+	clientsCollection := m.db.Database(m.database).Collection("clientes")
 	ctx := context.Background()
 
-	for _, order := range orders {
-		var itens []bson.M
-		for _, item := range order.Itens {
-			itemDoc := bson.M{
-				"produto_id":     item.ProductID,
-				"nome_produto":   item.Product.Name,
-				"quantidade":     item.Quantity,
-				"preco_unitario": item.Product.Price,
-			}
-			itens = append(itens, itemDoc)
-		}
+	var updates []mongo.WriteModel
+	for i := range orders {
+		order := &orders[i]
+		ensureID(&order.ID)
 
-		pedidoDoc := bson.M{
-			"pedido_id":   order.ID,
-			"data_pedido": order.OrderDate,
-			"status":      order.Status,
-			"valor_total": order.TotalValue,
-			"itens":       itens,
-		}
+		pedidoDoc := orderDocument(*order)
+		pedidoDoc["pedido_id"] = int64(order.ID)
+
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": int64(order.ClientID)}).
+			SetUpdate(bson.M{"$push": bson.M{"pedidos": pedidoDoc}}))
+	}
+
+	// Sem BatchSize cada pedido é um comando de update separado, como se fosse
+	// um UpdateOne por pedido.
+	batches := slices.Collect(slices.Chunk(updates, m.writes.batchSizeOr(1)))
+	bulkOptions := options.BulkWrite().SetOrdered(!m.writes.Unordered)
+	err := forEachParallel(batches, m.writes.parallelism(), func(batch []mongo.WriteModel) error {
+		_, err := clientsCollection.BulkWrite(ctx, batch, bulkOptions)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if m.salesCollection {
+		return m.incrementSales(ctx, orders)
+	}
+	return nil
+}
 
-		filter := bson.M{"_id": order.ClientID}
-		update := bson.M{
-			"$push": bson.M{
-				"pedidos": pedidoDoc,
-			},
+// orderDocument monta o pedido com os itens embutidos, sem o ID, que fica em
+// pedido_id quando o pedido é embutido no cliente e em _id na coleção pedidos.
+func orderDocument(order model.Order) bson.M {
+	var itens []bson.M
+	for _, item := range order.Itens {
+		itemDoc := bson.M{
+			"produto_id":     int64(item.ProductID),
+			"nome_produto":   item.Product.Name,
+			"quantidade":     item.Quantity,
+			"preco_unitario": item.Product.Price,
 		}
+		itens = append(itens, itemDoc)
+	}
 
-		_, err := clientsCollection.UpdateOne(ctx, filter, update)
-		if err != nil {
-			return err
+	doc := bson.M{
+		"data_pedido": order.OrderDate,
+		"status":      order.Status,
+		"valor_total": order.TotalValue,
+		"itens":       itens,
+	}
+	if order.AddressID != nil {
+		doc["endereco_id"] = int64(*order.AddressID)
+	}
+	if order.CouponCode != "" {
+		doc["codigo_cupom"] = order.CouponCode
+		doc["desconto"] = order.Discount
+	}
+	return doc
+}
+
+// insertMany grava os documentos em InsertMany de até WriteOptions.BatchSize
+// documentos; sem BatchSize, em um único InsertMany.
+func (m *MongoDBRepository) insertMany(ctx context.Context, collection *mongo.Collection, documents []any) error {
+	batches := slices.Collect(slices.Chunk(documents, m.writes.batchSizeOr(len(documents))))
+	insertOptions := options.InsertMany().SetOrdered(!m.writes.Unordered)
+	return forEachParallel(batches, m.writes.parallelism(), func(batch []any) error {
+		_, err := collection.InsertMany(ctx, batch, insertOptions)
+		return err
+	})
+}
+
+// incrementSales soma as quantidades vendidas dos pedidos em
+// vendas_por_produto, com um upsert por produto.
+func (m *MongoDBRepository) incrementSales(ctx context.Context, orders []model.Order) error {
+	sold := make(map[uint]int)
+	for _, order := range orders {
+		for _, item := range order.Itens {
+			sold[item.ProductID] += item.Quantity
 		}
 	}
+	if len(sold) == 0 {
+		return nil
+	}
 
-	return nil
+	var updates []mongo.WriteModel
+	for _, productID := range slices.Sorted(maps.Keys(sold)) {
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": int64(productID)}).
+			SetUpdate(bson.M{"$inc": bson.M{"total_vendido": sold[productID]}}).
+			SetUpsert(true))
+	}
+
+	collection := m.db.Database(m.database).Collection("vendas_por_produto")
+	_, err := collection.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false))
+	return err
 }
 
 func (m *MongoDBRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
+	if err := validateRecords(orderItems); err != nil {
+		return err
+	}
 	// No MongoDB, os itens do pedido são armazenados diretamente no documento do pedido
 	// dentro da coleção de clientes, então esta função não precisa fazer nada
 	return nil
 }
 
 func (m *MongoDBRepository) BatchCreatePayment(payments []model.Payment) error {
-	collection := m.db.Database("techmarket_db").Collection("pagamentos")
+	if err := validatePayments(payments, m.orderClients); err != nil {
+		return err
+	}
+	return m.insertPayments(payments)
+}
+
+// orderClients procura os pedidos embutidos em clientes.pedidos, pelo índice
+// multichave em pedidos.pedido_id.
+func (m *MongoDBRepository) orderClients(orderIDs []uint) (map[uint]uint, error) {
+	collection := m.db.Database(m.database).Collection("clientes")
+	ctx := context.Background()
+
+	ids := make(bson.A, len(orderIDs))
+	for i, id := range orderIDs {
+		ids[i] = int64(id)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"pedidos.pedido_id": bson.M{"$in": ids}}}},
+		{{Key: "$unwind", Value: "$pedidos"}},
+		{{Key: "$match", Value: bson.M{"pedidos.pedido_id": bson.M{"$in": ids}}}},
+		{{Key: "$project", Value: bson.M{"_id": "$pedidos.pedido_id", "id_cliente": "$_id"}}},
+	}
+	return mongoOrderClients(ctx, collection, pipeline)
+}
+
+// mongoOrderClients lê documentos {_id: pedido, id_cliente: cliente}.
+func mongoOrderClients(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) (map[uint]uint, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID       int64 `bson:"_id"`
+		ClientID int64 `bson:"id_cliente"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	clients := make(map[uint]uint, len(rows))
+	for _, row := range rows {
+		clients[uint(row.ID)] = uint(row.ClientID)
+	}
+	return clients, nil
+}
+
+// addresses procura os endereços embutidos em clientes.enderecos, pelo índice
+// multichave em enderecos._id. Os endereços ficam no cliente nas duas
+// variantes.
+func (m *MongoDBRepository) addresses(addressIDs []uint) (map[uint]model.Address, error) {
+	collection := m.db.Database(m.database).Collection("clientes")
+	ctx := context.Background()
+
+	ids := make(bson.A, len(addressIDs))
+	for i, id := range addressIDs {
+		ids[i] = int64(id)
+	}
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"enderecos._id": bson.M{"$in": ids}}}},
+		{{Key: "$unwind", Value: "$enderecos"}},
+		{{Key: "$match", Value: bson.M{"enderecos._id": bson.M{"$in": ids}}}},
+		{{Key: "$project", Value: bson.M{"_id": "$enderecos._id", "id_cliente": "$_id", "uf": "$enderecos.uf"}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []model.Address
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	addresses := make(map[uint]model.Address, len(rows))
+	for _, row := range rows {
+		addresses[row.ID] = row
+	}
+	return addresses, nil
+}
+
+func (m *MongoDBRepository) existingClients(clientIDs []uint) (map[uint]bool, error) {
+	collection := m.db.Database(m.database).Collection("clientes")
+	ctx := context.Background()
+
+	ids := make(bson.A, len(clientIDs))
+	for i, id := range clientIDs {
+		ids[i] = int64(id)
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ID int64 `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	clients := make(map[uint]bool, len(rows))
+	for _, row := range rows {
+		clients[uint(row.ID)] = true
+	}
+	return clients, nil
+}
+
+func (m *MongoDBRepository) insertPayments(payments []model.Payment) error {
+	collection := m.db.Database(m.database).Collection("pagamentos")
 	ctx := context.Background()
 
 	var documents []any
-	for _, payment := range payments {
+	for i := range payments {
+		payment := &payments[i]
+		ensureID(&payment.ID)
+
 		doc := bson.M{
-			"tipo":           payment.Type,
-			"status":         payment.Status,
-			"data_pagamento": payment.PaymentDate,
-			"pedido_id":      payment.OrderID,
+			"_id":             int64(payment.ID),
+			"tipo":            payment.Type,
+			"status":          payment.Status,
+			"valor":           payment.Amount,
+			"parcelas":        payment.Installments,
+			"valor_estornado": payment.Refunded,
+			"data_pagamento":  payment.PaymentDate,
+			"pedido_id":       int64(payment.OrderID),
+			"id_cliente":      int64(payment.ClientID),
 		}
 		documents = append(documents, doc)
 	}
 
-	_, err := collection.InsertMany(ctx, documents)
-	return err
+	return m.insertMany(ctx, collection, documents)
 }
 
 func (m *MongoDBRepository) GetClientByEmail(email string) (model.Client, error) {
-	collection := m.db.Database("techmarket_db").Collection("clientes")
+	collection := m.db.Database(m.database).Collection("clientes")
 	ctx := context.Background()
 
 	filter := bson.M{"email": email}
@@ -151,21 +392,24 @@ func (m *MongoDBRepository) GetClientByEmail(email string) (model.Client, error)
 }
 
 func (m *MongoDBRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
-	collection := m.db.Database("techmarket_db").Collection("clientes")
+	collection := m.db.Database(m.database).Collection("clientes")
 	ctx := context.Background()
 
-	filter := bson.M{"_id": clientID}
+	filter := bson.M{"_id": int64(clientID)}
 	var result struct {
 		Pedidos []struct {
-			PedidoID   uint      `bson:"pedido_id"`
-			DataPedido time.Time `bson:"data_pedido"`
-			Status     string    `bson:"status"`
-			ValorTotal float64   `bson:"valor_total"`
-			Itens      []struct {
-				NomeProduto   string  `bson:"nome_produto"`
-				ProdutoID     uint    `bson:"produto_id"`
-				Quantidade    int     `bson:"quantidade"`
-				PrecoUnitario float64 `bson:"preco_unitario"`
+			PedidoID    uint        `bson:"pedido_id"`
+			EnderecoID  *uint       `bson:"endereco_id"`
+			DataPedido  time.Time   `bson:"data_pedido"`
+			Status      string      `bson:"status"`
+			ValorTotal  model.Money `bson:"valor_total"`
+			CodigoCupom string      `bson:"codigo_cupom"`
+			Desconto    model.Money `bson:"desconto"`
+			Itens       []struct {
+				NomeProduto   string      `bson:"nome_produto"`
+				ProdutoID     uint        `bson:"produto_id"`
+				Quantidade    int         `bson:"quantidade"`
+				PrecoUnitario model.Money `bson:"preco_unitario"`
 			} `bson:"itens"`
 		} `bson:"pedidos"`
 	}
@@ -180,7 +424,7 @@ func (m *MongoDBRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.O
 
 	var orders []model.Order
 	for _, pedido := range result.Pedidos {
-		if pedido.Status == "entregue" {
+		if pedido.Status == model.OrderStatusDelivered {
 			var itens []model.OrderItem
 			for _, item := range pedido.Itens {
 				itens = append(itens, model.OrderItem{
@@ -197,9 +441,12 @@ func (m *MongoDBRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.O
 
 			order := model.Order{
 				ID:         pedido.PedidoID,
+				AddressID:  pedido.EnderecoID,
 				OrderDate:  pedido.DataPedido,
 				Status:     pedido.Status,
 				TotalValue: pedido.ValorTotal,
+				CouponCode: pedido.CodigoCupom,
+				Discount:   pedido.Desconto,
 				ClientID:   clientID,
 				Itens:      itens,
 			}
@@ -214,16 +461,37 @@ func (m *MongoDBRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.O
 	return orders, nil
 }
 
+// Get5MostSoldProducts soma na hora as quantidades dos itens embutidos em
+// clientes.pedidos ou, na visão Precomputed, lê os totais de
+// vendas_por_produto. Empates são desfeitos pelo ID do produto.
 func (m *MongoDBRepository) Get5MostSoldProducts() ([]model.Product, error) {
-	collection := m.db.Database("techmarket_db").Collection("produtos")
+	if m.readPrecomputed {
+		return m.mostSoldProducts("vendas_por_produto", mongo.Pipeline{})
+	}
+	return m.mostSoldProducts("clientes", mongo.Pipeline{
+		{{Key: "$unwind", Value: "$pedidos"}},
+		{{Key: "$unwind", Value: "$pedidos.itens"}},
+		{{Key: "$group", Value: bson.M{"_id": "$pedidos.itens.produto_id", "total_vendido": bson.M{"$sum": "$pedidos.itens.quantidade"}}}},
+	})
+}
+
+// mostSoldProducts completa um pipeline que produz documentos
+// {_id: produto_id, total_vendido} com a ordenação, o limite e os detalhes
+// dos produtos.
+func (m *MongoDBRepository) mostSoldProducts(collectionName string, pipeline mongo.Pipeline) ([]model.Product, error) {
+	collection := m.db.Database(m.database).Collection(collectionName)
 	ctx := context.Background()
 
-	filter := bson.M{}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "total_vendido", Value: -1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: 5}},
+		bson.D{{Key: "$lookup", Value: bson.M{"from": "produtos", "localField": "_id", "foreignField": "_id", "as": "produto"}}},
+		bson.D{{Key: "$unwind", Value: "$produto"}},
+		bson.D{{Key: "$replaceWith", Value: "$produto"}},
+	)
+
 	var products []model.Product
-	cursor, err := collection.Find(ctx, filter)
-	if err != nil && err == mongo.ErrNoDocuments {
-		return nil, nil
-	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
@@ -237,7 +505,7 @@ func (m *MongoDBRepository) Get5MostSoldProducts() ([]model.Product, error) {
 }
 
 func (m *MongoDBRepository) GetProductByCategory(category string) ([]model.Product, error) {
-	collection := m.db.Database("techmarket_db").Collection("produtos")
+	collection := m.db.Database(m.database).Collection("produtos")
 	ctx := context.Background()
 
 	filter := bson.M{"categoria": category}
@@ -259,10 +527,10 @@ func (m *MongoDBRepository) GetProductByCategory(category string) ([]model.Produ
 }
 
 func (m *MongoDBRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
-	collection := m.db.Database("techmarket_db").Collection("pagamentos")
+	collection := m.db.Database(m.database).Collection("pagamentos")
 	ctx := context.Background()
 
-	filter := bson.M{"tipo": "pix", "data_pagamento": bson.M{"$gte": time.Now().AddDate(0, -1, 0), "$lte": time.Now()}}
+	filter := bson.M{"tipo": model.PaymentTypePix, "data_pagamento": bson.M{"$gte": time.Now().AddDate(0, -1, 0), "$lte": time.Now()}}
 	var payments []model.Payment
 	cursor, err := collection.Find(ctx, filter)
 	if err != nil && err == mongo.ErrNoDocuments {
@@ -280,30 +548,663 @@ func (m *MongoDBRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
 	return payments, nil
 }
 
-func (m *MongoDBRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (float64, error) {
-	collection := m.db.Database("techmarket_db").Collection("pagamentos")
+func (m *MongoDBRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	collection := m.db.Database(m.database).Collection("pagamentos")
 	ctx := context.Background()
 
-	filter := bson.M{"id_cliente": clientID, "data_pagamento": bson.M{"$gte": startDate, "$lte": endDate}}
-	var payments []model.Payment
-	cursor, err := collection.Find(ctx, filter)
-	if err != nil && err == mongo.ErrNoDocuments {
-		return 0, nil
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"id_cliente":     int64(clientID),
+			"data_pagamento": bson.M{"$gte": startDate, "$lte": endDate},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "total": bson.M{"$sum": bson.M{"$subtract": bson.A{
+			"$valor",
+			bson.M{"$ifNull": bson.A{"$valor_estornado", model.Money(0)}},
+		}}}}}},
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	err = cursor.All(ctx, &payments)
-	if err != nil {
+	var result []struct {
+		Total model.Money `bson:"total"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
 		return 0, err
 	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+	return result[0].Total, nil
+}
+
+// mongoOrderRefunds lê o total e grava o status de um pedido, que fica
+// embutido em clientes.pedidos ou na coleção pedidos conforme a variante.
+type mongoOrderRefunds struct {
+	total     func(ctx context.Context, orderID, clientID uint) (model.Money, error)
+	setStatus func(ctx context.Context, orderID, clientID uint, status string) error
+}
+
+func (m *MongoDBRepository) RefundPayment(refund *model.Refund) error {
+	clients := m.db.Database(m.database).Collection("clientes")
+	filter := func(orderID, clientID uint) bson.M {
+		return bson.M{"_id": int64(clientID), "pedidos.pedido_id": int64(orderID)}
+	}
 
-	var total float64
-	collection.Aggregate(ctx, []bson.M{
-		{
-			"$match": bson.M{"id_cliente": clientID, "data_pagamento": bson.M{"$gte": startDate, "$lte": endDate}},
+	return m.refundPayment(refund, mongoOrderRefunds{
+		total: func(ctx context.Context, orderID, clientID uint) (model.Money, error) {
+			var client struct {
+				Pedidos []struct {
+					Total model.Money `bson:"valor_total"`
+				} `bson:"pedidos"`
+			}
+			err := clients.FindOne(ctx, filter(orderID, clientID), options.FindOne().SetProjection(bson.M{"pedidos.$": 1})).Decode(&client)
+			if err != nil {
+				return 0, err
+			}
+			return client.Pedidos[0].Total, nil
 		},
-		{
-			"$group": bson.M{"_id": nil, "total": bson.M{"$sum": "$valor_total"}},
+		setStatus: func(ctx context.Context, orderID, clientID uint, status string) error {
+			_, err := clients.UpdateOne(ctx, filter(orderID, clientID), bson.M{"$set": bson.M{"pedidos.$.status": status}})
+			return err
 		},
 	})
-	return total, nil
+}
+
+// refundPayment embute o estorno em estornos, no documento do pagamento, com
+// um único update em pipeline. O filtro só casa enquanto o status permite o
+// estorno e o saldo o comporta, então estornos concorrentes do mesmo pagamento
+// não passam do valor pago. O status do pedido é recalculado em seguida a
+// partir de todos os pagamentos dele.
+func (m *MongoDBRepository) refundPayment(refund *model.Refund, orders mongoOrderRefunds) error {
+	if err := refund.Validate(); err != nil {
+		return err
+	}
+
+	collection := m.db.Database(m.database).Collection("pagamentos")
+	ctx := context.Background()
+	ensureID(&refund.ID)
+
+	refunded := bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$valor_estornado", model.Money(0)}}, refund.Amount}}
+	filter := bson.M{
+		"_id":    int64(refund.PaymentID),
+		"status": bson.M{"$in": bson.A{model.PaymentStatusApproved, model.StatusPartiallyRefunded}},
+		"$expr":  bson.M{"$lte": bson.A{refunded, "$valor"}},
+	}
+	// $literal impede que um motivo começando com "$" seja lido como campo.
+	entry := bson.M{"$literal": bson.M{
+		"_id":          int64(refund.ID),
+		"valor":        refund.Amount,
+		"motivo":       refund.Reason,
+		"data_estorno": refund.RefundDate,
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"valor_estornado": refunded,
+			"estornos":        bson.M{"$concatArrays": bson.A{bson.M{"$ifNull": bson.A{"$estornos", bson.A{}}}, bson.A{entry}}},
+		}}},
+		{{Key: "$set", Value: bson.M{"status": bson.M{"$cond": bson.A{
+			bson.M{"$gte": bson.A{"$valor_estornado", "$valor"}},
+			model.StatusRefunded,
+			model.StatusPartiallyRefunded,
+		}}}}},
+	}
+
+	var payment model.Payment
+	err := collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&payment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// O filtro não diz qual condição falhou; o pagamento atual diz.
+		var current model.Payment
+		err := collection.FindOne(ctx, bson.M{"_id": int64(refund.PaymentID)}).Decode(&current)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		if err := current.CheckRefund(*refund); err != nil {
+			return err
+		}
+		return fmt.Errorf("pagamento %d alterado durante o estorno", refund.PaymentID)
+	}
+	if err != nil {
+		return err
+	}
+	refund.OrderID, refund.ClientID = payment.OrderID, payment.ClientID
+
+	total, err := orders.total(ctx, payment.OrderID, payment.ClientID)
+	if err != nil {
+		return err
+	}
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"pedido_id": int64(payment.OrderID)}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "total": bson.M{"$sum": bson.M{"$ifNull": bson.A{"$valor_estornado", model.Money(0)}}}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var result []struct {
+		Total model.Money `bson:"total"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return err
+	}
+	return orders.setStatus(ctx, payment.OrderID, payment.ClientID, model.RefundStatus(total, result[0].Total))
+}
+
+func (m *MongoDBRepository) Refunds(paymentID uint) ([]model.Refund, error) {
+	collection := m.db.Database(m.database).Collection("pagamentos")
+	ctx := context.Background()
+
+	var payment struct {
+		OrderID  int64          `bson:"pedido_id"`
+		ClientID int64          `bson:"id_cliente"`
+		Refunds  []model.Refund `bson:"estornos"`
+	}
+	projection := bson.M{"pedido_id": 1, "id_cliente": 1, "estornos": 1}
+	err := collection.FindOne(ctx, bson.M{"_id": int64(paymentID)}, options.FindOne().SetProjection(projection)).Decode(&payment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range payment.Refunds {
+		refund := &payment.Refunds[i]
+		refund.PaymentID, refund.OrderID, refund.ClientID = paymentID, uint(payment.OrderID), uint(payment.ClientID)
+	}
+	slices.SortStableFunc(payment.Refunds, func(a, b model.Refund) int {
+		return a.RefundDate.Compare(b.RefundDate)
+	})
+	return payment.Refunds, nil
+}
+
+// BatchCreateReview grava as avaliações na coleção avaliacoes com a categoria
+// do produto, que GetAverageRatingByCategory agrupa sem consultar produtos.
+func (m *MongoDBRepository) BatchCreateReview(reviews []model.Review) error {
+	categories, err := validateReviews(reviews, m.productCategories)
+	if err != nil {
+		return err
+	}
+	collection := m.db.Database(m.database).Collection("avaliacoes")
+	ctx := context.Background()
+
+	var documents []any
+	for i := range reviews {
+		review := &reviews[i]
+		ensureID(&review.ID)
+
+		documents = append(documents, bson.M{
+			"_id":            int64(review.ID),
+			"id_produto":     int64(review.ProductID),
+			"id_cliente":     int64(review.ClientID),
+			"nota":           review.Rating,
+			"comentario":     review.Comment,
+			"data_avaliacao": review.CreatedAt,
+			"categoria":      categories[review.ProductID],
+		})
+	}
+
+	return m.insertMany(ctx, collection, documents)
+}
+
+func (m *MongoDBRepository) productCategories(productIDs []uint) (map[uint]string, error) {
+	collection := m.db.Database(m.database).Collection("produtos")
+	ctx := context.Background()
+
+	ids := make([]int64, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = int64(id)
+	}
+	projection := bson.M{"categoria": 1}
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+
+	var products []struct {
+		ID       int64  `bson:"_id"`
+		Category string `bson:"categoria"`
+	}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+
+	categories := make(map[uint]string, len(products))
+	for _, product := range products {
+		categories[uint(product.ID)] = product.Category
+	}
+	return categories, nil
+}
+
+func (m *MongoDBRepository) GetProductReviews(productID uint, beforeID uint, limit int) ([]model.Review, error) {
+	collection := m.db.Database(m.database).Collection("avaliacoes")
+	ctx := context.Background()
+
+	filter := bson.M{"id_produto": int64(productID)}
+	if beforeID != 0 {
+		filter["_id"] = bson.M{"$lt": int64(beforeID)}
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var reviews []model.Review
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+func (m *MongoDBRepository) GetAverageRatingByCategory() ([]model.CategoryRating, error) {
+	collection := m.db.Database(m.database).Collection("avaliacoes")
+	ctx := context.Background()
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":        "$categoria",
+			"avaliacoes": bson.M{"$sum": 1},
+			"soma_notas": bson.M{"$sum": "$nota"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ratings []model.CategoryRating
+	if err := cursor.All(ctx, &ratings); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// BatchCreateShipment grava os envios na coleção envios, com os eventos em um
+// array eventos que AppendTrackingEvents completa.
+func (m *MongoDBRepository) BatchCreateShipment(shipments []model.Shipment) error {
+	if err := validateShipments(shipments, m.orderClients); err != nil {
+		return err
+	}
+	collection := m.db.Database(m.database).Collection("envios")
+	ctx := context.Background()
+
+	var documents []any
+	for i := range shipments {
+		shipment := &shipments[i]
+		ensureID(&shipment.ID)
+
+		documents = append(documents, bson.M{
+			"_id":             int64(shipment.ID),
+			"pedido_id":       int64(shipment.OrderID),
+			"transportadora":  shipment.Carrier,
+			"codigo_rastreio": shipment.TrackingCode,
+			"endereco":        shipment.Address,
+			"data_envio":      shipment.ShippedAt,
+			"eventos":         bson.A{},
+		})
+	}
+
+	return m.insertMany(ctx, collection, documents)
+}
+
+// AppendTrackingEvents acrescenta os eventos de cada envio com um único $push
+// que mantém o array ordenado por data_evento, então a leitura não ordena.
+func (m *MongoDBRepository) AppendTrackingEvents(events []model.TrackingEvent) error {
+	if err := validateTrackingEvents(events, m.shipmentOrders); err != nil {
+		return err
+	}
+	collection := m.db.Database(m.database).Collection("envios")
+	ctx := context.Background()
+
+	byShipment := make(map[uint]bson.A)
+	for _, event := range events {
+		byShipment[event.ShipmentID] = append(byShipment[event.ShipmentID], bson.M{
+			"status":      event.Status,
+			"local":       event.Location,
+			"descricao":   event.Description,
+			"data_evento": event.OccurredAt,
+		})
+	}
+
+	var updates []mongo.WriteModel
+	for _, shipmentID := range slices.Sorted(maps.Keys(byShipment)) {
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": int64(shipmentID)}).
+			SetUpdate(bson.M{"$push": bson.M{"eventos": bson.M{
+				"$each": byShipment[shipmentID],
+				"$sort": bson.M{"data_evento": 1},
+			}}}))
+	}
+
+	batches := slices.Collect(slices.Chunk(updates, m.writes.batchSizeOr(len(updates))))
+	bulkOptions := options.BulkWrite().SetOrdered(!m.writes.Unordered)
+	return forEachParallel(batches, m.writes.parallelism(), func(batch []mongo.WriteModel) error {
+		_, err := collection.BulkWrite(ctx, batch, bulkOptions)
+		return err
+	})
+}
+
+func (m *MongoDBRepository) shipmentOrders(shipmentIDs []uint) (map[uint]uint, error) {
+	collection := m.db.Database(m.database).Collection("envios")
+	ctx := context.Background()
+
+	ids := make([]int64, len(shipmentIDs))
+	for i, id := range shipmentIDs {
+		ids[i] = int64(id)
+	}
+	projection := bson.M{"pedido_id": 1}
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+
+	var shipments []struct {
+		ID      int64 `bson:"_id"`
+		OrderID int64 `bson:"pedido_id"`
+	}
+	if err := cursor.All(ctx, &shipments); err != nil {
+		return nil, err
+	}
+
+	orders := make(map[uint]uint, len(shipments))
+	for _, shipment := range shipments {
+		orders[uint(shipment.ID)] = uint(shipment.OrderID)
+	}
+	return orders, nil
+}
+
+func (m *MongoDBRepository) GetTrackingHistory(orderID uint) ([]model.Shipment, error) {
+	collection := m.db.Database(m.database).Collection("envios")
+	ctx := context.Background()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "data_envio", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"pedido_id": int64(orderID)}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var shipments []model.Shipment
+	if err := cursor.All(ctx, &shipments); err != nil {
+		return nil, err
+	}
+	for i := range shipments {
+		for j := range shipments[i].Events {
+			shipments[i].Events[j].ShipmentID = shipments[i].ID
+		}
+	}
+	return shipments, nil
+}
+
+// BatchCreateAddress acrescenta os endereços ao array enderecos do cliente. O
+// filtro ignora endereços que o cliente já tem, então a gravação pode ser
+// repetida. Um endereço padrão é gravado com um pipeline que, no mesmo update
+// atômico, desmarca o padrão anterior.
+func (m *MongoDBRepository) BatchCreateAddress(addresses []model.Address) error {
+	if err := validateAddresses(addresses, m.existingClients); err != nil {
+		return err
+	}
+	collection := m.db.Database(m.database).Collection("clientes")
+	ctx := context.Background()
+
+	var updates []mongo.WriteModel
+	for i := range addresses {
+		address := &addresses[i]
+		ensureID(&address.ID)
+
+		doc := addressDocument(*address)
+		var update any = bson.M{"$push": bson.M{"enderecos": doc}}
+		if address.Default {
+			update = mongo.Pipeline{{{Key: "$set", Value: bson.M{
+				"enderecos": bson.M{"$concatArrays": bson.A{withDefault(0), bson.A{bson.M{"$literal": doc}}}},
+			}}}}
+		}
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": int64(address.ClientID), "enderecos._id": bson.M{"$ne": int64(address.ID)}}).
+			SetUpdate(update))
+	}
+
+	// Os endereços de um mesmo cliente precisam ser gravados em ordem para que
+	// o último padrão do lote prevaleça, então os lotes não rodam em paralelo.
+	batches := slices.Collect(slices.Chunk(updates, m.writes.batchSizeOr(len(updates))))
+	bulkOptions := options.BulkWrite().SetOrdered(true)
+	for _, batch := range batches {
+		if _, err := collection.BulkWrite(ctx, batch, bulkOptions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addressDocument monta o endereço embutido em clientes.enderecos, sem o
+// cliente, que é o dono do documento.
+func addressDocument(address model.Address) bson.M {
+	return bson.M{
+		"_id":         int64(address.ID),
+		"cep":         address.CEP,
+		"logradouro":  address.Street,
+		"numero":      address.Number,
+		"complemento": address.Complement,
+		"cidade":      address.City,
+		"uf":          address.State,
+		"padrao":      address.Default,
+	}
+}
+
+// withDefault é a expressão de agregação que recalcula padrao em cada endereço
+// do cliente, marcando só o endereço id; com id 0 todos ficam desmarcados.
+func withDefault(id uint) bson.M {
+	return bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$enderecos", bson.A{}}},
+		"as":    "endereco",
+		"in": bson.M{"$mergeObjects": bson.A{"$$endereco", bson.M{
+			"padrao": bson.M{"$eq": bson.A{"$$endereco._id", int64(id)}},
+		}}},
+	}}
+}
+
+// SetDefaultAddress troca o padrão com um único update no documento do
+// cliente, que só casa se o endereço for dele.
+func (m *MongoDBRepository) SetDefaultAddress(clientID uint, addressID uint) error {
+	collection := m.db.Database(m.database).Collection("clientes")
+	ctx := context.Background()
+
+	filter := bson.M{"_id": int64(clientID), "enderecos._id": int64(addressID)}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"enderecos": withDefault(addressID)}}}}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return addressNotFound(clientID, addressID)
+	}
+	return nil
+}
+
+func (m *MongoDBRepository) GetClientAddresses(clientID uint) ([]model.Address, error) {
+	collection := m.db.Database(m.database).Collection("clientes")
+	ctx := context.Background()
+
+	var client struct {
+		Enderecos []model.Address `bson:"enderecos"`
+	}
+	projection := bson.M{"enderecos": 1}
+	err := collection.FindOne(ctx, bson.M{"_id": int64(clientID)}, options.FindOne().SetProjection(projection)).Decode(&client)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range client.Enderecos {
+		client.Enderecos[i].ClientID = clientID
+	}
+	sortAddresses(client.Enderecos)
+	return client.Enderecos, nil
+}
+
+// GetRevenueByState desembrulha os pedidos de cada cliente e procura o
+// endereço de entrega no array enderecos do próprio documento.
+func (m *MongoDBRepository) GetRevenueByState() ([]model.StateRevenue, error) {
+	collection := m.db.Database(m.database).Collection("clientes")
+	return revenueByState(collection, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"pedidos.endereco_id": bson.M{"$exists": true}}}},
+		{{Key: "$unwind", Value: "$pedidos"}},
+		{{Key: "$match", Value: bson.M{
+			"pedidos.endereco_id": bson.M{"$exists": true},
+			"pedidos.status":      bson.M{"$ne": model.OrderStatusCancelled},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"valor_total": "$pedidos.valor_total",
+			"endereco":    orderAddress("$enderecos", "$pedidos.endereco_id"),
+		}}},
+	})
+}
+
+// orderAddress é a expressão que escolhe, no array addresses, o endereço
+// addressID do pedido.
+func orderAddress(addresses, addressID string) bson.M {
+	return bson.M{"$arrayElemAt": bson.A{
+		bson.M{"$filter": bson.M{
+			"input": addresses,
+			"cond":  bson.M{"$eq": bson.A{"$$this._id", addressID}},
+		}},
+		0,
+	}}
+}
+
+// revenueByState completa pipeline, que deve produzir documentos com
+// valor_total e endereco, com o agrupamento por UF.
+func revenueByState(collection *mongo.Collection, pipeline mongo.Pipeline) ([]model.StateRevenue, error) {
+	ctx := context.Background()
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":     "$endereco.uf",
+			"pedidos": bson.M{"$sum": 1},
+			"receita": bson.M{"$sum": "$valor_total"},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	)
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var revenue []model.StateRevenue
+	if err := cursor.All(ctx, &revenue); err != nil {
+		return nil, err
+	}
+	return revenue, nil
+}
+
+func (m *MongoDBRepository) BatchCreateCoupon(coupons []model.Coupon) error {
+	if err := validateRecords(coupons); err != nil {
+		return err
+	}
+	collection := m.db.Database(m.database).Collection("cupons")
+	ctx := context.Background()
+
+	documents := make([]any, len(coupons))
+	for i, coupon := range coupons {
+		if coupon.Categories == nil {
+			coupon.Categories = []string{}
+		}
+		documents[i] = coupon
+	}
+	return m.insertMany(ctx, collection, documents)
+}
+
+func (m *MongoDBRepository) coupon(code string) (model.Coupon, bool, error) {
+	collection := m.db.Database(m.database).Collection("cupons")
+
+	var coupon model.Coupon
+	err := collection.FindOne(context.Background(), bson.M{"_id": code}).Decode(&coupon)
+	if err == mongo.ErrNoDocuments {
+		return model.Coupon{}, false, nil
+	}
+	if err != nil {
+		return model.Coupon{}, false, err
+	}
+	return coupon, true, nil
+}
+
+func (m *MongoDBRepository) CreateOrderWithCoupon(order *model.Order) error {
+	return m.createOrderWithCoupon(order, m.BatchCreateOrder)
+}
+
+// createOrderWithCoupon conta o uso em usos_cupom com um único update atômico,
+// que só soma enquanto o cliente está abaixo do limite, e grava o pedido com
+// write. O upsert de um cliente que já chegou ao limite não encontra o
+// documento e falha ao inserir outro com o mesmo _id; a mesma falha no
+// primeiro uso vem de outro pedido concorrente que criou o documento antes,
+// então o update é repetido uma vez. Sem transação, se a gravação do pedido
+// falha o uso é devolvido.
+func (m *MongoDBRepository) createOrderWithCoupon(order *model.Order, write func([]model.Order) error) error {
+	coupon, err := prepareCouponOrder(order, m.addresses, m.existingClients, m.coupon, m.productCategories)
+	if err != nil {
+		return err
+	}
+	ensureID(&order.ID)
+	collection := m.db.Database(m.database).Collection("usos_cupom")
+	ctx := context.Background()
+
+	id := bson.D{{Key: "codigo", Value: coupon.Code}, {Key: "id_cliente", Value: int64(order.ClientID)}}
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err = collection.UpdateOne(ctx,
+			bson.M{"_id": id, "usos": bson.M{"$lt": coupon.UsageLimit}},
+			bson.M{"$inc": bson.M{"usos": 1}},
+			options.Update().SetUpsert(true))
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return coupon.CheckUses(*order, coupon.UsageLimit)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := write([]model.Order{*order}); err != nil {
+		if _, undo := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"usos": -1}}); undo != nil {
+			return errors.Join(err, undo)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetCouponUsage desembrulha os pedidos com o cupom, encontrados pelo índice
+// multichave em pedidos.codigo_cupom.
+func (m *MongoDBRepository) GetCouponUsage(code string) (model.CouponUsage, error) {
+	collection := m.db.Database(m.database).Collection("clientes")
+	return couponUsage(collection, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"pedidos.codigo_cupom": code}}},
+		{{Key: "$unwind", Value: "$pedidos"}},
+		{{Key: "$match", Value: bson.M{"pedidos.codigo_cupom": code}}},
+		{{Key: "$project", Value: bson.M{"desconto": "$pedidos.desconto"}}},
+	})
+}
+
+// couponUsage completa pipeline, que deve produzir um documento com desconto
+// por pedido, com a soma.
+func couponUsage(collection *mongo.Collection, pipeline mongo.Pipeline) (model.CouponUsage, error) {
+	ctx := context.Background()
+
+	pipeline = append(pipeline, bson.D{{Key: "$group", Value: bson.M{
+		"_id":      nil,
+		"pedidos":  bson.M{"$sum": 1},
+		"desconto": bson.M{"$sum": "$desconto"},
+	}}})
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return model.CouponUsage{}, err
+	}
+
+	var usage []model.CouponUsage
+	if err := cursor.All(ctx, &usage); err != nil || len(usage) == 0 {
+		return model.CouponUsage{}, err
+	}
+	return usage[0], nil
 }
@@ -0,0 +1,261 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	_ TechMarketRepository = &MongoDBReferencedRepository{}
+	_ Refunder             = &MongoDBReferencedRepository{}
+	_ Reviewer             = &MongoDBReferencedRepository{}
+	_ ShipmentTracker      = &MongoDBReferencedRepository{}
+	_ AddressBook          = &MongoDBReferencedRepository{}
+	_ CouponBook           = &MongoDBReferencedRepository{}
+)
+
+// MongoDBReferencedRepository é a variante do MongoDBRepository em que os
+// pedidos ficam na coleção pedidos, referenciando o cliente por id_cliente, em
+// vez de embutidos em clientes.pedidos. Cada lote de pedidos é gravado com um
+// único InsertMany, ao custo de consultas que precisam filtrar ou juntar a
+// coleção de pedidos. Clientes, endereços, produtos e pagamentos são gravados
+// e lidos como na variante embutida, mas em um banco próprio
+// (MONGODB_REFERENCED_DATABASE) para que as duas rodem lado a lado.
+type MongoDBReferencedRepository struct {
+	*MongoDBRepository
+}
+
+func NewMongoDBReferencedRepository() *MongoDBReferencedRepository {
+	config := config.LoadMongoDBConfig()
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(config.URI))
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao conectar com o MongoDB: %v", err))
+	}
+
+	r := &MongoDBReferencedRepository{&MongoDBRepository{
+		db:              client,
+		database:        config.ReferencedDatabase,
+		salesCollection: config.SalesCollection,
+	}}
+	if err := r.createIndexes(context.Background()); err != nil {
+		panic(fmt.Sprintf("Erro ao criar os índices do MongoDB: %v", err))
+	}
+	return r
+}
+
+// createIndexes cria os índices do banco da variante, que não passa pelas
+// migrações de schema. CreateMany não faz nada se os índices já existirem.
+func (r *MongoDBReferencedRepository) createIndexes(ctx context.Context) error {
+	database := r.db.Database(r.database)
+
+	indexes := map[string][]mongo.IndexModel{
+		"clientes":           {{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)}, {Keys: bson.D{{Key: "enderecos._id", Value: 1}}}},
+		"produtos":           {{Keys: bson.D{{Key: "categoria", Value: 1}, {Key: "preco", Value: 1}}}},
+		"pedidos":            {{Keys: bson.D{{Key: "id_cliente", Value: 1}, {Key: "status", Value: 1}}}, {Keys: bson.D{{Key: "codigo_cupom", Value: 1}}, Options: options.Index().SetSparse(true)}},
+		"pagamentos":         {{Keys: bson.D{{Key: "tipo", Value: 1}, {Key: "data_pagamento", Value: -1}}}, {Keys: bson.D{{Key: "pedido_id", Value: 1}}}},
+		"vendas_por_produto": {{Keys: bson.D{{Key: "total_vendido", Value: -1}, {Key: "_id", Value: 1}}}},
+		"avaliacoes":         {{Keys: bson.D{{Key: "id_produto", Value: 1}, {Key: "_id", Value: -1}}}},
+		"envios":             {{Keys: bson.D{{Key: "pedido_id", Value: 1}, {Key: "data_envio", Value: 1}}}},
+	}
+	for collection, models := range indexes {
+		if _, err := database.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MongoDBReferencedRepository) BatchCreateClient(clients []model.Client) error {
+	if err := validateRecords(clients); err != nil {
+		return err
+	}
+	collection := r.db.Database(r.database).Collection("clientes")
+	ctx := context.Background()
+
+	var documents []any
+	for i := range clients {
+		client := &clients[i]
+		ensureID(&client.ID)
+		documents = append(documents, clientDocument(*client))
+	}
+
+	return r.insertMany(ctx, collection, documents)
+}
+
+func (r *MongoDBReferencedRepository) BatchCreateOrder(orders []model.Order) error {
+	if _, err := validateOrders(orders, r.existingClients, r.addresses); err != nil {
+		return err
+	}
+	collection := r.db.Database(r.database).Collection("pedidos")
+	ctx := context.Background()
+
+	var documents []any
+	for i := range orders {
+		order := &orders[i]
+		ensureID(&order.ID)
+
+		doc := orderDocument(*order)
+		doc["_id"] = int64(order.ID)
+		doc["id_cliente"] = int64(order.ClientID)
+		documents = append(documents, doc)
+	}
+
+	if err := r.insertMany(ctx, collection, documents); err != nil {
+		return err
+	}
+
+	if r.salesCollection {
+		return r.incrementSales(ctx, orders)
+	}
+	return nil
+}
+
+func (r *MongoDBReferencedRepository) BatchCreatePayment(payments []model.Payment) error {
+	if err := validatePayments(payments, r.orderClients); err != nil {
+		return err
+	}
+	return r.insertPayments(payments)
+}
+
+func (r *MongoDBReferencedRepository) orderClients(orderIDs []uint) (map[uint]uint, error) {
+	collection := r.db.Database(r.database).Collection("pedidos")
+
+	ids := make(bson.A, len(orderIDs))
+	for i, id := range orderIDs {
+		ids[i] = int64(id)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": bson.M{"$in": ids}}}},
+		{{Key: "$project", Value: bson.M{"id_cliente": 1}}},
+	}
+	return mongoOrderClients(context.Background(), collection, pipeline)
+}
+
+func (r *MongoDBReferencedRepository) RefundPayment(refund *model.Refund) error {
+	orders := r.db.Database(r.database).Collection("pedidos")
+
+	return r.refundPayment(refund, mongoOrderRefunds{
+		total: func(ctx context.Context, orderID, _ uint) (model.Money, error) {
+			var order struct {
+				Total model.Money `bson:"valor_total"`
+			}
+			err := orders.FindOne(ctx, bson.M{"_id": int64(orderID)}).Decode(&order)
+			return order.Total, err
+		},
+		setStatus: func(ctx context.Context, orderID, _ uint, status string) error {
+			_, err := orders.UpdateOne(ctx, bson.M{"_id": int64(orderID)}, bson.M{"$set": bson.M{"status": status}})
+			return err
+		},
+	})
+}
+
+func (r *MongoDBReferencedRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
+	collection := r.db.Database(r.database).Collection("pedidos")
+	ctx := context.Background()
+
+	filter := bson.M{"id_cliente": int64(clientID), "status": model.OrderStatusDelivered}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var pedidos []struct {
+		PedidoID    uint        `bson:"_id"`
+		EnderecoID  *uint       `bson:"endereco_id"`
+		DataPedido  time.Time   `bson:"data_pedido"`
+		Status      string      `bson:"status"`
+		ValorTotal  model.Money `bson:"valor_total"`
+		CodigoCupom string      `bson:"codigo_cupom"`
+		Desconto    model.Money `bson:"desconto"`
+		Itens       []struct {
+			NomeProduto   string      `bson:"nome_produto"`
+			ProdutoID     uint        `bson:"produto_id"`
+			Quantidade    int         `bson:"quantidade"`
+			PrecoUnitario model.Money `bson:"preco_unitario"`
+		} `bson:"itens"`
+	}
+	if err := cursor.All(ctx, &pedidos); err != nil {
+		return nil, err
+	}
+
+	var orders []model.Order
+	for _, pedido := range pedidos {
+		var itens []model.OrderItem
+		for _, item := range pedido.Itens {
+			itens = append(itens, model.OrderItem{
+				OrderID:   pedido.PedidoID,
+				ProductID: item.ProdutoID,
+				Quantity:  item.Quantidade,
+				Product: model.Product{
+					ID:    item.ProdutoID,
+					Name:  item.NomeProduto,
+					Price: item.PrecoUnitario,
+				},
+			})
+		}
+
+		orders = append(orders, model.Order{
+			ID:         pedido.PedidoID,
+			AddressID:  pedido.EnderecoID,
+			OrderDate:  pedido.DataPedido,
+			Status:     pedido.Status,
+			TotalValue: pedido.ValorTotal,
+			CouponCode: pedido.CodigoCupom,
+			Discount:   pedido.Desconto,
+			ClientID:   clientID,
+			Itens:      itens,
+		})
+	}
+
+	return orders, nil
+}
+
+func (r *MongoDBReferencedRepository) Get5MostSoldProducts() ([]model.Product, error) {
+	return r.mostSoldProducts("pedidos", mongo.Pipeline{
+		{{Key: "$unwind", Value: "$itens"}},
+		{{Key: "$group", Value: bson.M{"_id": "$itens.produto_id", "total_vendido": bson.M{"$sum": "$itens.quantidade"}}}},
+	})
+}
+
+// GetRevenueByState junta cada pedido ao cliente dono do endereço de entrega,
+// pelo índice em clientes.enderecos._id, e escolhe o endereço no array.
+func (r *MongoDBReferencedRepository) GetRevenueByState() ([]model.StateRevenue, error) {
+	collection := r.db.Database(r.database).Collection("pedidos")
+	return revenueByState(collection, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"endereco_id": bson.M{"$exists": true},
+			"status":      bson.M{"$ne": model.OrderStatusCancelled},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "clientes",
+			"localField":   "endereco_id",
+			"foreignField": "enderecos._id",
+			"as":           "cliente",
+		}}},
+		{{Key: "$unwind", Value: "$cliente"}},
+		{{Key: "$project", Value: bson.M{
+			"valor_total": 1,
+			"endereco":    orderAddress("$cliente.enderecos", "$endereco_id"),
+		}}},
+	})
+}
+
+func (r *MongoDBReferencedRepository) CreateOrderWithCoupon(order *model.Order) error {
+	return r.createOrderWithCoupon(order, r.BatchCreateOrder)
+}
+
+func (r *MongoDBReferencedRepository) GetCouponUsage(code string) (model.CouponUsage, error) {
+	collection := r.db.Database(r.database).Collection("pedidos")
+	return couponUsage(collection, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"codigo_cupom": code}}},
+	})
+}
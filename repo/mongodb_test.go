@@ -0,0 +1,48 @@
+package repo_test
+
+import (
+	"os"
+	"reflect"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/conformance"
+	"testing"
+)
+
+// Exige um banco recém-criado pelo docker-compose, já que a suíte grava IDs fixos.
+// A suíte verifica a agregação feita na hora; a coleção pré-agregada, mantida
+// durante a carga, precisa chegar aos mesmos 5 mais vendidos.
+func TestMongoDBRepositoryConformance(t *testing.T) {
+	if os.Getenv("MONGODB_URI") == "" {
+		t.Skip("MONGODB_URI não definido")
+	}
+	t.Setenv("MONGODB_SALES_COLLECTION", "true")
+
+	conformance.RunAfter(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewMongoDBRepository()
+	}, func(t *testing.T, loaded repo.TechMarketRepository) repo.TechMarketRepository {
+		mongo := loaded.(*repo.MongoDBRepository)
+
+		onTheFly, err := mongo.Get5MostSoldProducts()
+		if err != nil {
+			t.Fatalf("Get5MostSoldProducts: %v", err)
+		}
+		precomputed, err := mongo.Precomputed().Get5MostSoldProducts()
+		if err != nil {
+			t.Fatalf("Get5MostSoldProducts pré-agregado: %v", err)
+		}
+		if !reflect.DeepEqual(onTheFly, precomputed) {
+			t.Errorf("pré-agregado = %v, agregação = %v", precomputed, onTheFly)
+		}
+		return loaded
+	})
+}
+
+// Usa o banco MONGODB_REFERENCED_DATABASE, que também precisa estar vazio.
+func TestMongoDBReferencedRepositoryConformance(t *testing.T) {
+	if os.Getenv("MONGODB_URI") == "" {
+		t.Skip("MONGODB_URI não definido")
+	}
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewMongoDBReferencedRepository()
+	})
+}
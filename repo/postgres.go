@@ -14,87 +14,52 @@ import (
 var _ TechMarketRepository = &PostgresRepository{}
 
 type PostgresRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	writes WriteOptions
 }
 
 func (p *PostgresRepository) BatchCreateClient(clients []model.Client) error {
-	return p.db.Transaction(func(tx *gorm.DB) error {
-		batchSize := 100
-		for i := 0; i < len(clients); i += batchSize {
-			end := min(i+batchSize, len(clients))
-
-			if err := tx.Table("cliente").Create(clients[i:end]).Error; err != nil {
-				return err
-			}
-		}
-		return nil
-	})
+	if err := validateRecords(clients); err != nil {
+		return err
+	}
+	return gormWrite(p.db, p.writes, 100, "cliente", clients)
 }
 
 func (p *PostgresRepository) BatchCreateOrder(orders []model.Order) error {
-	return p.db.Transaction(func(tx *gorm.DB) error {
-		batchSize := 100
-		for i := 0; i < len(orders); i += batchSize {
-			end := min(i+batchSize, len(orders))
-
-			if err := tx.Table("pedido").Create(orders[i:end]).Error; err != nil {
-				return err
-			}
-		}
-		return nil
-	})
+	if _, err := validateOrders(orders, gormExistingClients(p.db, false), gormAddresses(p.db)); err != nil {
+		return err
+	}
+	return gormWrite(p.db, p.writes, 100, "pedido", orders)
 }
 
 func (p *PostgresRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
-	return p.db.Transaction(func(tx *gorm.DB) error {
-		batchSize := 100
-		for i := 0; i < len(orderItems); i += batchSize {
-			end := min(i+batchSize, len(orderItems))
-
-			items := make([]map[string]any, len(orderItems[i:end]))
-			for j, item := range orderItems[i:end] {
-				items[j] = map[string]any{
-					"id_pedido":      item.OrderID,
-					"id_produto":     item.ProductID,
-					"quantidade":     item.Quantity,
-					"preco_unitario": item.Product.Price,
-				}
-			}
-
-			if err := tx.Table("item_pedido").Create(items).Error; err != nil {
-				return err
-			}
+	if err := validateRecords(orderItems); err != nil {
+		return err
+	}
+	items := make([]map[string]any, len(orderItems))
+	for i, item := range orderItems {
+		items[i] = map[string]any{
+			"id_pedido":      item.OrderID,
+			"id_produto":     item.ProductID,
+			"quantidade":     item.Quantity,
+			"preco_unitario": item.Product.Price,
 		}
-		return nil
-	})
+	}
+	return gormWrite(p.db, p.writes, 100, "item_pedido", items)
 }
 
 func (p *PostgresRepository) BatchCreatePayment(payments []model.Payment) error {
-	return p.db.Transaction(func(tx *gorm.DB) error {
-		batchSize := 100
-		for i := 0; i < len(payments); i += batchSize {
-			end := min(i+batchSize, len(payments))
-
-			if err := tx.Table("pagamento").Create(payments[i:end]).Error; err != nil {
-				return err
-			}
-		}
-		return nil
-	})
+	if err := validatePayments(payments, gormOrderClients(p.db)); err != nil {
+		return err
+	}
+	return gormWrite(p.db, p.writes, 100, "pagamento", payments)
 }
 
 func (p *PostgresRepository) BatchCreateProduct(products []model.Product) error {
-	return p.db.Transaction(func(tx *gorm.DB) error {
-		batchSize := 100
-		for i := 0; i < len(products); i += batchSize {
-			end := min(i+batchSize, len(products))
-
-			if err := tx.Table("produto").Create(products[i:end]).Error; err != nil {
-				return err
-			}
-		}
-		return nil
-	})
+	if err := validateRecords(products); err != nil {
+		return err
+	}
+	return gormWrite(p.db, p.writes, 100, "produto", products)
 }
 
 func (p *PostgresRepository) GetClientByEmail(email string) (model.Client, error) {
@@ -116,9 +81,9 @@ func (p *PostgresRepository) GetProductByCategory(category string) ([]model.Prod
 }
 
 func (p *PostgresRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
-	query := `SELECT * FROM pedido WHERE id_cliente = ? AND status = 'entregue'`
+	query := `SELECT * FROM pedido WHERE id_cliente = ? AND status = ?`
 	var orders []model.Order
-	if err := p.db.Raw(query, clientID).Scan(&orders).Error; err != nil {
+	if err := p.db.Raw(query, clientID, model.OrderStatusDelivered).Scan(&orders).Error; err != nil {
 		return nil, err
 	}
 	return orders, nil
@@ -141,32 +106,43 @@ func (p *PostgresRepository) Get5MostSoldProducts() ([]model.Product, error) {
 }
 
 func (p *PostgresRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
-	query := `SELECT * FROM pagamento WHERE tipo = 'pix' AND data_pagamento >= ? AND data_pagamento <= ?`
+	query := `SELECT * FROM pagamento WHERE tipo = ? AND data_pagamento >= ? AND data_pagamento <= ?`
 	var payments []model.Payment
-	if err := p.db.Raw(query, time.Now().AddDate(0, -1, 0), time.Now()).Scan(&payments).Error; err != nil {
+	if err := p.db.Raw(query, model.PaymentTypePix, time.Now().AddDate(0, -1, 0), time.Now()).Scan(&payments).Error; err != nil {
 		return nil, err
 	}
 	return payments, nil
 }
 
-func (p *PostgresRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (float64, error) {
-	query := `SELECT SUM(valor_total) FROM pagamento WHERE id_cliente = ? AND data_pagamento >= ? AND data_pagamento <= ?`
-	var total float64
+func (p *PostgresRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	query := `SELECT COALESCE(SUM(valor - valor_estornado), 0) FROM pagamento WHERE id_cliente = ? AND data_pagamento >= ? AND data_pagamento <= ?`
+	var total model.Money
 	if err := p.db.Raw(query, clientID, startDate, endDate).Scan(&total).Error; err != nil {
 		return 0, err
 	}
 	return total, nil
 }
 
+// RetrySafeWrites vale enquanto não há TransactionPerBatch.
+func (p *PostgresRepository) RetrySafeWrites() bool {
+	return !p.writes.TransactionPerBatch
+}
+
+func (p *PostgresRepository) SetWriteOptions(opts WriteOptions) {
+	p.writes = opts
+}
+
 func NewPostgresRepository() *PostgresRepository {
 	config := config.LoadPostgresConfig()
+	return &PostgresRepository{db: openPostgres(config.URI)}
+}
 
-	db, err := gorm.Open(postgres.Open(config.URI), &gorm.Config{
+func openPostgres(uri string) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(uri), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
 		panic(fmt.Sprintf("Erro ao conectar com o PostgreSQL: %v", err))
 	}
-
-	return &PostgresRepository{db: db}
+	return db
 }
@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"slices"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+var _ TechMarketRepository = &PostgresCopyRepository{}
+
+// PostgresCopyRepository grava cada Batch* com um único COPY (pgx CopyFrom) em
+// vez dos INSERTs em lotes de 100 do GORM; as consultas são as do
+// PostgresRepository. Como o COPY não devolve IDs gerados pelo banco,
+// registros sem ID recebem um do gerador de IDs antes da gravação. Um COPY é
+// tudo ou nada, então as gravações continuam podendo ser repetidas. Com
+// WriteOptions.BatchSize a chamada é dividida em vários COPY, na mesma
+// transação ou, com TransactionPerBatch, cada um na sua.
+type PostgresCopyRepository struct {
+	*PostgresRepository
+}
+
+func NewPostgresCopyRepository() *PostgresCopyRepository {
+	config := config.LoadPostgresConfig()
+	return &PostgresCopyRepository{&PostgresRepository{db: openPostgres(config.CopyURI)}}
+}
+
+func (p *PostgresCopyRepository) BatchCreateClient(clients []model.Client) error {
+	if err := validateRecords(clients); err != nil {
+		return err
+	}
+	rows := make([][]any, len(clients))
+	for i := range clients {
+		client := &clients[i]
+		ensureID(&client.ID)
+		rows[i] = []any{int64(client.ID), client.Nome, client.Email, client.Phone, client.CreatedAt, client.CPF}
+	}
+	return p.copyFrom("cliente", []string{"id", "nome", "email", "telefone", "data_cadastro", "cpf"}, rows)
+}
+
+func (p *PostgresCopyRepository) BatchCreateProduct(products []model.Product) error {
+	if err := validateRecords(products); err != nil {
+		return err
+	}
+	rows := make([][]any, len(products))
+	for i := range products {
+		product := &products[i]
+		ensureID(&product.ID)
+		rows[i] = []any{int64(product.ID), product.Name, product.Category, product.Price, product.Stock}
+	}
+	return p.copyFrom("produto", []string{"id", "nome", "categoria", "preco", "estoque"}, rows)
+}
+
+func (p *PostgresCopyRepository) BatchCreateOrder(orders []model.Order) error {
+	if _, err := validateOrders(orders, gormExistingClients(p.db, false), gormAddresses(p.db)); err != nil {
+		return err
+	}
+	rows := make([][]any, len(orders))
+	for i := range orders {
+		order := &orders[i]
+		ensureID(&order.ID)
+		var addressID any
+		if order.AddressID != nil {
+			addressID = int64(*order.AddressID)
+		}
+		rows[i] = []any{int64(order.ID), int64(order.ClientID), addressID, order.OrderDate, order.Status, order.TotalValue, order.CouponCode, order.Discount}
+	}
+	return p.copyFrom("pedido", []string{"id", "id_cliente", "id_endereco", "data_pedido", "status", "valor_total", "codigo_cupom", "desconto"}, rows)
+}
+
+func (p *PostgresCopyRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
+	if err := validateRecords(orderItems); err != nil {
+		return err
+	}
+	rows := make([][]any, len(orderItems))
+	for i, item := range orderItems {
+		rows[i] = []any{int64(item.OrderID), int64(item.ProductID), item.Quantity, item.Product.Price}
+	}
+	return p.copyFrom("item_pedido", []string{"id_pedido", "id_produto", "quantidade", "preco_unitario"}, rows)
+}
+
+func (p *PostgresCopyRepository) BatchCreatePayment(payments []model.Payment) error {
+	if err := validatePayments(payments, gormOrderClients(p.db)); err != nil {
+		return err
+	}
+	rows := make([][]any, len(payments))
+	for i := range payments {
+		payment := &payments[i]
+		ensureID(&payment.ID)
+		rows[i] = []any{int64(payment.ID), int64(payment.OrderID), int64(payment.ClientID), payment.Type, payment.Status, payment.Amount, payment.Installments, payment.Refunded, payment.PaymentDate}
+	}
+	return p.copyFrom("pagamento", []string{"id", "id_pedido", "id_cliente", "tipo", "status", "valor", "parcelas", "valor_estornado", "data_pagamento"}, rows)
+}
+
+// copyFrom executa o COPY na conexão pgx usada por baixo do GORM, um por lote
+// de WriteOptions.BatchSize linhas.
+func (p *PostgresCopyRepository) copyFrom(table string, columns []string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	batches := slices.Collect(slices.Chunk(rows, p.writes.batchSizeOr(len(rows))))
+
+	if !p.writes.TransactionPerBatch {
+		return p.withPgxConn(func(ctx context.Context, conn *pgx.Conn) error {
+			tx, err := conn.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback(ctx)
+
+			for _, batch := range batches {
+				if _, err := tx.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(batch)); err != nil {
+					return err
+				}
+			}
+			return tx.Commit(ctx)
+		})
+	}
+
+	return forEachParallel(batches, p.writes.parallelism(), func(batch [][]any) error {
+		return p.withPgxConn(func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(batch))
+			return err
+		})
+	})
+}
+
+// withPgxConn reserva uma conexão do pool do GORM e chama fn com a conexão pgx
+// por baixo dela.
+func (p *PostgresCopyRepository) withPgxConn(fn func(ctx context.Context, conn *pgx.Conn) error) error {
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		return fn(ctx, driverConn.(*stdlib.Conn).Conn())
+	})
+}
@@ -0,0 +1,28 @@
+package repo_test
+
+import (
+	"os"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/conformance"
+	"testing"
+)
+
+// Exige um banco recém-criado pelo docker-compose, já que a suíte grava IDs fixos.
+func TestPostgresRepositoryConformance(t *testing.T) {
+	if os.Getenv("POSTGRES_URI") == "" {
+		t.Skip("POSTGRES_URI não definido")
+	}
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewPostgresRepository()
+	})
+}
+
+// Grava pelo COPY em POSTGRES_COPY_URI, que precisa ser outro banco recém-criado.
+func TestPostgresCopyRepositoryConformance(t *testing.T) {
+	if os.Getenv("POSTGRES_COPY_URI") == "" {
+		t.Skip("POSTGRES_COPY_URI não definido")
+	}
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+		return repo.NewPostgresCopyRepository()
+	})
+}
@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"techmarket_showcase/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	_ Refunder = &PostgresRepository{}
+	_ Refunder = &SQLiteRepository{}
+)
+
+// gormRefund aplica o estorno em uma transação. No PostgreSQL o pagamento e o
+// pedido são lidos com FOR UPDATE, então estornos concorrentes do mesmo
+// pagamento esperam um pelo outro e não passam do saldo; no SQLite as
+// transações começam com BEGIN IMMEDIATE (_txlock=immediate) e esperam a trava
+// de escrita antes da primeira leitura.
+func gormRefund(db *gorm.DB, forUpdate bool, refund *model.Refund) error {
+	if err := refund.Validate(); err != nil {
+		return err
+	}
+
+	lock := ""
+	if forUpdate {
+		lock = " FOR UPDATE"
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var payment model.Payment
+		if err := tx.Raw(`SELECT * FROM pagamento WHERE id = ?`+lock, refund.PaymentID).Scan(&payment).Error; err != nil {
+			return err
+		}
+		if err := payment.CheckRefund(*refund); err != nil {
+			return err
+		}
+
+		refund.OrderID, refund.ClientID = payment.OrderID, payment.ClientID
+		if err := tx.Table("estorno").Create(refund).Error; err != nil {
+			return err
+		}
+
+		payment.Refunded += refund.Amount
+		err := tx.Exec(`UPDATE pagamento SET valor_estornado = ?, status = ? WHERE id = ?`,
+			payment.Refunded, model.RefundStatus(payment.Amount, payment.Refunded), payment.ID).Error
+		if err != nil {
+			return err
+		}
+
+		var total, refunded model.Money
+		if err := tx.Raw(`SELECT valor_total FROM pedido WHERE id = ?`+lock, payment.OrderID).Scan(&total).Error; err != nil {
+			return err
+		}
+		if err := tx.Raw(`SELECT COALESCE(SUM(valor_estornado), 0) FROM pagamento WHERE id_pedido = ?`, payment.OrderID).Scan(&refunded).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE pedido SET status = ? WHERE id = ?`, model.RefundStatus(total, refunded), payment.OrderID).Error
+	})
+}
+
+func gormRefunds(db *gorm.DB, paymentID uint) ([]model.Refund, error) {
+	query := `SELECT * FROM estorno WHERE id_pagamento = ? ORDER BY data_estorno, id`
+	var refunds []model.Refund
+	if err := db.Raw(query, paymentID).Scan(&refunds).Error; err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+func (p *PostgresRepository) RefundPayment(refund *model.Refund) error {
+	return gormRefund(p.db, true, refund)
+}
+
+func (p *PostgresRepository) Refunds(paymentID uint) ([]model.Refund, error) {
+	return gormRefunds(p.db, paymentID)
+}
+
+func (s *SQLiteRepository) RefundPayment(refund *model.Refund) error {
+	return gormRefund(s.db, false, refund)
+}
+
+func (s *SQLiteRepository) Refunds(paymentID uint) ([]model.Refund, error) {
+	return gormRefunds(s.db, paymentID)
+}
@@ -0,0 +1,278 @@
+package repo
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"syscall"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ TechMarketRepository = &ResilientRepository{}
+
+var (
+	// ErrTransient marca erros que podem ser repetidos com segurança.
+	ErrTransient = errors.New("erro transitório")
+	// ErrCircuitOpen é retornado sem chamar o banco enquanto o circuito está aberto.
+	ErrCircuitOpen = errors.New("circuito aberto")
+)
+
+// RetrySafeWriter é implementado pelos repositórios cujos métodos Batch* são
+// tudo ou nada (uma transação por chamada), e por isso podem ser repetidos sem
+// duplicar registros.
+type RetrySafeWriter interface {
+	RetrySafeWrites() bool
+}
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "fechado"
+	BreakerOpen     BreakerState = "aberto"
+	BreakerHalfOpen BreakerState = "semiaberto"
+)
+
+type ResilienceStats struct {
+	Calls    int
+	Retries  int
+	Failures int
+	Rejected int
+	State    BreakerState
+}
+
+// ResilientRepository repete chamadas que falham com erros transitórios usando
+// backoff exponencial com jitter e abre um circuito após falhas consecutivas.
+// Consultas são sempre repetidas; escritas só quando o repositório envolvido
+// implementa RetrySafeWriter.
+type ResilientRepository struct {
+	inner      TechMarketRepository
+	config     config.RetryConfig
+	safeWrites bool
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	openUntil time.Time
+	probing   bool
+	stats     ResilienceStats
+}
+
+func NewResilientRepository(inner TechMarketRepository) *ResilientRepository {
+	safe, ok := inner.(RetrySafeWriter)
+
+	return &ResilientRepository{
+		inner:      inner,
+		config:     config.LoadRetryConfig(),
+		safeWrites: ok && safe.RetrySafeWrites(),
+		state:      BreakerClosed,
+	}
+}
+
+func (r *ResilientRepository) Stats() ResilienceStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.State = r.state
+	if r.state == BreakerOpen && !time.Now().Before(r.openUntil) {
+		stats.State = BreakerHalfOpen
+	}
+	return stats
+}
+
+// IsTransient informa se err vem de uma falha passageira (timeout, conexão
+// perdida, nó indisponível) em qualquer um dos drivers usados pelo projeto.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var (
+		writeTimeout *gocql.RequestErrWriteTimeout
+		readTimeout  *gocql.RequestErrReadTimeout
+		unavailable  *gocql.RequestErrUnavailable
+	)
+
+	return errors.Is(err, ErrTransient) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		pgconn.SafeToRetry(err) ||
+		pgconn.Timeout(err) ||
+		mongo.IsTimeout(err) ||
+		mongo.IsNetworkError(err) ||
+		errors.Is(err, gocql.ErrTimeoutNoResponse) ||
+		errors.Is(err, gocql.ErrConnectionClosed) ||
+		errors.Is(err, gocql.ErrNoConnections) ||
+		errors.As(err, &writeTimeout) ||
+		errors.As(err, &readTimeout) ||
+		errors.As(err, &unavailable)
+}
+
+func (r *ResilientRepository) allow() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Calls++
+	switch r.state {
+	case BreakerOpen:
+		if time.Now().Before(r.openUntil) {
+			r.stats.Rejected++
+			return false
+		}
+		r.state = BreakerHalfOpen
+		r.probing = true
+		return true
+	case BreakerHalfOpen:
+		// Apenas uma chamada de teste por vez enquanto o circuito está semiaberto.
+		if r.probing {
+			r.stats.Rejected++
+			return false
+		}
+		r.probing = true
+		return true
+	}
+	return true
+}
+
+func (r *ResilientRepository) report(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.probing = false
+	if !IsTransient(err) {
+		r.state = BreakerClosed
+		r.failures = 0
+		return
+	}
+
+	r.stats.Failures++
+	r.failures++
+	if r.state == BreakerHalfOpen || r.failures >= r.config.BreakerThreshold {
+		r.state = BreakerOpen
+		r.openUntil = time.Now().Add(r.config.BreakerCooldown)
+	}
+}
+
+func (r *ResilientRepository) backoff(attempt int) time.Duration {
+	delay := min(r.config.BaseDelay<<attempt, r.config.MaxDelay)
+	if delay <= 0 {
+		return 0
+	}
+	return rand.N(delay + 1)
+}
+
+func (r *ResilientRepository) do(retry bool, call func() error) error {
+	if !r.allow() {
+		return ErrCircuitOpen
+	}
+
+	var err error
+	for attempt := 0; attempt < r.config.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			r.mu.Lock()
+			r.stats.Retries++
+			r.mu.Unlock()
+			time.Sleep(r.backoff(attempt - 1))
+		}
+
+		err = call()
+		if !retry || !IsTransient(err) {
+			break
+		}
+	}
+
+	r.report(err)
+	return err
+}
+
+func (r *ResilientRepository) BatchCreateClient(clients []model.Client) error {
+	return r.do(r.safeWrites, func() error { return r.inner.BatchCreateClient(clients) })
+}
+
+func (r *ResilientRepository) BatchCreateProduct(products []model.Product) error {
+	return r.do(r.safeWrites, func() error { return r.inner.BatchCreateProduct(products) })
+}
+
+func (r *ResilientRepository) BatchCreateOrder(orders []model.Order) error {
+	return r.do(r.safeWrites, func() error { return r.inner.BatchCreateOrder(orders) })
+}
+
+func (r *ResilientRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
+	return r.do(r.safeWrites, func() error { return r.inner.BatchCreateOrderItem(orderItems) })
+}
+
+func (r *ResilientRepository) BatchCreatePayment(payments []model.Payment) error {
+	return r.do(r.safeWrites, func() error { return r.inner.BatchCreatePayment(payments) })
+}
+
+func (r *ResilientRepository) GetClientByEmail(email string) (model.Client, error) {
+	var client model.Client
+	err := r.do(true, func() (err error) {
+		client, err = r.inner.GetClientByEmail(email)
+		return err
+	})
+	return client, err
+}
+
+func (r *ResilientRepository) GetProductByCategory(category string) ([]model.Product, error) {
+	var products []model.Product
+	err := r.do(true, func() (err error) {
+		products, err = r.inner.GetProductByCategory(category)
+		return err
+	})
+	return products, err
+}
+
+func (r *ResilientRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
+	var orders []model.Order
+	err := r.do(true, func() (err error) {
+		orders, err = r.inner.GetDeliveredOrdersByClient(clientID)
+		return err
+	})
+	return orders, err
+}
+
+func (r *ResilientRepository) Get5MostSoldProducts() ([]model.Product, error) {
+	var products []model.Product
+	err := r.do(true, func() (err error) {
+		products, err = r.inner.Get5MostSoldProducts()
+		return err
+	})
+	return products, err
+}
+
+func (r *ResilientRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
+	var payments []model.Payment
+	err := r.do(true, func() (err error) {
+		payments, err = r.inner.GetLastMonthPixPayments()
+		return err
+	})
+	return payments, err
+}
+
+func (r *ResilientRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	var total model.Money
+	err := r.do(true, func() (err error) {
+		total, err = r.inner.GetClientTotalSpentByPeriod(clientID, startDate, endDate)
+		return err
+	})
+	return total, err
+}
@@ -0,0 +1,152 @@
+package repo_test
+
+import (
+	"errors"
+	"fmt"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"techmarket_showcase/repo"
+	"testing"
+)
+
+// flakyRepository falha com erro transitório nas primeiras chamadas.
+type flakyRepository struct {
+	repo.TechMarketRepository
+	failures int
+	calls    int
+	safe     bool
+}
+
+func (f *flakyRepository) RetrySafeWrites() bool { return f.safe }
+
+func (f *flakyRepository) fail() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return fmt.Errorf("consulta %d: %w", f.calls, repo.ErrTransient)
+	}
+	return nil
+}
+
+func (f *flakyRepository) BatchCreateClient(clients []model.Client) error {
+	return f.fail()
+}
+
+func (f *flakyRepository) GetClientByEmail(email string) (model.Client, error) {
+	if err := f.fail(); err != nil {
+		return model.Client{}, err
+	}
+	return model.Client{ID: 1, Email: email}, nil
+}
+
+func setRetryEnv(t *testing.T) {
+	t.Setenv("RETRY_MAX_ATTEMPTS", "3")
+	t.Setenv("RETRY_BASE_DELAY_MS", "1")
+	t.Setenv("RETRY_MAX_DELAY_MS", "2")
+	t.Setenv("BREAKER_FAILURE_THRESHOLD", "2")
+	t.Setenv("BREAKER_COOLDOWN_SECONDS", "60")
+}
+
+func TestResilientRepositoryRetriesReads(t *testing.T) {
+	setRetryEnv(t)
+	inner := &flakyRepository{failures: 2}
+	r := repo.NewResilientRepository(inner)
+
+	client, err := r.GetClientByEmail("ana@techmarket.com")
+	if err != nil || client.ID != 1 {
+		t.Fatalf("GetClientByEmail = %+v, %v", client, err)
+	}
+	if stats := r.Stats(); stats.Retries != 2 || stats.Failures != 0 || stats.State != repo.BreakerClosed {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestResilientRepositoryWritesOnlyWhenSafe(t *testing.T) {
+	setRetryEnv(t)
+
+	unsafe := &flakyRepository{failures: 1}
+	if err := repo.NewResilientRepository(unsafe).BatchCreateClient(nil); !errors.Is(err, repo.ErrTransient) {
+		t.Errorf("escrita não segura: err = %v, esperado erro transitório", err)
+	}
+	if unsafe.calls != 1 {
+		t.Errorf("escrita não segura executada %d vezes, esperado 1", unsafe.calls)
+	}
+
+	safe := &flakyRepository{failures: 1, safe: true}
+	if err := repo.NewResilientRepository(safe).BatchCreateClient(nil); err != nil {
+		t.Errorf("escrita segura: err = %v", err)
+	}
+	if safe.calls != 2 {
+		t.Errorf("escrita segura executada %d vezes, esperado 2", safe.calls)
+	}
+}
+
+// Com RETRY_MAX_ATTEMPTS zero ou inválido a chamada ainda é feita uma vez.
+func TestResilientRepositoryCallsAtLeastOnce(t *testing.T) {
+	setRetryEnv(t)
+	for _, attempts := range []string{"0", "-1", "x"} {
+		t.Setenv("RETRY_MAX_ATTEMPTS", attempts)
+		inner := &flakyRepository{failures: 1, safe: true}
+		if err := repo.NewResilientRepository(inner).BatchCreateClient(nil); !errors.Is(err, repo.ErrTransient) {
+			t.Errorf("RETRY_MAX_ATTEMPTS=%s: err = %v, esperado erro transitório", attempts, err)
+		}
+		if inner.calls != 1 {
+			t.Errorf("RETRY_MAX_ATTEMPTS=%s: escrita executada %d vezes, esperado 1", attempts, inner.calls)
+		}
+	}
+}
+
+// O FaultyRepository fica entre o repositório e o ResilientRepository, então
+// precisa repassar RetrySafeWrites para que as escritas sejam repetidas.
+func TestResilientRepositoryOverFaultyRepository(t *testing.T) {
+	setRetryEnv(t)
+
+	safe := &flakyRepository{failures: 1, safe: true}
+	if err := repo.NewResilientRepository(repo.NewFaultyRepository(safe, config.FaultConfig{})).BatchCreateClient(nil); err != nil {
+		t.Errorf("escrita segura: err = %v", err)
+	}
+	if safe.calls != 2 {
+		t.Errorf("escrita segura executada %d vezes, esperado 2", safe.calls)
+	}
+
+	unsafe := &flakyRepository{failures: 1}
+	if err := repo.NewResilientRepository(repo.NewFaultyRepository(unsafe, config.FaultConfig{})).BatchCreateClient(nil); !errors.Is(err, repo.ErrTransient) {
+		t.Errorf("escrita não segura: err = %v, esperado erro transitório", err)
+	}
+	if unsafe.calls != 1 {
+		t.Errorf("escrita não segura executada %d vezes, esperado 1", unsafe.calls)
+	}
+
+	// Uma escrita parcial já gravou o início do lote e não é repetida.
+	partial := repo.NewFaultyRepository(&flakyRepository{safe: true}, config.FaultConfig{
+		Seed: 1,
+		Methods: map[string]config.MethodFault{
+			"BatchCreateClient": {PartialFailureRate: 1},
+		},
+	})
+	if err := repo.NewResilientRepository(partial).BatchCreateClient(make([]model.Client, 10)); !errors.Is(err, repo.ErrPartialWrite) {
+		t.Errorf("escrita parcial: err = %v, esperado escrita parcial", err)
+	}
+	if stats := partial.Stats(); stats.PartialWrites != 1 {
+		t.Errorf("escrita parcial repetida: stats = %+v", stats)
+	}
+}
+
+func TestResilientRepositoryOpensCircuit(t *testing.T) {
+	setRetryEnv(t)
+	inner := &flakyRepository{failures: 100}
+	r := repo.NewResilientRepository(inner)
+
+	for range 2 {
+		if _, err := r.GetClientByEmail("ana@techmarket.com"); !errors.Is(err, repo.ErrTransient) {
+			t.Fatalf("err = %v, esperado erro transitório", err)
+		}
+	}
+	if _, err := r.GetClientByEmail("ana@techmarket.com"); !errors.Is(err, repo.ErrCircuitOpen) {
+		t.Fatalf("err = %v, esperado circuito aberto", err)
+	}
+
+	stats := r.Stats()
+	if inner.calls != 6 || stats.Failures != 2 || stats.Rejected != 1 || stats.State != repo.BreakerOpen {
+		t.Errorf("calls = %d, stats = %+v", inner.calls, stats)
+	}
+}
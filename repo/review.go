@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"techmarket_showcase/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	_ Reviewer = &PostgresRepository{}
+	_ Reviewer = &SQLiteRepository{}
+)
+
+// productCategoriesFunc retorna a categoria de cada produto informado que
+// existe no banco.
+type productCategoriesFunc func(productIDs []uint) (map[uint]string, error)
+
+// validateReviews valida as avaliações e confere, com productCategories, que
+// cada produto existe. As categorias encontradas são retornadas para os bancos
+// que as copiam na avaliação.
+func validateReviews(reviews []model.Review, productCategories productCategoriesFunc) (map[uint]string, error) {
+	if err := validateRecords(reviews); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[uint]bool)
+	for _, review := range reviews {
+		ids[review.ProductID] = true
+	}
+	categories, err := productCategories(slices.Sorted(maps.Keys(ids)))
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, review := range reviews {
+		if _, ok := categories[review.ProductID]; !ok {
+			v := &model.ValidationError{Entity: "avaliacao", ID: review.ID}
+			v.Add("id_produto", "produto %d não encontrado", review.ProductID)
+			errs = append(errs, v)
+		}
+	}
+	return categories, errors.Join(errs...)
+}
+
+// gormProductCategories consulta a tabela produto em blocos, como
+// gormOrderClients.
+func gormProductCategories(db *gorm.DB) productCategoriesFunc {
+	return func(productIDs []uint) (map[uint]string, error) {
+		categories := make(map[uint]string)
+		for chunk := range slices.Chunk(productIDs, 500) {
+			var rows []struct {
+				ID       uint   `gorm:"column:id"`
+				Category string `gorm:"column:categoria"`
+			}
+			if err := db.Raw(`SELECT id, categoria FROM produto WHERE id IN ?`, chunk).Scan(&rows).Error; err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				categories[row.ID] = row.Category
+			}
+		}
+		return categories, nil
+	}
+}
+
+func gormCreateReviews(db *gorm.DB, writes WriteOptions, reviews []model.Review) error {
+	if _, err := validateReviews(reviews, gormProductCategories(db)); err != nil {
+		return err
+	}
+	return gormWrite(db, writes, 100, "avaliacao", reviews)
+}
+
+// gormProductReviews usa o índice (id_produto, id), então cada página é uma
+// leitura do índice a partir de beforeID, sem OFFSET.
+func gormProductReviews(db *gorm.DB, productID, beforeID uint, limit int) ([]model.Review, error) {
+	query := db.Table("avaliacao").Where("id_produto = ?", productID)
+	if beforeID != 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	var reviews []model.Review
+	if err := query.Order("id DESC").Limit(limit).Scan(&reviews).Error; err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+func gormAverageRatingByCategory(db *gorm.DB) ([]model.CategoryRating, error) {
+	query := `
+		SELECT p.categoria, COUNT(*) AS avaliacoes, SUM(a.nota) AS soma_notas
+		FROM avaliacao a
+		JOIN produto p ON p.id = a.id_produto
+		GROUP BY p.categoria
+		ORDER BY p.categoria
+	`
+	var ratings []model.CategoryRating
+	if err := db.Raw(query).Scan(&ratings).Error; err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+func (p *PostgresRepository) BatchCreateReview(reviews []model.Review) error {
+	return gormCreateReviews(p.db, p.writes, reviews)
+}
+
+func (p *PostgresRepository) GetProductReviews(productID uint, beforeID uint, limit int) ([]model.Review, error) {
+	return gormProductReviews(p.db, productID, beforeID, limit)
+}
+
+func (p *PostgresRepository) GetAverageRatingByCategory() ([]model.CategoryRating, error) {
+	return gormAverageRatingByCategory(p.db)
+}
+
+func (s *SQLiteRepository) BatchCreateReview(reviews []model.Review) error {
+	return gormCreateReviews(s.db, s.writes, reviews)
+}
+
+func (s *SQLiteRepository) GetProductReviews(productID uint, beforeID uint, limit int) ([]model.Review, error) {
+	return gormProductReviews(s.db, productID, beforeID, limit)
+}
+
+func (s *SQLiteRepository) GetAverageRatingByCategory() ([]model.CategoryRating, error) {
+	return gormAverageRatingByCategory(s.db)
+}
@@ -0,0 +1,97 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"techmarket_showcase/model"
+)
+
+// cities são cidades reais com a faixa de CEP do estado. O seed sorteia o CEP
+// dentro da faixa, então cidade, UF e prefixo do CEP são coerentes.
+var cities = []struct {
+	name, state    string
+	cepFrom, cepTo int
+}{
+	{"São Paulo", "SP", 1000, 5999},
+	{"Campinas", "SP", 13000, 13139},
+	{"Rio de Janeiro", "RJ", 20000, 23799},
+	{"Belo Horizonte", "MG", 30000, 31999},
+	{"Salvador", "BA", 40000, 42599},
+	{"Recife", "PE", 50000, 52999},
+	{"Fortaleza", "CE", 60000, 61599},
+	{"Belém", "PA", 66000, 66999},
+	{"Manaus", "AM", 69000, 69099},
+	{"Brasília", "DF", 70000, 72799},
+	{"Goiânia", "GO", 74000, 74899},
+	{"Curitiba", "PR", 80000, 82999},
+	{"Florianópolis", "SC", 88000, 88099},
+	{"Porto Alegre", "RS", 90000, 91999},
+	{"Vitória", "ES", 29000, 29099},
+	{"Natal", "RN", 59000, 59139},
+}
+
+var (
+	streetTypes = []string{"Rua", "Avenida", "Travessa", "Alameda", "Praça"}
+	streetNames = []string{
+		"das Flores", "Sete de Setembro", "XV de Novembro", "Getúlio Vargas", "Tiradentes",
+		"Santos Dumont", "Dom Pedro II", "Marechal Deodoro", "da Paz", "José Bonifácio",
+	}
+	complements = []string{"", "", "", "Apto 12", "Apto 304", "Casa 2", "Bloco B", "Fundos"}
+)
+
+func generatePostalAddress() model.PostalAddress {
+	city := cities[rand.Intn(len(cities))]
+	cep := city.cepFrom + rand.Intn(city.cepTo-city.cepFrom+1)
+
+	return model.PostalAddress{
+		CEP:        fmt.Sprintf("%05d-%03d", cep, rand.Intn(1000)),
+		Street:     streetTypes[rand.Intn(len(streetTypes))] + " " + streetNames[rand.Intn(len(streetNames))],
+		Number:     fmt.Sprintf("%d", rand.Intn(3000)+1),
+		Complement: complements[rand.Intn(len(complements))],
+		City:       city.name,
+		State:      city.state,
+	}
+}
+
+// GenerateAddresses cria de um a três endereços para cada cliente. O primeiro
+// endereço de cada cliente é o padrão.
+func GenerateAddresses(ids *model.IDGenerator, clients []model.Client) []model.Address {
+	var addresses []model.Address
+	for _, client := range clients {
+		for i := range rand.Intn(3) + 1 {
+			addresses = append(addresses, model.Address{
+				ID:            ids.Next(),
+				ClientID:      client.ID,
+				PostalAddress: generatePostalAddress(),
+				Default:       i == 0,
+			})
+		}
+	}
+	return addresses
+}
+
+// AssignAddresses define o endereço de entrega de cada pedido entre os
+// endereços do cliente: o padrão em quatro de cada cinco pedidos e um dos
+// outros no restante. Pedidos de clientes sem endereço ficam sem.
+func AssignAddresses(orders []model.Order, addresses []model.Address) {
+	byClient := make(map[uint][]uint)
+	for _, address := range addresses {
+		if address.Default {
+			byClient[address.ClientID] = append([]uint{address.ID}, byClient[address.ClientID]...)
+		} else {
+			byClient[address.ClientID] = append(byClient[address.ClientID], address.ID)
+		}
+	}
+
+	for i := range orders {
+		ids := byClient[orders[i].ClientID]
+		if len(ids) == 0 {
+			continue
+		}
+		id := ids[0]
+		if len(ids) > 1 && rand.Intn(5) == 0 {
+			id = ids[1+rand.Intn(len(ids)-1)]
+		}
+		orders[i].AddressID = &id
+	}
+}
@@ -10,39 +10,17 @@ import (
 	"github.com/go-faker/faker/v4"
 )
 
+// generateCPF sorteia os nove primeiros dígitos e completa com os dígitos
+// verificadores, descartando sequências de um único dígito, que
+// model.ValidCPF rejeita.
 func generateCPF() string {
-	cpf := ""
-	for range 9 {
-		cpf += fmt.Sprintf("%d", rand.Intn(10))
-	}
-
-	sum := 0
-	for i := range 9 {
-		digit := int(cpf[i] - '0')
-		sum += digit * (10 - i)
-	}
-	d1 := 11 - (sum % 11)
-	if d1 >= 10 {
-		d1 = 0
-	}
-	cpf += fmt.Sprintf("%d", d1)
-
-	sum = 0
-	for i := range 10 {
-		digit := int(cpf[i] - '0')
-		sum += digit * (11 - i)
-	}
-	d2 := 11 - (sum % 11)
-	if d2 >= 10 {
-		d2 = 0
+	for {
+		base := fmt.Sprintf("%09d", rand.Intn(1000000000))
+		if strings.Count(base, base[:1]) == len(base) {
+			continue
+		}
+		return model.FormatCPF(base + model.CPFCheckDigits(base))
 	}
-	cpf += fmt.Sprintf("%d", d2)
-
-	return fmt.Sprintf("%s.%s.%s-%s",
-		cpf[0:3],
-		cpf[3:6],
-		cpf[6:9],
-		cpf[9:11])
 }
 
 func generatePhone() string {
@@ -55,8 +33,12 @@ func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
-func GenerateClients(count int) []model.Client {
+// GenerateClients cria clientes com CPF e email únicos dentro do lote, já que
+// os dois têm índice único em todos os bancos.
+func GenerateClients(ids *model.IDGenerator, count int) []model.Client {
 	clients := make([]model.Client, count)
+	usedCPFs := make(map[string]bool)
+	usedEmails := make(map[string]bool)
 
 	for i := 0; i < count; i++ {
 		var person struct {
@@ -66,12 +48,27 @@ func GenerateClients(count int) []model.Client {
 		}
 		faker.FakeData(&person)
 
+		cpf := generateCPF()
+		for usedCPFs[cpf] {
+			cpf = generateCPF()
+		}
+		usedCPFs[cpf] = true
+
+		local := strings.ToLower(strings.ReplaceAll(person.FirstName+"."+person.LastName, " ", ""))
+		domain := strings.ToLower(person.Domain)
+		email := local + "@" + domain
+		for n := 2; usedEmails[email]; n++ {
+			email = fmt.Sprintf("%s%d@%s", local, n, domain)
+		}
+		usedEmails[email] = true
+
 		clients[i] = model.Client{
+			ID:        ids.Next(),
 			Nome:      fmt.Sprintf("%s %s", person.FirstName, person.LastName),
-			Email:     strings.ToLower(fmt.Sprintf("%s.%s@%s", person.FirstName, person.LastName, person.Domain)),
+			Email:     email,
 			Phone:     generatePhone(),
 			CreatedAt: time.Now().Add(-time.Duration(rand.Intn(365)) * 24 * time.Hour), // Data aleatória no último ano
-			CPF:       generateCPF(),
+			CPF:       cpf,
 		}
 	}
 	return clients
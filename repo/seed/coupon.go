@@ -0,0 +1,46 @@
+package seed
+
+import (
+	"math/rand"
+	"techmarket_showcase/model"
+	"time"
+)
+
+// GenerateCoupons cria os cupons da campanha atual, válidos de 30 dias atrás
+// até 30 dias à frente: um percentual e um de valor fixo para todos os itens e
+// dois restritos a categorias, com limites de uso diferentes.
+func GenerateCoupons() []model.Coupon {
+	now := time.Now().Truncate(time.Second)
+	validFrom, validUntil := now.AddDate(0, 0, -30), now.AddDate(0, 0, 30)
+
+	return []model.Coupon{
+		{Code: "BEMVINDO10", Type: model.CouponPercentage, Percent: 10, ValidFrom: validFrom, ValidUntil: validUntil, UsageLimit: 1},
+		{Code: "PIX50", Type: model.CouponFixed, Amount: model.Reais(50), MinOrderValue: model.Reais(500), ValidFrom: validFrom, ValidUntil: validUntil, UsageLimit: 3},
+		{Code: "NOTEBOOK15", Type: model.CouponPercentage, Percent: 15, MinOrderValue: model.Reais(2000), ValidFrom: validFrom, ValidUntil: validUntil, UsageLimit: 2, Categories: []string{"Notebooks", "Tablets"}},
+		{Code: "FONE100", Type: model.CouponFixed, Amount: model.Reais(100), ValidFrom: validFrom, ValidUntil: validUntil, UsageLimit: 2, Categories: []string{"Fones de Ouvido", "Acessórios"}},
+	}
+}
+
+// GenerateCouponOrders cria count pedidos com cupom dos clientes informados,
+// dentro da validade dos cupons. Com poucos clientes muitos pedidos disputam o
+// limite de usos de cada um, e parte deles é recusada. TotalValue é o valor
+// dos itens, sem desconto, que é calculado pelo repositório.
+func GenerateCouponOrders(ids *model.IDGenerator, count int, clients []model.Client, products []model.Product, coupons []model.Coupon) []model.Order {
+	orders := make([]model.Order, count)
+
+	for i := range orders {
+		orderID := ids.Next()
+		order := model.Order{
+			ID:         orderID,
+			ClientID:   clients[rand.Intn(len(clients))].ID,
+			OrderDate:  time.Now().Add(-time.Duration(rand.Intn(29*24)) * time.Hour),
+			Status:     orderStatus[rand.Intn(len(orderStatus))],
+			CouponCode: coupons[rand.Intn(len(coupons))].Code,
+			Itens:      generateOrderItems(orderID, products, 5),
+		}
+		order.TotalValue = order.Subtotal()
+		orders[i] = order
+	}
+
+	return orders
+}
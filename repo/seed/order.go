@@ -12,58 +12,74 @@ var orderStatus = []string{
 	"Pago",
 	"Em Separação",
 	"Em Transporte",
-	"Entregue",
-	"Cancelado",
+	model.OrderStatusDelivered,
+	model.OrderStatusCancelled,
 }
 
-func generateOrderItems(productCount int, maxItems int) []model.OrderItem {
-	numItems := rand.Intn(maxItems) + 1
+func generateOrderItems(orderID uint, products []model.Product, maxItems int) []model.OrderItem {
+	numItems := min(rand.Intn(maxItems)+1, len(products))
 	items := make([]model.OrderItem, numItems)
 
 	usedProducts := make(map[uint]bool)
 
 	for i := 0; i < numItems; i++ {
-		var productID uint
+		var product model.Product
 		for {
-			productID = uint(rand.Intn(productCount) + 1)
-			if !usedProducts[productID] {
-				usedProducts[productID] = true
+			product = products[rand.Intn(len(products))]
+			if !usedProducts[product.ID] {
+				usedProducts[product.ID] = true
 				break
 			}
 		}
 
 		items[i] = model.OrderItem{
-			ProductID: productID,
+			OrderID:   orderID,
+			ProductID: product.ID,
 			Quantity:  rand.Intn(5) + 1,
+			Product:   product,
 		}
 	}
 
 	return items
 }
 
-func GenerateOrders(count int, clientCount int, productCount int) []model.Order {
+// GenerateOrders cria pedidos que referenciam os IDs dos clientes e produtos
+// informados, então os dados podem ser gravados em qualquer banco sem quebrar
+// as referências.
+func GenerateOrders(ids *model.IDGenerator, count int, clients []model.Client, products []model.Product) []model.Order {
 	orders := make([]model.Order, count)
 	maxItemsPerOrder := 10
 
 	for i := 0; i < count; i++ {
+		orderID := ids.Next()
 		orderDate := time.Now().Add(-time.Duration(rand.Intn(90)) * 24 * time.Hour)
 
-		items := generateOrderItems(productCount, maxItemsPerOrder)
+		items := generateOrderItems(orderID, products, maxItemsPerOrder)
 
-		var totalValue float64
+		var totalValue model.Money
 		for _, item := range items {
-			productPrice := 100.0 + rand.Float64()*900.0
-			totalValue += productPrice * float64(item.Quantity)
+			totalValue += item.Product.Price.Mul(item.Quantity)
 		}
 
 		orders[i] = model.Order{
-			ClientID:   uint(rand.Intn(clientCount) + 1),
+			ID:         orderID,
+			ClientID:   clients[rand.Intn(len(clients))].ID,
 			OrderDate:  orderDate,
 			Status:     orderStatus[rand.Intn(len(orderStatus))],
-			TotalValue: float64(int(totalValue*100)) / 100,
+			TotalValue: totalValue,
 			Itens:      items,
 		}
 	}
 
 	return orders
 }
+
+// OrderItems reúne os itens de todos os pedidos, na forma esperada por
+// BatchCreateOrderItem.
+func OrderItems(orders []model.Order) []model.OrderItem {
+	var items []model.OrderItem
+	for _, order := range orders {
+		items = append(items, order.Itens...)
+	}
+	return items
+}
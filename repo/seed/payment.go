@@ -8,8 +8,8 @@ import (
 
 var (
 	paymentTypes = []string{
-		"PIX",
-		"Cartão de Crédito",
+		model.PaymentTypePix,
+		model.PaymentTypeCreditCard,
 		"Cartão de Débito",
 		"Boleto",
 		"Transferência Bancária",
@@ -46,19 +46,72 @@ func generatePaymentStatus() string {
 	return "Aprovado"
 }
 
-func GeneratePayments(orderCount int, count int) []model.Payment {
+func GeneratePayments(ids *model.IDGenerator, orders []model.Order, count int) []model.Payment {
 	payments := make([]model.Payment, count)
 
 	for i := 0; i < count; i++ {
 		paymentDate := time.Now().Add(-time.Duration(rand.Intn(30)) * 24 * time.Hour)
+		order := orders[rand.Intn(len(orders))]
+		paymentType := paymentTypes[rand.Intn(len(paymentTypes))]
+
+		installments := 1
+		if paymentType == model.PaymentTypeCreditCard {
+			installments = rand.Intn(12) + 1
+		}
 
 		payments[i] = model.Payment{
-			OrderID:     uint(rand.Intn(orderCount) + 1),
-			Type:        paymentTypes[rand.Intn(len(paymentTypes))],
-			Status:      generatePaymentStatus(),
-			PaymentDate: paymentDate,
+			ID:           ids.Next(),
+			OrderID:      order.ID,
+			ClientID:     order.ClientID,
+			Type:         paymentType,
+			Status:       generatePaymentStatus(),
+			Amount:       order.TotalValue,
+			Installments: installments,
+			PaymentDate:  paymentDate,
+		}
+		if payments[i].Status == model.StatusRefunded {
+			payments[i].Refunded = payments[i].Amount
 		}
 	}
 
 	return payments
 }
+
+var refundReasons = []string{
+	"Desistência da compra",
+	"Produto com defeito",
+	"Produto não entregue",
+	"Cobrança em duplicidade",
+}
+
+// GenerateRefunds gera até count estornos de pagamentos aprovados distintos,
+// metade do valor ou o valor inteiro, alguns dias depois do pagamento.
+func GenerateRefunds(ids *model.IDGenerator, payments []model.Payment, count int) []model.Refund {
+	var refunds []model.Refund
+
+	for _, i := range rand.Perm(len(payments)) {
+		if len(refunds) == count {
+			break
+		}
+
+		payment := payments[i]
+		if payment.Status != model.PaymentStatusApproved {
+			continue
+		}
+
+		amount := payment.Amount
+		if rand.Intn(2) == 0 && amount > 1 {
+			amount /= 2
+		}
+
+		refunds = append(refunds, model.Refund{
+			ID:         ids.Next(),
+			PaymentID:  payment.ID,
+			Amount:     amount,
+			Reason:     refundReasons[rand.Intn(len(refundReasons))],
+			RefundDate: payment.PaymentDate.Add(time.Duration(rand.Intn(72)+1) * time.Hour),
+		})
+	}
+
+	return refunds
+}
@@ -46,17 +46,17 @@ var (
 		"ProTech",
 	}
 
-	categoryPriceRanges = map[string]struct{ min, max float64 }{
-		"Smartphones":     {800.00, 8000.00},
-		"Notebooks":       {2000.00, 15000.00},
-		"Tablets":         {500.00, 5000.00},
-		"Smart TVs":       {1200.00, 12000.00},
-		"Fones de Ouvido": {50.00, 2000.00},
-		"Smartwatches":    {200.00, 3000.00},
-		"Câmeras":         {500.00, 8000.00},
-		"Acessórios":      {20.00, 500.00},
-		"Periféricos":     {50.00, 1000.00},
-		"Componentes PC":  {100.00, 5000.00},
+	categoryPriceRanges = map[string]struct{ min, max model.Money }{
+		"Smartphones":     {model.Reais(800), model.Reais(8000)},
+		"Notebooks":       {model.Reais(2000), model.Reais(15000)},
+		"Tablets":         {model.Reais(500), model.Reais(5000)},
+		"Smart TVs":       {model.Reais(1200), model.Reais(12000)},
+		"Fones de Ouvido": {model.Reais(50), model.Reais(2000)},
+		"Smartwatches":    {model.Reais(200), model.Reais(3000)},
+		"Câmeras":         {model.Reais(500), model.Reais(8000)},
+		"Acessórios":      {model.Reais(20), model.Reais(500)},
+		"Periféricos":     {model.Reais(50), model.Reais(1000)},
+		"Componentes PC":  {model.Reais(100), model.Reais(5000)},
 	}
 )
 
@@ -68,19 +68,19 @@ func generateProductName(category string) string {
 	return fmt.Sprintf("%s %s %s %s", brand, category, prefix, model)
 }
 
-func generatePrice(category string) float64 {
+func generatePrice(category string) model.Money {
 	priceRange := categoryPriceRanges[category]
-	price := priceRange.min + rand.Float64()*(priceRange.max-priceRange.min)
-	return float64(int(price*100)) / 100
+	return priceRange.min + model.Money(rand.Int63n(int64(priceRange.max-priceRange.min)))
 }
 
-func GenerateProducts(count int) []model.Product {
+func GenerateProducts(ids *model.IDGenerator, count int) []model.Product {
 	products := make([]model.Product, count)
 
 	for i := range count {
 		category := categories[rand.Intn(len(categories))]
 
 		products[i] = model.Product{
+			ID:       ids.Next(),
 			Name:     generateProductName(category),
 			Category: category,
 			Price:    generatePrice(category),
@@ -0,0 +1,88 @@
+package seed
+
+import (
+	"math/rand"
+	"techmarket_showcase/model"
+	"time"
+)
+
+var (
+	// ratingWeights concentra as notas no alto, como nas lojas reais.
+	ratingWeights = map[int]int{1: 7, 2: 8, 3: 15, 4: 30, 5: 40}
+
+	reviewComments = map[int][]string{
+		1: {"Parou de funcionar em uma semana.", "Não recomendo.", ""},
+		2: {"Qualidade abaixo do esperado.", "Chegou com defeito, precisei trocar.", ""},
+		3: {"Cumpre o que promete, nada além.", "Bom, mas poderia ser mais barato.", ""},
+		4: {"Muito bom, recomendo.", "Ótimo custo-benefício.", ""},
+		5: {"Excelente, superou as expectativas!", "Produto perfeito, entrega rápida.", ""},
+	}
+)
+
+func generateRating() int {
+	roll := rand.Intn(100)
+	accumulated := 0
+
+	for rating := model.MinRating; rating <= model.MaxRating; rating++ {
+		accumulated += ratingWeights[rating]
+		if roll < accumulated {
+			return rating
+		}
+	}
+
+	return model.MaxRating
+}
+
+// GenerateReviews cria até count avaliações de produtos comprados nos pedidos
+// informados, pelo cliente do pedido e depois da compra. Cada cliente avalia
+// um produto no máximo uma vez.
+func GenerateReviews(ids *model.IDGenerator, orders []model.Order, count int) []model.Review {
+	var reviews []model.Review
+	reviewed := make(map[[2]uint]bool)
+
+	for attempts := 0; len(reviews) < count && attempts < count*10; attempts++ {
+		order := orders[rand.Intn(len(orders))]
+		if len(order.Itens) == 0 {
+			continue
+		}
+		item := order.Itens[rand.Intn(len(order.Itens))]
+
+		key := [2]uint{order.ClientID, item.ProductID}
+		if reviewed[key] {
+			continue
+		}
+		reviewed[key] = true
+
+		createdAt := order.OrderDate.Add(time.Duration(rand.Intn(30)+1) * 24 * time.Hour)
+		if now := time.Now(); createdAt.After(now) {
+			createdAt = now
+		}
+
+		rating := generateRating()
+		comments := reviewComments[rating]
+		reviews = append(reviews, model.Review{
+			ID:        ids.Next(),
+			ProductID: item.ProductID,
+			ClientID:  order.ClientID,
+			Rating:    rating,
+			Comment:   comments[rand.Intn(len(comments))],
+			CreatedAt: createdAt,
+		})
+	}
+
+	return reviews
+}
+
+// MostReviewedProduct retorna o produto com mais avaliações, ou zero se não
+// houver nenhuma.
+func MostReviewedProduct(reviews []model.Review) uint {
+	counts := make(map[uint]int)
+	var best uint
+	for _, review := range reviews {
+		counts[review.ProductID]++
+		if counts[review.ProductID] > counts[best] {
+			best = review.ProductID
+		}
+	}
+	return best
+}
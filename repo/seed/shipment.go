@@ -0,0 +1,92 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"techmarket_showcase/model"
+	"time"
+)
+
+var carriers = []string{"Correios", "Jadlog", "Loggi", "Total Express", "Azul Cargo Express"}
+
+// generateTrackingCode segue o formato dos Correios, como AA123456789BR.
+func generateTrackingCode() string {
+	letters := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	return fmt.Sprintf("%c%c%09dBR", letters[rand.Intn(26)], letters[rand.Intn(26)], rand.Intn(1000000000))
+}
+
+// GenerateShipments cria um envio para cada pedido em transporte ou entregue,
+// com a linha do tempo de eventos: postagem, passagens por centros de
+// distribuição e, nos pedidos entregues, a saída para entrega e a entrega,
+// às vezes depois de uma tentativa sem sucesso. O envio vai para o endereço do
+// pedido, entre addresses, ou para um endereço sorteado se o pedido não tiver
+// um. Os eventos são retornados à parte, em ordem de data de cada envio, já
+// que são gravados depois dos envios.
+func GenerateShipments(ids *model.IDGenerator, orders []model.Order, addresses []model.Address) ([]model.Shipment, []model.TrackingEvent) {
+	var (
+		shipments []model.Shipment
+		events    []model.TrackingEvent
+	)
+
+	byID := make(map[uint]model.PostalAddress, len(addresses))
+	for _, address := range addresses {
+		byID[address.ID] = address.PostalAddress
+	}
+
+	for _, order := range orders {
+		delivered := order.Status == model.OrderStatusDelivered
+		if !delivered && order.Status != "Em Transporte" {
+			continue
+		}
+
+		var address model.PostalAddress
+		if order.AddressID != nil {
+			address = byID[*order.AddressID]
+		}
+		if address.CEP == "" {
+			address = generatePostalAddress()
+		}
+
+		shipment := model.Shipment{
+			ID:           ids.Next(),
+			OrderID:      order.ID,
+			Carrier:      carriers[rand.Intn(len(carriers))],
+			TrackingCode: generateTrackingCode(),
+			Address:      address,
+			ShippedAt:    order.OrderDate.Add(time.Duration(rand.Intn(48)+1) * time.Hour),
+		}
+		shipments = append(shipments, shipment)
+
+		at := shipment.ShippedAt
+		event := func(status, location, description string) {
+			events = append(events, model.TrackingEvent{
+				ShipmentID:  shipment.ID,
+				Status:      status,
+				Location:    location,
+				Description: description,
+				OccurredAt:  at,
+			})
+			at = at.Add(time.Duration(rand.Intn(24)+4) * time.Hour)
+		}
+
+		origin := cities[rand.Intn(len(cities))]
+		event(model.TrackingPosted, origin.name+"/"+origin.state, "Objeto postado")
+		for range rand.Intn(3) + 1 {
+			hub := cities[rand.Intn(len(cities))]
+			event(model.TrackingInTransit, hub.name+"/"+hub.state, "Objeto em trânsito para o centro de distribuição")
+		}
+		if !delivered {
+			continue
+		}
+
+		destination := shipment.Address.City + "/" + shipment.Address.State
+		if rand.Intn(10) == 0 {
+			event(model.TrackingOutForDelivery, destination, "Objeto saiu para entrega ao destinatário")
+			event(model.TrackingFailedDelivery, destination, "Destinatário ausente")
+		}
+		event(model.TrackingOutForDelivery, destination, "Objeto saiu para entrega ao destinatário")
+		event(model.OrderStatusDelivered, destination, "Objeto entregue ao destinatário")
+	}
+
+	return shipments, events
+}
@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"techmarket_showcase/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	_ ShipmentTracker = &PostgresRepository{}
+	_ ShipmentTracker = &SQLiteRepository{}
+)
+
+// shipmentOrdersFunc retorna o pedido de cada envio informado que existe no
+// banco.
+type shipmentOrdersFunc func(shipmentIDs []uint) (map[uint]uint, error)
+
+// validateShipments valida os envios e confere, com orderClients, que cada
+// pedido existe.
+func validateShipments(shipments []model.Shipment, orderClients orderClientsFunc) error {
+	if err := validateRecords(shipments); err != nil {
+		return err
+	}
+
+	ids := make(map[uint]bool)
+	for _, shipment := range shipments {
+		ids[shipment.OrderID] = true
+	}
+	clients, err := orderClients(slices.Sorted(maps.Keys(ids)))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, shipment := range shipments {
+		if _, ok := clients[shipment.OrderID]; !ok {
+			v := &model.ValidationError{Entity: "envio", ID: shipment.ID}
+			v.Add("id_pedido", "pedido %d não encontrado", shipment.OrderID)
+			errs = append(errs, v)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateTrackingEvents valida os eventos e confere, com shipmentOrders, que
+// cada envio existe.
+func validateTrackingEvents(events []model.TrackingEvent, shipmentOrders shipmentOrdersFunc) error {
+	if err := validateRecords(events); err != nil {
+		return err
+	}
+
+	ids := make(map[uint]bool)
+	for _, event := range events {
+		ids[event.ShipmentID] = true
+	}
+	orders, err := shipmentOrders(slices.Sorted(maps.Keys(ids)))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, event := range events {
+		if _, ok := orders[event.ShipmentID]; !ok {
+			v := &model.ValidationError{Entity: "evento_rastreio", ID: event.ShipmentID}
+			v.Add("id_envio", "envio %d não encontrado", event.ShipmentID)
+			errs = append(errs, v)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// gormShipmentOrders consulta a tabela envio em blocos, como gormOrderClients.
+func gormShipmentOrders(db *gorm.DB) shipmentOrdersFunc {
+	return func(shipmentIDs []uint) (map[uint]uint, error) {
+		orders := make(map[uint]uint)
+		for chunk := range slices.Chunk(shipmentIDs, 500) {
+			var rows []struct {
+				ID      uint `gorm:"column:id"`
+				OrderID uint `gorm:"column:id_pedido"`
+			}
+			if err := db.Raw(`SELECT id, id_pedido FROM envio WHERE id IN ?`, chunk).Scan(&rows).Error; err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				orders[row.ID] = row.OrderID
+			}
+		}
+		return orders, nil
+	}
+}
+
+func gormCreateShipments(db *gorm.DB, writes WriteOptions, shipments []model.Shipment) error {
+	if err := validateShipments(shipments, gormOrderClients(db)); err != nil {
+		return err
+	}
+	return gormWrite(db, writes, 100, "envio", shipments)
+}
+
+// gormAppendTrackingEvents insere os eventos em evento_rastreio, que tem um
+// índice por (id_envio, data_evento) para a leitura da linha do tempo.
+func gormAppendTrackingEvents(db *gorm.DB, writes WriteOptions, events []model.TrackingEvent) error {
+	if err := validateTrackingEvents(events, gormShipmentOrders(db)); err != nil {
+		return err
+	}
+	return gormWrite(db, writes, 100, "evento_rastreio", events)
+}
+
+func gormTrackingHistory(db *gorm.DB, orderID uint) ([]model.Shipment, error) {
+	var shipments []model.Shipment
+	if err := db.Raw(`SELECT * FROM envio WHERE id_pedido = ? ORDER BY data_envio, id`, orderID).Scan(&shipments).Error; err != nil {
+		return nil, err
+	}
+	if len(shipments) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(shipments))
+	for i, shipment := range shipments {
+		ids[i] = shipment.ID
+	}
+	query := `SELECT id_envio, status, local, descricao, data_evento FROM evento_rastreio WHERE id_envio IN ? ORDER BY data_evento, id`
+	var events []model.TrackingEvent
+	if err := db.Raw(query, ids).Scan(&events).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range shipments {
+		for _, event := range events {
+			if event.ShipmentID == shipments[i].ID {
+				shipments[i].Events = append(shipments[i].Events, event)
+			}
+		}
+	}
+	return shipments, nil
+}
+
+func (p *PostgresRepository) BatchCreateShipment(shipments []model.Shipment) error {
+	return gormCreateShipments(p.db, p.writes, shipments)
+}
+
+func (p *PostgresRepository) AppendTrackingEvents(events []model.TrackingEvent) error {
+	return gormAppendTrackingEvents(p.db, p.writes, events)
+}
+
+func (p *PostgresRepository) GetTrackingHistory(orderID uint) ([]model.Shipment, error) {
+	return gormTrackingHistory(p.db, orderID)
+}
+
+func (s *SQLiteRepository) BatchCreateShipment(shipments []model.Shipment) error {
+	return gormCreateShipments(s.db, s.writes, shipments)
+}
+
+func (s *SQLiteRepository) AppendTrackingEvents(events []model.TrackingEvent) error {
+	return gormAppendTrackingEvents(s.db, s.writes, events)
+}
+
+func (s *SQLiteRepository) GetTrackingHistory(orderID uint) ([]model.Shipment, error) {
+	return gormTrackingHistory(s.db, orderID)
+}
@@ -0,0 +1,163 @@
+package repo
+
+import (
+	"fmt"
+	"techmarket_showcase/config"
+	"techmarket_showcase/model"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var _ TechMarketRepository = &SQLiteRepository{}
+
+type SQLiteRepository struct {
+	db     *gorm.DB
+	writes WriteOptions
+}
+
+func (s *SQLiteRepository) BatchCreateClient(clients []model.Client) error {
+	if err := validateRecords(clients); err != nil {
+		return err
+	}
+	return gormWrite(s.db, s.writes, 100, "cliente", clients)
+}
+
+func (s *SQLiteRepository) BatchCreateProduct(products []model.Product) error {
+	if err := validateRecords(products); err != nil {
+		return err
+	}
+	return gormWrite(s.db, s.writes, 100, "produto", products)
+}
+
+func (s *SQLiteRepository) BatchCreateOrder(orders []model.Order) error {
+	if _, err := validateOrders(orders, gormExistingClients(s.db, false), gormAddresses(s.db)); err != nil {
+		return err
+	}
+	return gormWrite(s.db, s.writes, 100, "pedido", orders)
+}
+
+func (s *SQLiteRepository) BatchCreateOrderItem(orderItems []model.OrderItem) error {
+	if err := validateRecords(orderItems); err != nil {
+		return err
+	}
+	items := make([]map[string]any, len(orderItems))
+	for i, item := range orderItems {
+		items[i] = map[string]any{
+			"id_pedido":      item.OrderID,
+			"id_produto":     item.ProductID,
+			"quantidade":     item.Quantity,
+			"preco_unitario": item.Product.Price,
+		}
+	}
+	return gormWrite(s.db, s.writes, 100, "item_pedido", items)
+}
+
+func (s *SQLiteRepository) BatchCreatePayment(payments []model.Payment) error {
+	if err := validatePayments(payments, gormOrderClients(s.db)); err != nil {
+		return err
+	}
+	return gormWrite(s.db, s.writes, 100, "pagamento", payments)
+}
+
+func (s *SQLiteRepository) GetClientByEmail(email string) (model.Client, error) {
+	query := `SELECT * FROM cliente WHERE email = ?`
+	var client model.Client
+	if err := s.db.Raw(query, email).Scan(&client).Error; err != nil {
+		return model.Client{}, err
+	}
+	return client, nil
+}
+
+func (s *SQLiteRepository) GetProductByCategory(category string) ([]model.Product, error) {
+	query := `SELECT * FROM produto WHERE categoria = ?`
+	var products []model.Product
+	if err := s.db.Raw(query, category).Scan(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (s *SQLiteRepository) GetDeliveredOrdersByClient(clientID uint) ([]model.Order, error) {
+	query := `SELECT * FROM pedido WHERE id_cliente = ? AND status = ?`
+	var orders []model.Order
+	if err := s.db.Raw(query, clientID, model.OrderStatusDelivered).Scan(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (s *SQLiteRepository) Get5MostSoldProducts() ([]model.Product, error) {
+	query := `
+		SELECT p.*, COALESCE(SUM(ip.quantidade), 0) as total_vendas
+		FROM produto p
+		LEFT JOIN item_pedido ip ON p.id = ip.id_produto
+		GROUP BY p.id
+		ORDER BY total_vendas DESC, p.id
+		LIMIT 5
+	`
+	var products []model.Product
+	if err := s.db.Raw(query).Scan(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// As datas são gravadas como texto com fuso horário, então as comparações
+// passam por julianday() para não depender da ordem lexicográfica.
+func (s *SQLiteRepository) GetLastMonthPixPayments() ([]model.Payment, error) {
+	query := `
+		SELECT * FROM pagamento
+		WHERE tipo = ? AND julianday(data_pagamento) BETWEEN julianday(?) AND julianday(?)
+	`
+	var payments []model.Payment
+	if err := s.db.Raw(query, model.PaymentTypePix, time.Now().AddDate(0, -1, 0), time.Now()).Scan(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (s *SQLiteRepository) GetClientTotalSpentByPeriod(clientID uint, startDate time.Time, endDate time.Time) (model.Money, error) {
+	query := `
+		SELECT COALESCE(SUM(valor - valor_estornado), 0)
+		FROM pagamento
+		WHERE id_cliente = ? AND julianday(data_pagamento) BETWEEN julianday(?) AND julianday(?)
+	`
+	var total model.Money
+	if err := s.db.Raw(query, clientID, startDate, endDate).Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// RetrySafeWrites vale enquanto não há TransactionPerBatch.
+func (s *SQLiteRepository) RetrySafeWrites() bool {
+	return !s.writes.TransactionPerBatch
+}
+
+func (s *SQLiteRepository) SetWriteOptions(opts WriteOptions) {
+	s.writes = opts
+}
+
+func NewSQLiteRepository() *SQLiteRepository {
+	config := config.LoadSQLiteConfig()
+
+	// Com _txlock=immediate as transações pegam a trava de escrita no BEGIN.
+	// Transações que leem antes de gravar, como a de um estorno, esperam a vez
+	// em vez de falhar com "database is locked" ao tentar gravar.
+	dsn := fmt.Sprintf("%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", config.Path)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao conectar com o SQLite: %v", err))
+	}
+
+	if err := db.Exec(config.Schema).Error; err != nil {
+		panic(fmt.Sprintf("Erro ao criar o schema do SQLite: %v", err))
+	}
+
+	return &SQLiteRepository{db: db}
+}
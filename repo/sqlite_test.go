@@ -0,0 +1,26 @@
+package repo_test
+
+import (
+	"path/filepath"
+	"techmarket_showcase/repo"
+	"techmarket_showcase/repo/conformance"
+	"testing"
+)
+
+func TestSQLiteRepositoryConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+		t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "techmarket.db"))
+		return repo.NewSQLiteRepository()
+	})
+}
+
+// Lotes pequenos em transações próprias gravadas em paralelo precisam chegar
+// ao mesmo resultado da transação única.
+func TestSQLiteRepositoryConformanceWithWriteOptions(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) repo.TechMarketRepository {
+		t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "techmarket.db"))
+		r := repo.NewSQLiteRepository()
+		r.SetWriteOptions(repo.WriteOptions{BatchSize: 2, TransactionPerBatch: true, Parallelism: 4})
+		return r
+	})
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"techmarket_showcase/model"
+
+	"gorm.io/gorm"
+)
+
+// validateRecords valida todo o lote antes de qualquer gravação, para que cada
+// backend rejeite os mesmos registros com os mesmos erros. O erro retornado
+// junta um *model.ValidationError por registro inválido.
+func validateRecords[T interface{ Validate() error }](records []T) error {
+	var errs []error
+	for _, record := range records {
+		if err := record.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// orderClientsFunc retorna o cliente de cada pedido informado que existe no
+// banco.
+type orderClientsFunc func(orderIDs []uint) (map[uint]uint, error)
+
+// validatePayments valida os pagamentos e confere, com orderClients, que cada
+// pedido existe e pertence ao cliente do pagamento.
+func validatePayments(payments []model.Payment, orderClients orderClientsFunc) error {
+	if err := validateRecords(payments); err != nil {
+		return err
+	}
+
+	ids := make(map[uint]bool)
+	for _, payment := range payments {
+		ids[payment.OrderID] = true
+	}
+	clients, err := orderClients(slices.Sorted(maps.Keys(ids)))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, payment := range payments {
+		v := &model.ValidationError{Entity: "pagamento", ID: payment.ID}
+		clientID, ok := clients[payment.OrderID]
+		switch {
+		case !ok:
+			v.Add("id_pedido", "pedido %d não encontrado", payment.OrderID)
+		case clientID != payment.ClientID:
+			v.Add("id_cliente", "o pedido %d é do cliente %d", payment.OrderID, clientID)
+		}
+		if err := v.Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// gormOrderClients consulta a tabela pedido em blocos, abaixo do limite de
+// parâmetros por comando do SQLite.
+func gormOrderClients(db *gorm.DB) orderClientsFunc {
+	return func(orderIDs []uint) (map[uint]uint, error) {
+		clients := make(map[uint]uint)
+		for chunk := range slices.Chunk(orderIDs, 500) {
+			var rows []struct {
+				ID       uint `gorm:"column:id"`
+				ClientID uint `gorm:"column:id_cliente"`
+			}
+			if err := db.Raw(`SELECT id, id_cliente FROM pedido WHERE id IN ?`, chunk).Scan(&rows).Error; err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				clients[row.ID] = row.ClientID
+			}
+		}
+		return clients, nil
+	}
+}
@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// WriteOptions ajusta como os métodos Batch* gravam os registros. Campos zero
+// mantêm o comportamento padrão de cada banco.
+type WriteOptions struct {
+	// BatchSize é o número de registros por comando: INSERT com vários VALUES,
+	// COPY, InsertMany/BulkWrite ou batch do Cassandra.
+	BatchSize int
+	// Unordered deixa o MongoDB gravar os documentos de um lote fora de ordem e
+	// continuar após um erro.
+	Unordered bool
+	// TransactionPerBatch confirma cada lote em sua própria transação nos
+	// bancos relacionais, em vez de uma transação por chamada. As gravações
+	// deixam de ser tudo ou nada: sem ela, repetir um Batch* após uma falha não
+	// duplica registros; com ela, os lotes já confirmados seriam gravados de
+	// novo, e RetrySafeWrites passa a ser false.
+	TransactionPerBatch bool
+	// Parallelism é o número de lotes gravados ao mesmo tempo. Nos bancos
+	// relacionais só vale com TransactionPerBatch, já que uma transação usa uma
+	// única conexão.
+	Parallelism int
+}
+
+// WriteTunable é implementado pelos repositórios que aceitam WriteOptions. As
+// opções devem ser definidas antes de envolver o repositório em decoradores,
+// já que o ResilientRepository consulta RetrySafeWrites ao ser criado.
+type WriteTunable interface {
+	SetWriteOptions(opts WriteOptions)
+}
+
+var (
+	_ WriteTunable = &PostgresRepository{}
+	_ WriteTunable = &PostgresCopyRepository{}
+	_ WriteTunable = &SQLiteRepository{}
+	_ WriteTunable = &MongoDBRepository{}
+	_ WriteTunable = &MongoDBReferencedRepository{}
+	_ WriteTunable = &CassandraRepository{}
+)
+
+// String descreve as opções no formato usado pelo relatório do benchmark.
+func (o WriteOptions) String() string {
+	batchSize := "padrão"
+	if o.BatchSize > 0 {
+		batchSize = fmt.Sprint(o.BatchSize)
+	}
+	transaction := "chamada"
+	if o.TransactionPerBatch {
+		transaction = "lote"
+	}
+	order := "ordenada"
+	if o.Unordered {
+		order = "livre"
+	}
+
+	return strings.Join([]string{
+		"lote=" + batchSize,
+		"transação=" + transaction,
+		"ordem=" + order,
+		fmt.Sprintf("paralelo=%d", o.parallelism()),
+	}, " ")
+}
+
+// batchSizeOr retorna BatchSize ou, se não definido, o padrão do banco.
+func (o WriteOptions) batchSizeOr(defaultSize int) int {
+	if o.BatchSize > 0 {
+		return o.BatchSize
+	}
+	return max(defaultSize, 1)
+}
+
+func (o WriteOptions) parallelism() int {
+	return max(o.Parallelism, 1)
+}
+
+// gormWrite grava records na tabela em lotes, todos em uma transação ou, com
+// TransactionPerBatch, cada lote na sua, com até Parallelism lotes ao mesmo
+// tempo.
+func gormWrite[T any](db *gorm.DB, opts WriteOptions, defaultBatchSize int, table string, records []T) error {
+	batches := slices.Collect(slices.Chunk(records, opts.batchSizeOr(defaultBatchSize)))
+
+	if !opts.TransactionPerBatch {
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, batch := range batches {
+				if err := tx.Table(table).Create(batch).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	return forEachParallel(batches, opts.parallelism(), func(batch []T) error {
+		return db.Table(table).Create(batch).Error
+	})
+}
+
+// forEachParallel chama fn para cada item com no máximo parallelism chamadas
+// ao mesmo tempo. Após o primeiro erro nenhuma outra chamada é iniciada; as que
+// já estavam em andamento terminam e o primeiro erro é retornado.
+func forEachParallel[T any](items []T, parallelism int, fn func(T) error) error {
+	if parallelism <= 1 {
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	slots := make(chan struct{}, parallelism)
+	for _, item := range items {
+		slots <- struct{}{}
+		if failed() {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := fn(item); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
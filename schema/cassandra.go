@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"techmarket_showcase/config"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+var _ Engine = &CassandraEngine{}
+
+// CassandraEngine aplica os scripts de schema/cassandra. O CQL não tem
+// transações: se um comando falhar, os anteriores permanecem e a versão não é
+// registrada, por isso os scripts usam IF NOT EXISTS / IF EXISTS e podem ser
+// executados de novo.
+type CassandraEngine struct {
+	db      *gocql.Session
+	scripts []script
+}
+
+func NewCassandraEngine() *CassandraEngine {
+	config := config.LoadCassandraConfig()
+
+	cluster := gocql.NewCluster(config.Hosts...)
+	cluster.Consistency = config.Consistency
+	cluster.ConnectTimeout = config.Timeout
+	cluster.Timeout = config.Timeout
+
+	// O keyspace precisa existir antes de abrir a sessão que o utiliza.
+	session, err := cluster.CreateSession()
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao conectar com o Cassandra: %v", err))
+	}
+	err = session.Query(fmt.Sprintf(`
+		CREATE KEYSPACE IF NOT EXISTS %s
+		WITH REPLICATION = { 'class' : 'SimpleStrategy', 'replication_factor' : 1 }
+	`, config.Keyspace)).Exec()
+	session.Close()
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao criar o keyspace %s: %v", config.Keyspace, err))
+	}
+
+	cluster.Keyspace = config.Keyspace
+	session, err = cluster.CreateSession()
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao conectar com o Cassandra: %v", err))
+	}
+
+	scripts, err := loadScripts(scriptFiles, "cassandra", ".cql")
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao carregar migrações do Cassandra: %v", err))
+	}
+
+	return &CassandraEngine{db: session, scripts: scripts}
+}
+
+func (c *CassandraEngine) Migrations() []Migration {
+	return migrationsOf(c.scripts)
+}
+
+func (c *CassandraEngine) Init(ctx context.Context) error {
+	return c.db.Query(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			versao int PRIMARY KEY,
+			nome text,
+			aplicada_em timestamp
+		)
+	`).WithContext(ctx).Exec()
+}
+
+func (c *CassandraEngine) Applied(ctx context.Context) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+	iter := c.db.Query(`SELECT versao, aplicada_em FROM schema_migrations`).WithContext(ctx).Iter()
+
+	var (
+		version   int
+		appliedAt time.Time
+	)
+	for iter.Scan(&version, &appliedAt) {
+		applied[version] = appliedAt
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (c *CassandraEngine) Up(ctx context.Context, m Migration) error {
+	s, err := findScript(c.scripts, m.Version)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range s.up {
+		if err := c.db.Query(statement).WithContext(ctx).Exec(); err != nil {
+			return err
+		}
+	}
+	return c.db.Query(`INSERT INTO schema_migrations (versao, nome, aplicada_em) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now()).WithContext(ctx).Exec()
+}
+
+func (c *CassandraEngine) Down(ctx context.Context, m Migration) error {
+	s, err := findScript(c.scripts, m.Version)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range s.down {
+		if err := c.db.Query(statement).WithContext(ctx).Exec(); err != nil {
+			return err
+		}
+	}
+	return c.db.Query(`DELETE FROM schema_migrations WHERE versao = ?`, m.Version).WithContext(ctx).Exec()
+}
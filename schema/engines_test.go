@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// Aplica todas as migrações, reverte todas e aplica de novo, garantindo que os
+// scripts .down desfazem os .up. Exige um banco descartável.
+func testRoundTrip(t *testing.T, engine Engine) {
+	runner := NewRunner(engine)
+	ctx := context.Background()
+	total := len(engine.Migrations())
+
+	if _, err := runner.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if reverted, err := runner.Down(ctx, total); err != nil || len(reverted) != total {
+		t.Fatalf("Down = %v, %v", reverted, err)
+	}
+	if applied, err := runner.Up(ctx, 0); err != nil || len(applied) != total {
+		t.Fatalf("Up = %v, %v", applied, err)
+	}
+
+	status, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if s.AppliedAt.IsZero() {
+			t.Errorf("migração %04d_%s pendente", s.Version, s.Name)
+		}
+	}
+}
+
+func TestPostgresEngine(t *testing.T) {
+	if os.Getenv("POSTGRES_URI") == "" {
+		t.Skip("POSTGRES_URI não definido")
+	}
+	testRoundTrip(t, NewPostgresEngine())
+}
+
+func TestMongoDBEngine(t *testing.T) {
+	if os.Getenv("MONGODB_URI") == "" {
+		t.Skip("MONGODB_URI não definido")
+	}
+	testRoundTrip(t, NewMongoDBEngine())
+}
+
+func TestCassandraEngine(t *testing.T) {
+	if os.Getenv("CASSANDRA_HOST") == "" {
+		t.Skip("CASSANDRA_HOST não definido")
+	}
+	testRoundTrip(t, NewCassandraEngine())
+}
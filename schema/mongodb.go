@@ -0,0 +1,504 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"techmarket_showcase/config"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ Engine = &MongoDBEngine{}
+
+// As migrações do MongoDB são código, já que coleções, índices e validadores
+// são criados por comandos do driver e não por um script.
+type mongoMigration struct {
+	Migration
+	up   func(ctx context.Context, db *mongo.Database) error
+	down func(ctx context.Context, db *mongo.Database) error
+}
+
+var mongoMigrations = []mongoMigration{
+	{
+		Migration: Migration{Version: 1, Name: "colecoes_e_indices"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{"clientes", "produtos", "pagamentos"} {
+				if err := createCollection(ctx, db, name); err != nil {
+					return err
+				}
+			}
+
+			indexes := map[string]mongo.IndexModel{
+				"clientes":   {Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+				"produtos":   {Keys: bson.D{{Key: "categoria", Value: 1}, {Key: "preco", Value: 1}}},
+				"pagamentos": {Keys: bson.D{{Key: "tipo", Value: 1}, {Key: "data_pagamento", Value: -1}}},
+			}
+			for collection, index := range indexes {
+				if _, err := db.Collection(collection).Indexes().CreateOne(ctx, index); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{"pagamentos", "produtos", "clientes"} {
+				if err := db.Collection(name).Drop(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Migration: Migration{Version: 2, Name: "validadores"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			validators := map[string]bson.M{
+				"clientes": {
+					"bsonType": "object",
+					"required": bson.A{"nome", "email", "cpf"},
+					"properties": bson.M{
+						"nome":    bson.M{"bsonType": "string"},
+						"email":   bson.M{"bsonType": "string"},
+						"cpf":     bson.M{"bsonType": "string"},
+						"pedidos": bson.M{"bsonType": "array"},
+					},
+				},
+				"produtos": {
+					"bsonType": "object",
+					"required": bson.A{"nome", "categoria", "preco", "estoque"},
+					"properties": bson.M{
+						"nome":      bson.M{"bsonType": "string"},
+						"categoria": bson.M{"bsonType": "string"},
+						"preco":     bson.M{"bsonType": bson.A{"double", "int", "long", "decimal"}, "minimum": 0},
+						"estoque":   bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0},
+					},
+				},
+				"pagamentos": {
+					"bsonType": "object",
+					"required": bson.A{"tipo", "status", "data_pagamento", "pedido_id"},
+					"properties": bson.M{
+						"tipo":           bson.M{"bsonType": "string"},
+						"status":         bson.M{"bsonType": "string"},
+						"data_pagamento": bson.M{"bsonType": "date"},
+					},
+				},
+			}
+
+			// "moderate" não bloqueia atualizações de documentos antigos que já
+			// estavam fora do formato.
+			for collection, schema := range validators {
+				err := db.RunCommand(ctx, bson.D{
+					{Key: "collMod", Value: collection},
+					{Key: "validator", Value: bson.M{"$jsonSchema": schema}},
+					{Key: "validationLevel", Value: "moderate"},
+					{Key: "validationAction", Value: "error"},
+				}).Err()
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			for _, collection := range []string{"clientes", "produtos", "pagamentos"} {
+				err := db.RunCommand(ctx, bson.D{
+					{Key: "collMod", Value: collection},
+					{Key: "validator", Value: bson.M{}},
+					{Key: "validationLevel", Value: "off"},
+				}).Err()
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Coleção pré-agregada mantida por repo.MongoDBRepository com
+		// MONGODB_SALES_COLLECTION=true. É populada a partir dos pedidos já
+		// gravados, então pode ser ativada em um banco existente.
+		Migration: Migration{Version: 3, Name: "vendas_por_produto"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createCollection(ctx, db, "vendas_por_produto"); err != nil {
+				return err
+			}
+
+			index := mongo.IndexModel{Keys: bson.D{{Key: "total_vendido", Value: -1}, {Key: "_id", Value: 1}}}
+			if _, err := db.Collection("vendas_por_produto").Indexes().CreateOne(ctx, index); err != nil {
+				return err
+			}
+
+			cursor, err := db.Collection("clientes").Aggregate(ctx, mongo.Pipeline{
+				{{Key: "$unwind", Value: "$pedidos"}},
+				{{Key: "$unwind", Value: "$pedidos.itens"}},
+				{{Key: "$group", Value: bson.M{"_id": "$pedidos.itens.produto_id", "total_vendido": bson.M{"$sum": "$pedidos.itens.quantidade"}}}},
+				{{Key: "$merge", Value: bson.M{"into": "vendas_por_produto", "whenMatched": "replace"}}},
+			})
+			if err != nil {
+				return err
+			}
+			return cursor.Close(ctx)
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("vendas_por_produto").Drop(ctx)
+		},
+	},
+	{
+		// model.Money grava os valores monetários como Decimal128; os
+		// documentos antigos guardavam double.
+		Migration: Migration{Version: 4, Name: "valores_decimal128"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			return convertMoney(ctx, db, "$toDecimal")
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return convertMoney(ctx, db, "$toDouble")
+		},
+	},
+	{
+		// Os pagamentos passam a guardar id_cliente, valor e parcelas. Os já
+		// gravados recebem o cliente e o valor total do pedido embutido.
+		Migration: Migration{Version: 5, Name: "valor_do_pagamento"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			index := mongo.IndexModel{Keys: bson.D{{Key: "id_cliente", Value: 1}, {Key: "data_pagamento", Value: 1}}}
+			if _, err := db.Collection("pagamentos").Indexes().CreateOne(ctx, index); err != nil {
+				return err
+			}
+
+			cursor, err := db.Collection("clientes").Aggregate(ctx, mongo.Pipeline{
+				{{Key: "$unwind", Value: "$pedidos"}},
+				{{Key: "$project", Value: bson.M{"_id": 0, "id_cliente": "$_id", "pedido_id": "$pedidos.pedido_id", "valor": "$pedidos.valor_total"}}},
+			})
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+
+			var updates []mongo.WriteModel
+			flush := func() error {
+				if len(updates) == 0 {
+					return nil
+				}
+				_, err := db.Collection("pagamentos").BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false))
+				updates = updates[:0]
+				return err
+			}
+			for cursor.Next(ctx) {
+				var order struct {
+					ClientID any `bson:"id_cliente"`
+					OrderID  any `bson:"pedido_id"`
+					Value    any `bson:"valor"`
+				}
+				if err := cursor.Decode(&order); err != nil {
+					return err
+				}
+				updates = append(updates, mongo.NewUpdateManyModel().
+					SetFilter(bson.M{"pedido_id": order.OrderID, "valor": bson.M{"$exists": false}}).
+					SetUpdate(bson.M{"$set": bson.M{"id_cliente": order.ClientID, "valor": order.Value, "parcelas": 1}}))
+				if len(updates) == 1000 {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+			if err := cursor.Err(); err != nil {
+				return err
+			}
+			return flush()
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("pagamentos").UpdateMany(ctx, bson.M{},
+				bson.M{"$unset": bson.M{"id_cliente": "", "valor": "", "parcelas": ""}})
+			if err != nil {
+				return err
+			}
+			_, err = db.Collection("pagamentos").Indexes().DropOne(ctx, "id_cliente_1_data_pagamento_1")
+			return err
+		},
+	},
+	{
+		// Antes de gravar um pagamento o repositório confere que o pedido
+		// existe, procurando-o entre os pedidos embutidos em clientes.
+		Migration: Migration{Version: 6, Name: "indice_pedido_embutido"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			index := mongo.IndexModel{Keys: bson.D{{Key: "pedidos.pedido_id", Value: 1}}}
+			_, err := db.Collection("clientes").Indexes().CreateOne(ctx, index)
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("clientes").Indexes().DropOne(ctx, "pedidos.pedido_id_1")
+			return err
+		},
+	},
+	{
+		// Os estornos ficam embutidos no pagamento, em estornos, e somados em
+		// valor_estornado. Pagamentos gravados como "Estornado" antes disso
+		// foram devolvidos por inteiro. O índice atende a soma dos estornos de
+		// um pedido.
+		Migration: Migration{Version: 7, Name: "estornos"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			index := mongo.IndexModel{Keys: bson.D{{Key: "pedido_id", Value: 1}}}
+			if _, err := db.Collection("pagamentos").Indexes().CreateOne(ctx, index); err != nil {
+				return err
+			}
+
+			_, err := db.Collection("pagamentos").UpdateMany(ctx,
+				bson.M{"status": "Estornado", "valor_estornado": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{"valor_estornado": "$valor"}}}},
+			)
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("pagamentos").UpdateMany(ctx, bson.M{},
+				bson.M{"$unset": bson.M{"valor_estornado": "", "estornos": ""}})
+			if err != nil {
+				return err
+			}
+			_, err = db.Collection("pagamentos").Indexes().DropOne(ctx, "pedido_id_1")
+			return err
+		},
+	},
+	{
+		// Avaliações em uma coleção própria, com a categoria do produto copiada
+		// para agrupar as notas sem $lookup. O índice atende a paginação das
+		// avaliações de um produto.
+		Migration: Migration{Version: 8, Name: "avaliacoes"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			err := db.CreateCollection(ctx, "avaliacoes", options.CreateCollection().SetValidator(bson.M{"$jsonSchema": bson.M{
+				"bsonType": "object",
+				"required": bson.A{"id_produto", "id_cliente", "nota", "categoria", "data_avaliacao"},
+				"properties": bson.M{
+					"nota":           bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 1, "maximum": 5},
+					"categoria":      bson.M{"bsonType": "string"},
+					"comentario":     bson.M{"bsonType": "string"},
+					"data_avaliacao": bson.M{"bsonType": "date"},
+				},
+			}}))
+			if err != nil {
+				return err
+			}
+
+			index := mongo.IndexModel{Keys: bson.D{{Key: "id_produto", Value: 1}, {Key: "_id", Value: -1}}}
+			_, err = db.Collection("avaliacoes").Indexes().CreateOne(ctx, index)
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("avaliacoes").Drop(ctx)
+		},
+	},
+	{
+		// Envios com a linha do tempo de rastreamento embutida em eventos,
+		// mantida em ordem de data pelo $push. O índice atende o histórico de um
+		// pedido.
+		Migration: Migration{Version: 9, Name: "envios"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createCollection(ctx, db, "envios"); err != nil {
+				return err
+			}
+			index := mongo.IndexModel{Keys: bson.D{{Key: "pedido_id", Value: 1}, {Key: "data_envio", Value: 1}}}
+			_, err := db.Collection("envios").Indexes().CreateOne(ctx, index)
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("envios").Drop(ctx)
+		},
+	},
+	{
+		// Endereços de entrega embutidos em clientes.enderecos. O índice
+		// multichave atende a validação do endereço dos pedidos.
+		Migration: Migration{Version: 10, Name: "enderecos"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			index := mongo.IndexModel{Keys: bson.D{{Key: "enderecos._id", Value: 1}}}
+			_, err := db.Collection("clientes").Indexes().CreateOne(ctx, index)
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			if _, err := db.Collection("clientes").Indexes().DropOne(ctx, "enderecos._id_1"); err != nil {
+				return err
+			}
+			_, err := db.Collection("clientes").UpdateMany(ctx,
+				bson.M{"enderecos": bson.M{"$exists": true}},
+				bson.M{"$unset": bson.M{"enderecos": ""}})
+			return err
+		},
+	},
+	{
+		// Cupons de desconto e os usos de cada cupom por cliente, com _id
+		// {codigo, id_cliente}. O índice multichave atende o resumo dos pedidos
+		// feitos com um cupom.
+		Migration: Migration{Version: 11, Name: "cupons"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{"cupons", "usos_cupom"} {
+				if err := createCollection(ctx, db, name); err != nil {
+					return err
+				}
+			}
+			index := mongo.IndexModel{Keys: bson.D{{Key: "pedidos.codigo_cupom", Value: 1}}, Options: options.Index().SetSparse(true)}
+			_, err := db.Collection("clientes").Indexes().CreateOne(ctx, index)
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			if _, err := db.Collection("clientes").Indexes().DropOne(ctx, "pedidos.codigo_cupom_1"); err != nil {
+				return err
+			}
+			_, err := db.Collection("clientes").UpdateMany(ctx,
+				bson.M{"pedidos.codigo_cupom": bson.M{"$exists": true}},
+				bson.M{"$unset": bson.M{"pedidos.$[].codigo_cupom": "", "pedidos.$[].desconto": ""}})
+			if err != nil {
+				return err
+			}
+			for _, name := range []string{"usos_cupom", "cupons"} {
+				if err := db.Collection(name).Drop(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// convertMoney aplica o operador de conversão (como $toDecimal) aos preços dos
+// produtos e aos valores dos pedidos embutidos em clientes e de seus itens.
+// Decimal128 é arredondado para duas casas, já que o double de origem pode não
+// representar os centavos exatamente.
+func convertMoney(ctx context.Context, db *mongo.Database, operator string) error {
+	convert := func(field string) bson.M {
+		value := bson.M{operator: field}
+		if operator == "$toDecimal" {
+			return bson.M{"$round": bson.A{value, 2}}
+		}
+		return value
+	}
+
+	_, err := db.Collection("produtos").UpdateMany(ctx,
+		bson.M{"preco": bson.M{"$exists": true}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"preco": convert("$preco")}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("clientes").UpdateMany(ctx,
+		bson.M{"pedidos.0": bson.M{"$exists": true}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"pedidos": bson.M{"$map": bson.M{
+			"input": "$pedidos",
+			"as":    "pedido",
+			"in": bson.M{"$mergeObjects": bson.A{"$$pedido", bson.M{
+				"valor_total": convert("$$pedido.valor_total"),
+				"itens": bson.M{"$map": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$$pedido.itens", bson.A{}}},
+					"as":    "item",
+					"in": bson.M{"$mergeObjects": bson.A{"$$item", bson.M{
+						"preco_unitario": convert("$$item.preco_unitario"),
+					}}},
+				}},
+			}}},
+		}}}}}},
+	)
+	return err
+}
+
+// MongoDBEngine aplica mongoMigrations no banco techmarket_db, o mesmo usado
+// por repo.MongoDBRepository.
+type MongoDBEngine struct {
+	db *mongo.Database
+}
+
+func NewMongoDBEngine() *MongoDBEngine {
+	config := config.LoadMongoDBConfig()
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(config.URI))
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao conectar com o MongoDB: %v", err))
+	}
+
+	return &MongoDBEngine{db: client.Database("techmarket_db")}
+}
+
+func (m *MongoDBEngine) Migrations() []Migration {
+	migrations := make([]Migration, len(mongoMigrations))
+	for i, migration := range mongoMigrations {
+		migrations[i] = migration.Migration
+	}
+	return migrations
+}
+
+// A coleção schema_migrations é criada na primeira gravação.
+func (m *MongoDBEngine) Init(ctx context.Context) error {
+	return nil
+}
+
+func (m *MongoDBEngine) Applied(ctx context.Context) (map[int]time.Time, error) {
+	cursor, err := m.db.Collection("schema_migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []struct {
+		Versao     int       `bson:"_id"`
+		AplicadaEm time.Time `bson:"aplicada_em"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time, len(docs))
+	for _, doc := range docs {
+		applied[doc.Versao] = doc.AplicadaEm
+	}
+	return applied, nil
+}
+
+func (m *MongoDBEngine) Up(ctx context.Context, migration Migration) error {
+	mm, err := findMongoMigration(migration.Version)
+	if err != nil {
+		return err
+	}
+	if err := mm.up(ctx, m.db); err != nil {
+		return err
+	}
+
+	_, err = m.db.Collection("schema_migrations").InsertOne(ctx, bson.M{
+		"_id":         migration.Version,
+		"nome":        migration.Name,
+		"aplicada_em": time.Now(),
+	})
+	return err
+}
+
+func (m *MongoDBEngine) Down(ctx context.Context, migration Migration) error {
+	mm, err := findMongoMigration(migration.Version)
+	if err != nil {
+		return err
+	}
+	if err := mm.down(ctx, m.db); err != nil {
+		return err
+	}
+
+	_, err = m.db.Collection("schema_migrations").DeleteOne(ctx, bson.M{"_id": migration.Version})
+	return err
+}
+
+func findMongoMigration(version int) (mongoMigration, error) {
+	for _, m := range mongoMigrations {
+		if m.Version == version {
+			return m, nil
+		}
+	}
+	return mongoMigration{}, fmt.Errorf("migração %d desconhecida", version)
+}
+
+// createCollection ignora coleções que já existem, como as criadas pelo antigo
+// init-mongo.js do container.
+func createCollection(ctx context.Context, db *mongo.Database, name string) error {
+	err := db.CreateCollection(ctx, name)
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceExists" {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"techmarket_showcase/config"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var _ Engine = &PostgresEngine{}
+
+// PostgresEngine aplica os scripts de schema/postgres. O DDL do PostgreSQL é
+// transacional, então cada migração e o seu registro em schema_migrations são
+// gravados juntos ou não são gravados.
+type PostgresEngine struct {
+	db      *gorm.DB
+	scripts []script
+}
+
+func NewPostgresEngine() *PostgresEngine {
+	config := config.LoadPostgresConfig()
+
+	db, err := gorm.Open(postgres.Open(config.URI), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao conectar com o PostgreSQL: %v", err))
+	}
+
+	scripts, err := loadScripts(scriptFiles, "postgres", ".sql")
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao carregar migrações do PostgreSQL: %v", err))
+	}
+
+	return &PostgresEngine{db: db, scripts: scripts}
+}
+
+func (p *PostgresEngine) Migrations() []Migration {
+	return migrationsOf(p.scripts)
+}
+
+func (p *PostgresEngine) Init(ctx context.Context) error {
+	return p.db.WithContext(ctx).Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			versao INT PRIMARY KEY,
+			nome VARCHAR(255) NOT NULL,
+			aplicada_em TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`).Error
+}
+
+func (p *PostgresEngine) Applied(ctx context.Context) (map[int]time.Time, error) {
+	var rows []struct {
+		Versao     int       `gorm:"column:versao"`
+		AplicadaEm time.Time `gorm:"column:aplicada_em"`
+	}
+	if err := p.db.WithContext(ctx).Raw(`SELECT versao, aplicada_em FROM schema_migrations`).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Versao] = row.AplicadaEm
+	}
+	return applied, nil
+}
+
+func (p *PostgresEngine) Up(ctx context.Context, m Migration) error {
+	s, err := findScript(p.scripts, m.Version)
+	if err != nil {
+		return err
+	}
+
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range s.up {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(`INSERT INTO schema_migrations (versao, nome) VALUES (?, ?)`, m.Version, m.Name).Error
+	})
+}
+
+func (p *PostgresEngine) Down(ctx context.Context, m Migration) error {
+	s, err := findScript(p.scripts, m.Version)
+	if err != nil {
+		return err
+	}
+
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range s.down {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(`DELETE FROM schema_migrations WHERE versao = ?`, m.Version).Error
+	})
+}
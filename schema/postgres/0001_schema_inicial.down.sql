@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS pagamento;
+
+DROP TABLE IF EXISTS item_pedido;
+
+DROP TABLE IF EXISTS pedido;
+
+DROP TABLE IF EXISTS produto;
+
+DROP TABLE IF EXISTS cliente;
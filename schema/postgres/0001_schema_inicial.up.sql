@@ -1,4 +1,5 @@
-CREATE TABLE cliente (
+-- IF NOT EXISTS permite adotar bancos criados pelo antigo init.sql do container.
+CREATE TABLE IF NOT EXISTS cliente (
     id SERIAL PRIMARY KEY,
     nome VARCHAR(255) NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
@@ -7,9 +8,9 @@ CREATE TABLE cliente (
     cpf VARCHAR(14) UNIQUE NOT NULL
 );
 
-CREATE INDEX idx_cliente_email ON cliente (email);
+CREATE INDEX IF NOT EXISTS idx_cliente_email ON cliente (email);
 
-CREATE TABLE produto (
+CREATE TABLE IF NOT EXISTS produto (
     id SERIAL PRIMARY KEY,
     nome VARCHAR(255) NOT NULL,
     categoria VARCHAR(100) NOT NULL,
@@ -17,21 +18,21 @@ CREATE TABLE produto (
     estoque INT NOT NULL
 );
 
-CREATE INDEX idx_produto_categoria ON produto (categoria);
+CREATE INDEX IF NOT EXISTS idx_produto_categoria ON produto (categoria);
 
-CREATE TABLE pedido (
+CREATE TABLE IF NOT EXISTS pedido (
     id SERIAL PRIMARY KEY,
-    id_cliente INT NOT NULL REFERENCES Cliente (id),
+    id_cliente INT NOT NULL REFERENCES cliente (id),
     data_pedido TIMESTAMPTZ DEFAULT NOW(),
     status VARCHAR(50) NOT NULL,
     valor_total DECIMAL(10, 2) NOT NULL
 );
 
-CREATE INDEX idx_pedido_id_cliente ON pedido (id_cliente);
+CREATE INDEX IF NOT EXISTS idx_pedido_id_cliente ON pedido (id_cliente);
 
-CREATE INDEX idx_pedido_status ON pedido (status);
+CREATE INDEX IF NOT EXISTS idx_pedido_status ON pedido (status);
 
-CREATE TABLE item_pedido (
+CREATE TABLE IF NOT EXISTS item_pedido (
     id_pedido INT NOT NULL REFERENCES pedido (id),
     id_produto INT NOT NULL REFERENCES produto (id),
     quantidade INT NOT NULL,
@@ -39,7 +40,7 @@ CREATE TABLE item_pedido (
     PRIMARY KEY (id_pedido, id_produto)
 );
 
-CREATE TABLE pagamento (
+CREATE TABLE IF NOT EXISTS pagamento (
     id SERIAL PRIMARY KEY,
     id_pedido INT NOT NULL REFERENCES pedido (id),
     tipo VARCHAR(50) NOT NULL,
@@ -47,8 +48,8 @@ CREATE TABLE pagamento (
     data_pagamento TIMESTAMPTZ DEFAULT NOW()
 );
 
-CREATE INDEX idx_pagamento_id_pedido ON pagamento (id_pedido);
+CREATE INDEX IF NOT EXISTS idx_pagamento_id_pedido ON pagamento (id_pedido);
 
-CREATE INDEX idx_pagamento_tipo ON pagamento (tipo);
+CREATE INDEX IF NOT EXISTS idx_pagamento_tipo ON pagamento (tipo);
 
-CREATE INDEX idx_pagamento_data ON Pagamento (data_pagamento);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_pagamento_data ON pagamento (data_pagamento);
@@ -0,0 +1,17 @@
+-- Só é possível voltar para INT enquanto todos os IDs couberem em 32 bits.
+ALTER TABLE pagamento ALTER COLUMN id_pedido TYPE INT;
+ALTER SEQUENCE pagamento_id_seq AS INT;
+ALTER TABLE pagamento ALTER COLUMN id TYPE INT;
+
+ALTER TABLE item_pedido ALTER COLUMN id_produto TYPE INT;
+ALTER TABLE item_pedido ALTER COLUMN id_pedido TYPE INT;
+
+ALTER TABLE pedido ALTER COLUMN id_cliente TYPE INT;
+ALTER SEQUENCE pedido_id_seq AS INT;
+ALTER TABLE pedido ALTER COLUMN id TYPE INT;
+
+ALTER SEQUENCE produto_id_seq AS INT;
+ALTER TABLE produto ALTER COLUMN id TYPE INT;
+
+ALTER SEQUENCE cliente_id_seq AS INT;
+ALTER TABLE cliente ALTER COLUMN id TYPE INT;
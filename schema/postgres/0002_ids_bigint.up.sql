@@ -0,0 +1,18 @@
+-- Os IDs passam a ser snowflakes de 64 bits atribuídos pela aplicação
+-- (model.IDGenerator). As sequências continuam atendendo inserções sem ID.
+ALTER TABLE cliente ALTER COLUMN id TYPE BIGINT;
+ALTER SEQUENCE cliente_id_seq AS BIGINT;
+
+ALTER TABLE produto ALTER COLUMN id TYPE BIGINT;
+ALTER SEQUENCE produto_id_seq AS BIGINT;
+
+ALTER TABLE pedido ALTER COLUMN id TYPE BIGINT;
+ALTER SEQUENCE pedido_id_seq AS BIGINT;
+ALTER TABLE pedido ALTER COLUMN id_cliente TYPE BIGINT;
+
+ALTER TABLE item_pedido ALTER COLUMN id_pedido TYPE BIGINT;
+ALTER TABLE item_pedido ALTER COLUMN id_produto TYPE BIGINT;
+
+ALTER TABLE pagamento ALTER COLUMN id TYPE BIGINT;
+ALTER SEQUENCE pagamento_id_seq AS BIGINT;
+ALTER TABLE pagamento ALTER COLUMN id_pedido TYPE BIGINT;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_pagamento_cliente_data;
+
+ALTER TABLE pagamento DROP COLUMN IF EXISTS parcelas;
+ALTER TABLE pagamento DROP COLUMN IF EXISTS valor;
+ALTER TABLE pagamento DROP COLUMN IF EXISTS id_cliente;
@@ -0,0 +1,16 @@
+-- O pagamento passa a guardar o cliente, o valor pago e o número de parcelas,
+-- então o total gasto por cliente não depende mais do valor do pedido.
+ALTER TABLE pagamento ADD COLUMN IF NOT EXISTS id_cliente BIGINT REFERENCES cliente (id);
+ALTER TABLE pagamento ADD COLUMN IF NOT EXISTS valor DECIMAL(10, 2);
+ALTER TABLE pagamento ADD COLUMN IF NOT EXISTS parcelas INT NOT NULL DEFAULT 1;
+
+-- Pagamentos já gravados recebem o cliente e o valor total do pedido.
+UPDATE pagamento pg
+SET id_cliente = pe.id_cliente, valor = pe.valor_total
+FROM pedido pe
+WHERE pe.id = pg.id_pedido AND (pg.id_cliente IS NULL OR pg.valor IS NULL);
+
+ALTER TABLE pagamento ALTER COLUMN id_cliente SET NOT NULL;
+ALTER TABLE pagamento ALTER COLUMN valor SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_pagamento_cliente_data ON pagamento (id_cliente, data_pagamento);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS estorno;
+
+ALTER TABLE pagamento DROP CONSTRAINT IF EXISTS chk_pagamento_valor_estornado;
+ALTER TABLE pagamento DROP COLUMN IF EXISTS valor_estornado;
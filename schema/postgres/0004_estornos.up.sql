@@ -0,0 +1,21 @@
+-- Estornos totais ou parciais de um pagamento. valor_estornado acumula os
+-- estornos do pagamento e é descontado do total gasto pelo cliente.
+ALTER TABLE pagamento ADD COLUMN IF NOT EXISTS valor_estornado DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+-- Pagamentos gravados como "Estornado" antes dos estornos foram devolvidos por
+-- inteiro.
+UPDATE pagamento SET valor_estornado = valor WHERE status = 'Estornado';
+
+ALTER TABLE pagamento ADD CONSTRAINT chk_pagamento_valor_estornado CHECK (valor_estornado BETWEEN 0 AND valor);
+
+CREATE TABLE IF NOT EXISTS estorno (
+    id BIGSERIAL PRIMARY KEY,
+    id_pagamento BIGINT NOT NULL REFERENCES pagamento (id),
+    id_pedido BIGINT NOT NULL REFERENCES pedido (id),
+    id_cliente BIGINT NOT NULL REFERENCES cliente (id),
+    valor DECIMAL(10, 2) NOT NULL CHECK (valor > 0),
+    motivo TEXT NOT NULL DEFAULT '',
+    data_estorno TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_estorno_id_pagamento ON estorno (id_pagamento, data_estorno);
@@ -0,0 +1,12 @@
+-- Avaliações de produtos. O índice (id_produto, id) atende a paginação das
+-- avaliações de um produto, da mais recente para a mais antiga.
+CREATE TABLE IF NOT EXISTS avaliacao (
+    id BIGINT PRIMARY KEY,
+    id_produto BIGINT NOT NULL REFERENCES produto (id),
+    id_cliente BIGINT NOT NULL REFERENCES cliente (id),
+    nota SMALLINT NOT NULL CHECK (nota BETWEEN 1 AND 5),
+    comentario TEXT NOT NULL DEFAULT '',
+    data_avaliacao TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_avaliacao_produto ON avaliacao (id_produto, id DESC);
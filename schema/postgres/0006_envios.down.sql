@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS evento_rastreio;
+
+DROP TABLE IF EXISTS envio;
@@ -0,0 +1,28 @@
+-- Envios dos pedidos, com uma cópia do endereço de entrega, e a linha do tempo
+-- de rastreamento de cada um. Os eventos só recebem INSERTs.
+CREATE TABLE IF NOT EXISTS envio (
+    id BIGINT PRIMARY KEY,
+    id_pedido BIGINT NOT NULL REFERENCES pedido (id),
+    transportadora VARCHAR(100) NOT NULL,
+    codigo_rastreio VARCHAR(50) NOT NULL,
+    cep CHAR(9) NOT NULL,
+    logradouro VARCHAR(200) NOT NULL,
+    numero VARCHAR(20) NOT NULL,
+    complemento VARCHAR(100) NOT NULL DEFAULT '',
+    cidade VARCHAR(100) NOT NULL,
+    uf CHAR(2) NOT NULL,
+    data_envio TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_envio_pedido ON envio (id_pedido);
+
+CREATE TABLE IF NOT EXISTS evento_rastreio (
+    id BIGSERIAL PRIMARY KEY,
+    id_envio BIGINT NOT NULL REFERENCES envio (id),
+    status VARCHAR(50) NOT NULL,
+    local VARCHAR(200) NOT NULL DEFAULT '',
+    descricao TEXT NOT NULL DEFAULT '',
+    data_evento TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_evento_rastreio_envio ON evento_rastreio (id_envio, data_evento);
@@ -0,0 +1,3 @@
+ALTER TABLE pedido DROP COLUMN IF EXISTS id_endereco;
+
+DROP TABLE IF EXISTS endereco;
@@ -0,0 +1,22 @@
+-- Endereços de entrega dos clientes. O índice único parcial garante no máximo
+-- um endereço padrão por cliente.
+CREATE TABLE IF NOT EXISTS endereco (
+    id BIGSERIAL PRIMARY KEY,
+    id_cliente BIGINT NOT NULL REFERENCES cliente (id),
+    cep CHAR(9) NOT NULL,
+    logradouro VARCHAR(200) NOT NULL,
+    numero VARCHAR(20) NOT NULL,
+    complemento VARCHAR(100) NOT NULL DEFAULT '',
+    cidade VARCHAR(100) NOT NULL,
+    uf CHAR(2) NOT NULL,
+    padrao BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_endereco_cliente ON endereco (id_cliente, id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_endereco_padrao ON endereco (id_cliente) WHERE padrao;
+
+-- Pedidos antigos ficam sem endereço de entrega.
+ALTER TABLE pedido ADD COLUMN IF NOT EXISTS id_endereco BIGINT REFERENCES endereco (id);
+
+CREATE INDEX IF NOT EXISTS idx_pedido_endereco ON pedido (id_endereco);
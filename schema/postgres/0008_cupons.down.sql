@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_pedido_cupom;
+
+ALTER TABLE pedido DROP COLUMN IF EXISTS desconto;
+ALTER TABLE pedido DROP COLUMN IF EXISTS codigo_cupom;
+
+DROP TABLE IF EXISTS uso_cupom;
+DROP TABLE IF EXISTS cupom_categoria;
+DROP TABLE IF EXISTS cupom;
@@ -0,0 +1,34 @@
+-- Cupons de desconto. As categorias ficam em cupom_categoria; um cupom sem
+-- categorias vale para todos os itens.
+CREATE TABLE IF NOT EXISTS cupom (
+    codigo VARCHAR(30) PRIMARY KEY,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('Percentual', 'Valor Fixo')),
+    percentual SMALLINT NOT NULL DEFAULT 0 CHECK (percentual BETWEEN 0 AND 100),
+    valor DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    valor_minimo DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    inicio_validade TIMESTAMPTZ NOT NULL,
+    fim_validade TIMESTAMPTZ NOT NULL,
+    limite_por_cliente INT NOT NULL CHECK (limite_por_cliente >= 1)
+);
+
+CREATE TABLE IF NOT EXISTS cupom_categoria (
+    codigo VARCHAR(30) NOT NULL REFERENCES cupom (codigo),
+    categoria VARCHAR(100) NOT NULL,
+    PRIMARY KEY (codigo, categoria)
+);
+
+-- Usos de cada cupom por cliente, contados por um upsert condicional ao
+-- limite.
+CREATE TABLE IF NOT EXISTS uso_cupom (
+    codigo VARCHAR(30) NOT NULL REFERENCES cupom (codigo),
+    id_cliente BIGINT NOT NULL REFERENCES cliente (id),
+    usos INT NOT NULL,
+    PRIMARY KEY (codigo, id_cliente)
+);
+
+-- O código não referencia cupom: pedidos copiados de outro banco mantêm o
+-- cupom com que foram feitos.
+ALTER TABLE pedido ADD COLUMN IF NOT EXISTS codigo_cupom VARCHAR(30) NOT NULL DEFAULT '';
+ALTER TABLE pedido ADD COLUMN IF NOT EXISTS desconto DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_pedido_cupom ON pedido (codigo_cupom);
@@ -0,0 +1,217 @@
+// Package schema aplica migrações numeradas de schema no PostgreSQL, no
+// Cassandra e no MongoDB. Cada banco guarda em schema_migrations as versões já
+// aplicadas, então os schemas evoluem sem recriar os volumes dos containers.
+package schema
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed postgres/*.sql cassandra/*.cql
+var scriptFiles embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+}
+
+// Status descreve uma migração conhecida. AppliedAt é zero enquanto a migração
+// estiver pendente.
+type Status struct {
+	Migration
+	AppliedAt time.Time
+}
+
+// Engine executa as migrações de um banco e registra quais foram aplicadas.
+type Engine interface {
+	Migrations() []Migration
+	// Init cria a estrutura que guarda o estado das migrações, se necessário.
+	Init(ctx context.Context) error
+	Applied(ctx context.Context) (map[int]time.Time, error)
+	Up(ctx context.Context, m Migration) error
+	Down(ctx context.Context, m Migration) error
+}
+
+type Runner struct {
+	engine Engine
+}
+
+func NewRunner(engine Engine) *Runner {
+	return &Runner{engine: engine}
+}
+
+// Up aplica, em ordem, as migrações pendentes com versão até target. Com
+// target igual a 0 todas as pendentes são aplicadas.
+func (r *Runner) Up(ctx context.Context, target int) ([]Migration, error) {
+	status, err := r.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, s := range status {
+		if !s.AppliedAt.IsZero() || (target > 0 && s.Version > target) {
+			continue
+		}
+		if err := r.engine.Up(ctx, s.Migration); err != nil {
+			return applied, fmt.Errorf("erro ao aplicar a migração %04d_%s: %w", s.Version, s.Name, err)
+		}
+		applied = append(applied, s.Migration)
+	}
+	return applied, nil
+}
+
+// Down reverte as últimas steps migrações aplicadas, da mais nova para a mais
+// antiga.
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	status, err := r.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for _, s := range slices.Backward(status) {
+		if len(reverted) == steps {
+			break
+		}
+		if s.AppliedAt.IsZero() {
+			continue
+		}
+		if err := r.engine.Down(ctx, s.Migration); err != nil {
+			return reverted, fmt.Errorf("erro ao reverter a migração %04d_%s: %w", s.Version, s.Name, err)
+		}
+		reverted = append(reverted, s.Migration)
+	}
+	return reverted, nil
+}
+
+// Status lista todas as migrações conhecidas em ordem de versão.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	if err := r.engine.Init(ctx); err != nil {
+		return nil, fmt.Errorf("erro ao preparar schema_migrations: %w", err)
+	}
+
+	applied, err := r.engine.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler schema_migrations: %w", err)
+	}
+
+	migrations := r.engine.Migrations()
+	status := make([]Status, len(migrations))
+	for i, m := range migrations {
+		status[i] = Status{Migration: m, AppliedAt: applied[m.Version]}
+	}
+	return status, nil
+}
+
+// script guarda os comandos de uma migração lida dos arquivos embutidos.
+type script struct {
+	Migration
+	up   []string
+	down []string
+}
+
+// loadScripts lê os arquivos NNNN_nome.up.ext e NNNN_nome.down.ext de dir. Toda
+// versão precisa ter os dois sentidos.
+func loadScripts(fsys fs.FS, dir string, ext string) ([]script, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*script)
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ext)
+		if !ok {
+			continue
+		}
+
+		base, direction := name, ""
+		if b, ok := strings.CutSuffix(name, ".up"); ok {
+			base, direction = b, "up"
+		} else if b, ok := strings.CutSuffix(name, ".down"); ok {
+			base, direction = b, "down"
+		} else {
+			return nil, fmt.Errorf("migração %s sem sentido .up ou .down", entry.Name())
+		}
+
+		number, title, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("nome de migração inválido: %s", entry.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		s, ok := byVersion[version]
+		if !ok {
+			s = &script{Migration: Migration{Version: version, Name: title}}
+			byVersion[version] = s
+		}
+		if s.Name != title {
+			return nil, fmt.Errorf("versão %d usada por duas migrações: %s e %s", version, s.Name, title)
+		}
+		if direction == "up" {
+			s.up = splitStatements(string(data))
+		} else {
+			s.down = splitStatements(string(data))
+		}
+	}
+
+	var result []script
+	for _, s := range byVersion {
+		if s.up == nil || s.down == nil {
+			return nil, fmt.Errorf("migração %04d_%s precisa dos arquivos .up e .down", s.Version, s.Name)
+		}
+		result = append(result, *s)
+	}
+	slices.SortFunc(result, func(a, b script) int { return a.Version - b.Version })
+	return result, nil
+}
+
+// splitStatements separa um script em comandos terminados por ";". Linhas de
+// comentário (--) são descartadas; os scripts não usam ";" dentro de textos.
+func splitStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	statements := []string{}
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+func migrationsOf(scripts []script) []Migration {
+	migrations := make([]Migration, len(scripts))
+	for i, s := range scripts {
+		migrations[i] = s.Migration
+	}
+	return migrations
+}
+
+func findScript(scripts []script, version int) (script, error) {
+	for _, s := range scripts {
+		if s.Version == version {
+			return s, nil
+		}
+	}
+	return script{}, fmt.Errorf("migração %d desconhecida", version)
+}
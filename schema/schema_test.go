@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// memoryEngine registra as migrações aplicadas em memória.
+type memoryEngine struct {
+	migrations []Migration
+	applied    map[int]time.Time
+	calls      []string
+}
+
+func (e *memoryEngine) Migrations() []Migration        { return e.migrations }
+func (e *memoryEngine) Init(ctx context.Context) error { return nil }
+
+func (e *memoryEngine) Applied(ctx context.Context) (map[int]time.Time, error) {
+	return e.applied, nil
+}
+
+func (e *memoryEngine) Up(ctx context.Context, m Migration) error {
+	e.applied[m.Version] = time.Now()
+	e.calls = append(e.calls, "up:"+m.Name)
+	return nil
+}
+
+func (e *memoryEngine) Down(ctx context.Context, m Migration) error {
+	delete(e.applied, m.Version)
+	e.calls = append(e.calls, "down:"+m.Name)
+	return nil
+}
+
+func TestRunnerUpDown(t *testing.T) {
+	engine := &memoryEngine{
+		migrations: []Migration{{1, "a"}, {2, "b"}, {3, "c"}},
+		applied:    map[int]time.Time{},
+	}
+	runner := NewRunner(engine)
+	ctx := context.Background()
+
+	if _, err := runner.Up(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"up:a", "up:b", "up:c", "down:c", "down:b"}
+	if !slices.Equal(engine.calls, want) {
+		t.Errorf("chamadas = %v, esperado %v", engine.calls, want)
+	}
+
+	status, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status[0].AppliedAt.IsZero() || !status[1].AppliedAt.IsZero() || !status[2].AppliedAt.IsZero() {
+		t.Errorf("status = %+v, esperado apenas a versão 1 aplicada", status)
+	}
+}
+
+func TestLoadScripts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_indice.up.sql":        {Data: []byte("CREATE INDEX i ON t (c);")},
+		"sql/0002_indice.down.sql":      {Data: []byte("DROP INDEX i;")},
+		"sql/0001_tabela.up.sql":        {Data: []byte("-- comentário; com ponto e vírgula\nCREATE TABLE t (c INT);\n\nCREATE TABLE u (c INT);\n")},
+		"sql/0001_tabela.down.sql":      {Data: []byte("DROP TABLE u;\nDROP TABLE t;")},
+		"sql/LEIAME.md":                 {Data: []byte("ignorado")},
+		"incompleto/0001_tabela.up.sql": {Data: []byte("CREATE TABLE t (c INT);")},
+		"invalido/primeira.up.sql":      {Data: []byte("")},
+		"invalido/primeira.down.sql":    {Data: []byte("")},
+		"duplicado/0001_a.up.sql":       {Data: []byte("")},
+		"duplicado/0001_a.down.sql":     {Data: []byte("")},
+		"duplicado/0001_b.up.sql":       {Data: []byte("")},
+		"duplicado/0001_b.down.sql":     {Data: []byte("")},
+	}
+
+	scripts, err := loadScripts(fsys, "sql", ".sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) != 2 || scripts[0].Version != 1 || scripts[0].Name != "tabela" || scripts[1].Name != "indice" {
+		t.Fatalf("scripts = %+v", scripts)
+	}
+	if want := []string{"CREATE TABLE t (c INT)", "CREATE TABLE u (c INT)"}; !slices.Equal(scripts[0].up, want) {
+		t.Errorf("comandos = %q, esperado %q", scripts[0].up, want)
+	}
+
+	for _, dir := range []string{"incompleto", "invalido", "duplicado"} {
+		if _, err := loadScripts(fsys, dir, ".sql"); err == nil {
+			t.Errorf("%s: esperado erro", dir)
+		}
+	}
+}
+
+func TestEmbeddedScripts(t *testing.T) {
+	for dir, ext := range map[string]string{"postgres": ".sql", "cassandra": ".cql"} {
+		scripts, err := loadScripts(scriptFiles, dir, ext)
+		if err != nil {
+			t.Fatalf("%s: %v", dir, err)
+		}
+		for i, s := range scripts {
+			if s.Version != i+1 {
+				t.Errorf("%s: versão %d fora de sequência na posição %d", dir, s.Version, i)
+			}
+			if len(s.up) == 0 || len(s.down) == 0 {
+				t.Errorf("%s: migração %04d_%s vazia", dir, s.Version, s.Name)
+			}
+		}
+	}
+
+	for i, m := range mongoMigrations {
+		if m.Version != i+1 {
+			t.Errorf("mongodb: versão %d fora de sequência na posição %d", m.Version, i)
+		}
+	}
+}